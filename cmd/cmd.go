@@ -2,11 +2,14 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/alecthomas/kong"
 	"github.com/vflame6/leaker/logger"
 	"github.com/vflame6/leaker/runner"
 	"github.com/vflame6/leaker/runner/sources"
+	"github.com/vflame6/leaker/utils"
+	"io"
 	"os"
 	"os/signal"
 	"strings"
@@ -22,38 +25,125 @@ var CLI struct {
 	Email struct {
 		Targets string `arg:"" optional:"" help:"Target email or file with emails, one per line"`
 	} `cmd:"" help:"Search by email address."`
+	Hash struct {
+		Targets string `arg:"" optional:"" help:"Target password hash or file with hashes, one per line"`
+	} `cmd:"" help:"Search by password hash."`
+	IP struct {
+		Targets string `arg:"" optional:"" help:"Target IP address or file with IP addresses, one per line"`
+	} `cmd:"" name:"ip" help:"Search by IP address."`
 	Keyword struct {
 		Targets string `arg:"" optional:"" help:"Target keyword or file with keywords, one per line"`
 	} `cmd:"" help:"Search by keyword."`
+	Name struct {
+		Targets string `arg:"" optional:"" help:"Target real name or file with names, one per line"`
+	} `cmd:"" help:"Search by real name."`
+	Password struct {
+		Targets string `arg:"" optional:"" help:"Target password or file with passwords, one per line"`
+	} `cmd:"" help:"Search by password."`
 	Phone struct {
 		Targets string `arg:"" optional:"" help:"Target phone number or file with phone numbers, one per line"`
 	} `cmd:"" help:"Search by phone number."`
 	Username struct {
 		Targets string `arg:"" optional:"" help:"Target username or file with usernames, one per line"`
 	} `cmd:"" help:"Search by username."`
+	Serve struct {
+		Listen     string `help:"Address to listen on" default:"127.0.0.1:8080"`
+		TokenFile  string `help:"File containing the API token clients must present (default: LEAKER_API_TOKEN)"`
+		MaxJobs    int    `help:"Maximum number of searches running at once" default:"4"`
+		JobWorkers int    `help:"Number of queued jobs run at once" default:"2"`
+	} `cmd:"" help:"Run the HTTP API server."`
+	Jobs struct {
+		List struct {
+			Status string `help:"Only list jobs in this status" enum:",queued,running,done,failed,cancelled" default:""`
+		} `cmd:"" help:"List jobs."`
+		Show struct {
+			ID      string `arg:"" help:"Job ID"`
+			Results bool   `help:"Print the results found by the job"`
+		} `cmd:"" help:"Show a job."`
+		Cancel struct {
+			ID string `arg:"" help:"Job ID"`
+		} `cmd:"" help:"Cancel a queued or running job."`
+	} `cmd:"" help:"Manage jobs queued through the API server."`
+	Monitor struct {
+		Run struct {
+			Interval time.Duration `help:"Search each target again after this long; 0 makes a single pass" default:"0s"`
+		} `cmd:"" default:"1" help:"Search the watchlist and report only new leaks (default)."`
+		Add struct {
+			Type    string `arg:"" enum:"domain,email,hash,ip,keyword,name,password,phone,username" help:"Target type"`
+			Targets string `arg:"" help:"Target or file with targets, one per line"`
+		} `cmd:"" help:"Add targets to the watchlist."`
+		Remove struct {
+			Type   string `arg:"" enum:"domain,email,hash,ip,keyword,name,password,phone,username" help:"Target type"`
+			Target string `arg:"" help:"Target"`
+		} `cmd:"" help:"Remove a target from the watchlist."`
+		List struct{} `cmd:"" help:"List the watchlist."`
+	} `cmd:"" help:"Monitor a saved watchlist for new leaks."`
+
+	Report struct {
+		Input string `arg:"" help:"JSONL file written by leaker -j"`
+	} `cmd:"" help:"Generate reports, STIX or MISP exports and hash files from a JSONL file."`
+
+	Credits struct{} `cmd:"" help:"Show the credits spent per source and API key across runs, as stored in the local DB."`
+
+	Decrypt struct {
+		Input    string   `arg:"" help:"File encrypted with --encrypt-to or --encrypt-passphrase"`
+		Identity []string `short:"i" help:"age identity file, as written by age-keygen (repeatable)"`
+	} `cmd:"" help:"Decrypt an encrypted output file to stdout or -o. A passphrase is read from LEAKER_PASSPHRASE."`
 
 	// INPUT
 	Sources []string `short:"s" default:"online" help:"Sources to use for enumeration. online (default), all, local, or explicit source names."`
 
 	// OPTIMIZATION
-	Timeout     time.Duration `help:"Seconds to wait on each request before timing out" default:"30s"`
-	NoRateLimit bool          `short:"N" help:"Disable rate limiting (DANGER)"`
+	Timeout     time.Duration  `help:"Seconds to wait on each request before timing out" default:"30s"`
+	NoRateLimit bool           `short:"N" help:"Disable rate limiting (DANGER)"`
+	MaxPages    int            `help:"Maximum number of result pages to request per source and target" default:"10"`
+	MaxResults  int            `help:"Maximum number of records to fetch per source and target" default:"10000"`
+	MaxCredits  map[string]int `help:"Stop a paid source after it consumed N credits in this run (source=N)"`
+
+	// DISCOVERY
+	Phonebook    bool `help:"Use the IntelX phonebook to discover email addresses during domain scans"`
+	ExpandEmails bool `help:"Enumerate email addresses discovered during domain scans as new email targets"`
 
 	// OUTPUT
 	JSON            bool   `short:"j" help:"Output results as JSONL (one JSON object per line)"`
+	Format          string `help:"Output format: plain (default), json, csv or tsv" enum:",plain,json,csv,tsv" default:""`
+	FlattenExtra    bool   `help:"Write each extra field as its own csv/tsv column instead of one JSON column"`
+	EscapeFormulas  bool   `help:"Prefix csv/tsv values starting with =, +, -, @, tab or CR with ' so spreadsheets don't run them"`
+	Template        string `help:"Go text/template rendered for each result, e.g. '{{.Email}}:{{.Password}}'"`
+	TemplateFile    string `help:"File with a Go text/template rendered for each result"`
+	ErrorsJSON      string `name:"errors-json" help:"File to write every source error to as JSONL (target, source, category)"`
+	ExportPasswords string `help:"Passwords in STIX and MISP exports: plain, hash (SHA-256) or omit" enum:"plain,hash,omit" default:"plain"`
+	HashDir         string `help:"Directory to write leaked hashes to for hashcat and John, one file per hashcat mode"`
+	MISP            string `name:"misp" help:"File to write the results to as a MISP event at the end of the run"`
 	NoDeduplication bool   `help:"Disable deduplication of results across sources"`
 	NoFilter        bool   `help:"Disable results filtering, include every result"`
+	Notify          bool   `help:"Send findings to the notification sinks in the provider config"`
 	Output          string `short:"o" help:"File to write output to"`
+	OutputDir       string `help:"Directory to write one output file per target to, with an index of result counts"`
 	Overwrite       bool   `help:"Force overwrite of existing output file"`
+	Redact          string `help:"Redact passwords in output and notifications: partial, length, hash or remove" enum:",partial,length,hash,remove" default:""`
+	RedactKey       string `help:"Key that makes --redact hash an HMAC-SHA256, so digests can't be brute-forced" env:"LEAKER_REDACT_KEY"`
+	RedactDB        bool   `name:"redact-db" help:"Also redact the passwords stored in the local DB"`
+	ReportHTML      string `name:"report-html" help:"File to write a self-contained HTML report to at the end of the run"`
+	ReportMarkdown  string `name:"report-md" help:"File to write a Markdown report to at the end of the run"`
+	STIX            string `name:"stix" help:"File to write the results to as a STIX 2.1 bundle at the end of the run"`
+	Summary         bool   `help:"Print a per-source run summary to stderr at the end of the run"`
+	SummaryJSON     string `name:"summary-json" help:"File to write the per-source run summary to as JSON"`
 	Verify          bool   `short:"V" help:"Verify credentials using HIBP password check and hash identification"`
 
+	// ENCRYPTION
+	EncryptTo   []string `help:"Encrypt output files with age to these X25519 public keys or recipient files"`
+	EncryptPass bool     `name:"encrypt-passphrase" help:"Encrypt output files with age to the passphrase in LEAKER_PASSPHRASE"`
+
 	// CONFIGURATION
-	ProviderConfig string `short:"p" help:"Provider config file"`
-	Proxy          string `help:"HTTP proxy to use with leaker"`
-	UserAgent      string `short:"A" help:"Custom user agent"`
-	Insecure       bool   `help:"Disable TLS certificate verification (use with caution)"`
-	DB             string `help:"Path to the local SQLite cache DB"`
-	NoWriteDB      bool   `help:"Disable writing results to the local SQLite cache"`
+	ProviderConfig string        `short:"p" help:"Provider config file"`
+	Proxy          string        `help:"Proxy URL to use with leaker (http, https, socks5, socks5h), or a file with one proxy per line"`
+	ProxyRotation  string        `help:"Rotate pooled proxies per request or per target" enum:"request,target" default:"request"`
+	ProxyCooldown  time.Duration `help:"How long a pooled proxy is evicted after a timeout or connection error" default:"1m"`
+	UserAgent      string        `short:"A" help:"Custom user agent"`
+	Insecure       bool          `help:"Disable TLS certificate verification (use with caution)"`
+	DB             string        `help:"Path to the local SQLite cache DB"`
+	NoWriteDB      bool          `help:"Disable writing results to the local SQLite cache"`
 
 	// DEBUG
 	Version     bool `help:"Print version of leaker"`
@@ -72,6 +162,37 @@ func resolveDBPath(flagValue string, getenv func(string) string) string {
 	return getenv("LEAKER_DB")
 }
 
+// resolveFormat combines --format with its -j shorthand.
+func resolveFormat(jsonFlag bool, format string) (string, error) {
+	switch {
+	case jsonFlag && format != "" && format != runner.FormatJSON:
+		return "", fmt.Errorf("-j conflicts with --format %s", format)
+	case jsonFlag:
+		return runner.FormatJSON, nil
+	case format == "":
+		return runner.FormatPlain, nil
+	default:
+		return format, nil
+	}
+}
+
+// resolveTemplate returns the --template text, or the contents of
+// --template-file.
+func resolveTemplate(text, file string) (string, error) {
+	switch {
+	case text != "" && file != "":
+		return "", fmt.Errorf("--template conflicts with --template-file")
+	case file != "":
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	default:
+		return text, nil
+	}
+}
+
 func resolveNoWriteDB(flagValue bool, getenv func(string) string, warnf func(string, ...any)) bool {
 	if flagValue {
 		return true
@@ -88,6 +209,234 @@ func resolveNoWriteDB(flagValue bool, getenv func(string) string, warnf func(str
 	}
 }
 
+// resolveAPIToken reads the API server token from tokenFile, falling back
+// to LEAKER_API_TOKEN. The token is never taken from the command line,
+// where other local users could read it.
+func resolveAPIToken(tokenFile string, getenv func(string) string) (string, error) {
+	if tokenFile != "" {
+		data, err := os.ReadFile(tokenFile)
+		if err != nil {
+			return "", err
+		}
+		if token := strings.TrimSpace(string(data)); token != "" {
+			return token, nil
+		}
+		return "", fmt.Errorf("API token file %s is empty", tokenFile)
+	}
+	if token := strings.TrimSpace(getenv("LEAKER_API_TOKEN")); token != "" {
+		return token, nil
+	}
+	return "", fmt.Errorf("set LEAKER_API_TOKEN or --token-file to serve the API")
+}
+
+// runJobs handles the `leaker jobs` subcommands against the local DB.
+func runJobs(command, dbPath string, w io.Writer) error {
+	writable := command == "jobs cancel <id>"
+	db, err := runner.OpenLeakerDB((&runner.Options{DBPath: dbPath}).ResolvedDBPath(), writable)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	switch command {
+	case "jobs list":
+		jobs, err := db.Jobs(runner.JobStatus(CLI.Jobs.List.Status))
+		if err != nil {
+			return err
+		}
+		return runner.WriteJobList(w, jobs)
+	case "jobs show <id>":
+		job, err := db.Job(CLI.Jobs.Show.ID)
+		if err != nil {
+			return err
+		}
+		if !CLI.Jobs.Show.Results {
+			return runner.WriteJob(w, job)
+		}
+		results, err := db.JobResults(context.Background(), job.ID)
+		if err != nil {
+			return err
+		}
+		for _, jr := range results {
+			result := runner.RedactResult(&jr.Result, CLI.Redact, CLI.RedactKey)
+			if CLI.JSON {
+				err = runner.WriteJSONResult(w, CLI.Metadata, result, jr.Target)
+			} else {
+				err = runner.WritePlainResult(w, CLI.Verbose, CLI.Metadata, result)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case "jobs cancel <id>":
+		if err := db.CancelJob(CLI.Jobs.Cancel.ID); err != nil {
+			return err
+		}
+		logger.Infof("Cancelled job %s", CLI.Jobs.Cancel.ID)
+		return nil
+	}
+	return fmt.Errorf("unknown command: %s", command)
+}
+
+// runWatchlist handles the `leaker monitor` watchlist subcommands against
+// the local DB.
+func runWatchlist(command, dbPath string, w io.Writer) error {
+	writable := command != "monitor list"
+	db, err := runner.OpenLeakerDB((&runner.Options{DBPath: dbPath}).ResolvedDBPath(), writable)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	switch command {
+	case "monitor list":
+		entries, err := db.Watchlist()
+		if err != nil {
+			return err
+		}
+		return runner.WriteWatchlist(w, entries)
+	case "monitor add <type> <targets>":
+		scanType, _ := sources.ParseScanType(CLI.Monitor.Add.Type)
+		added, err := db.AddWatchTargets(scanType, CLI.Monitor.Add.Targets)
+		if err != nil {
+			return err
+		}
+		logger.Infof("Added %d targets to the watchlist", added)
+		return nil
+	case "monitor remove <type> <target>":
+		scanType, _ := sources.ParseScanType(CLI.Monitor.Remove.Type)
+		if err := db.RemoveWatch(scanType, CLI.Monitor.Remove.Target); err != nil {
+			return err
+		}
+		logger.Infof("Removed %s from the watchlist", CLI.Monitor.Remove.Target)
+		return nil
+	}
+	return fmt.Errorf("unknown command: %s", command)
+}
+
+// runCredits writes the credits spent per source and API key, as stored
+// in the local DB by earlier runs.
+func runCredits(dbPath string, w io.Writer) error {
+	db, err := runner.OpenLeakerDB((&runner.Options{DBPath: dbPath}).ResolvedDBPath(), false)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+
+	quotas, err := db.QuotaUsage()
+	if err != nil {
+		return err
+	}
+	return runner.WriteQuotaUsage(w, quotas)
+}
+
+// runReport writes the reports and exports of a JSONL results file.
+func runReport(input string, outputs runner.Options) error {
+	if outputs.ReportHTML == "" && outputs.ReportMarkdown == "" && outputs.STIX == "" && outputs.MISP == "" && outputs.HashDir == "" {
+		return fmt.Errorf("set --report-html, --report-md, --stix, --misp and/or --hash-dir")
+	}
+	if outputs.ReportHTML != "" || outputs.ReportMarkdown != "" {
+		report := runner.NewReport()
+		report.Redact, report.RedactKey = outputs.Redact, outputs.RedactKey
+		if err := readJSONLFile(input, report.ReadJSONL); err != nil {
+			return err
+		}
+		if err := runner.WriteReportFiles(report, outputs.ReportHTML, outputs.ReportMarkdown, outputs.Overwrite); err != nil {
+			return err
+		}
+	}
+	if outputs.STIX == "" && outputs.MISP == "" && outputs.HashDir == "" {
+		return nil
+	}
+	findings := runner.NewFindings()
+	findings.Redact, findings.RedactKey = outputs.Redact, outputs.RedactKey
+	if err := readJSONLFile(input, findings.ReadJSONL); err != nil {
+		return err
+	}
+	if err := runner.WriteExportFiles(findings, outputs.STIX, outputs.MISP, outputs.ExportPasswords, outputs.Overwrite); err != nil {
+		return err
+	}
+	if outputs.HashDir == "" {
+		return nil
+	}
+	return runner.WriteHashFiles(findings, outputs.HashDir, outputs.Overwrite)
+}
+
+// readJSONLFile streams the JSONL file at path into read.
+func readJSONLFile(path string, read func(io.Reader) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	if err := read(file); err != nil {
+		return fmt.Errorf("could not read %s: %w", path, err)
+	}
+	return nil
+}
+
+// runDecrypt decrypts an age-encrypted output file to outputPath, or to
+// stdout when it is empty.
+func runDecrypt(input string, identities []string, passphrase, outputPath string, overwrite bool, stdout io.Writer) error {
+	src, err := os.Open(input)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = src.Close()
+	}()
+	if outputPath == "" {
+		return runner.DecryptAge(stdout, src, identities, passphrase)
+	}
+	dst, err := utils.CreateFileWithSafe(outputPath, false, overwrite)
+	if err != nil {
+		return err
+	}
+	err = runner.DecryptAge(dst, src, identities, passphrase)
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// resolvePassphrase returns the passphrase of --encrypt-passphrase.
+func resolvePassphrase(enabled bool, getenv func(string) string) (string, error) {
+	if !enabled {
+		return "", nil
+	}
+	passphrase := getenv("LEAKER_PASSPHRASE")
+	if passphrase == "" {
+		return "", fmt.Errorf("--encrypt-passphrase needs the passphrase in LEAKER_PASSPHRASE")
+	}
+	return passphrase, nil
+}
+
+// clearTextFile is a file flag of a run and its value.
+type clearTextFile struct {
+	flag, value string
+}
+
+// checkClearTextFiles refuses the files a run would write in clear text
+// next to its encrypted output, so encrypting the results doesn't leave a
+// readable copy of them behind.
+func checkClearTextFiles(files []clearTextFile) error {
+	for _, file := range files {
+		if file.value != "" {
+			return fmt.Errorf("--%s is written in clear text and can't be combined with --encrypt-to or --encrypt-passphrase", file.flag)
+		}
+	}
+	return nil
+}
+
 func Run() {
 	parser, err := kong.New(&CLI,
 		kong.Name("leaker"),
@@ -131,6 +480,55 @@ func Run() {
 		parser.FatalIfErrorf(parseErr)
 	}
 
+	// Job commands only read or update the local DB.
+	if strings.HasPrefix(ctx.Command(), "jobs ") {
+		if err := runJobs(ctx.Command(), resolveDBPath(CLI.DB, os.Getenv), os.Stdout); err != nil {
+			logger.Fatal(err)
+		}
+		os.Exit(0)
+	}
+
+	// Credit totals are only read from the local DB.
+	if ctx.Command() == "credits" {
+		if err := runCredits(resolveDBPath(CLI.DB, os.Getenv), os.Stdout); err != nil {
+			logger.Fatal(err)
+		}
+		os.Exit(0)
+	}
+
+	// Watchlist commands only read or update the local DB.
+	if command := ctx.Command(); strings.HasPrefix(command, "monitor ") && command != "monitor run" {
+		if err := runWatchlist(command, resolveDBPath(CLI.DB, os.Getenv), os.Stdout); err != nil {
+			logger.Fatal(err)
+		}
+		os.Exit(0)
+	}
+
+	if ctx.Command() == "decrypt <input>" {
+		if err := runDecrypt(CLI.Decrypt.Input, CLI.Decrypt.Identity, os.Getenv("LEAKER_PASSPHRASE"), CLI.Output, CLI.Overwrite, os.Stdout); err != nil {
+			logger.Fatal(err)
+		}
+		os.Exit(0)
+	}
+
+	// Reports from an existing JSONL file need no sources.
+	if ctx.Command() == "report <input>" {
+		if err := runReport(CLI.Report.Input, runner.Options{
+			ExportPasswords: CLI.ExportPasswords,
+			HashDir:         CLI.HashDir,
+			MISP:            CLI.MISP,
+			Overwrite:       CLI.Overwrite,
+			Redact:          CLI.Redact,
+			RedactKey:       CLI.RedactKey,
+			ReportHTML:      CLI.ReportHTML,
+			ReportMarkdown:  CLI.ReportMarkdown,
+			STIX:            CLI.STIX,
+		}); err != nil {
+			logger.Fatal(err)
+		}
+		os.Exit(0)
+	}
+
 	// output banner
 	if !CLI.Quiet {
 		PrintBanner()
@@ -139,8 +537,14 @@ func Run() {
 	// select command
 	var scanType sources.ScanType
 	var targets string
+	serve := false
+	monitor := false
 
 	switch ctx.Command() {
+	case "serve":
+		serve = true
+	case "monitor run":
+		monitor = true
 	case "email", "email <targets>":
 		scanType = sources.TypeEmail
 		targets = CLI.Email.Targets
@@ -156,9 +560,24 @@ func Run() {
 	case "phone", "phone <targets>":
 		scanType = sources.TypePhone
 		targets = CLI.Phone.Targets
+	case "hash", "hash <targets>":
+		scanType = sources.TypeHash
+		targets = CLI.Hash.Targets
+	case "ip", "ip <targets>":
+		scanType = sources.TypeIP
+		targets = CLI.IP.Targets
+	case "name", "name <targets>":
+		scanType = sources.TypeName
+		targets = CLI.Name.Targets
+	case "password", "password <targets>":
+		scanType = sources.TypePassword
+		targets = CLI.Password.Targets
 	default:
 		logger.Fatalf("Unknown command: %s", ctx.Command())
 	}
+	if CLI.OutputDir != "" && (serve || monitor) {
+		logger.Fatalf("--output-dir is not supported by %s", ctx.Command())
+	}
 
 	// Resolve --db / LEAKER_DB. Flag wins; fall back to env; empty means
 	// "use the runner default location".
@@ -169,22 +588,91 @@ func Run() {
 	// and are treated as false.
 	noWriteDB := resolveNoWriteDB(CLI.NoWriteDB, os.Getenv, logger.Warnf)
 
+	format, err := resolveFormat(CLI.JSON, CLI.Format)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	if CLI.FlattenExtra && format != runner.FormatCSV && format != runner.FormatTSV {
+		logger.Fatal("--flatten-extra needs --format csv or tsv")
+	}
+	if CLI.EscapeFormulas && format != runner.FormatCSV && format != runner.FormatTSV {
+		logger.Fatal("--escape-formulas needs --format csv or tsv")
+	}
+	outputTemplate, err := resolveTemplate(CLI.Template, CLI.TemplateFile)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	if outputTemplate != "" && format != runner.FormatPlain {
+		logger.Fatalf("--template conflicts with --format %s", format)
+	}
+
+	passphrase, err := resolvePassphrase(CLI.EncryptPass, os.Getenv)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	if (len(CLI.EncryptTo) > 0 || passphrase != "") && CLI.Output == "" && CLI.OutputDir == "" {
+		logger.Fatal("--encrypt-to and --encrypt-passphrase need -o or --output-dir")
+	}
+	if len(CLI.EncryptTo) > 0 && passphrase != "" {
+		logger.Fatal("--encrypt-to conflicts with --encrypt-passphrase")
+	}
+	if len(CLI.EncryptTo) > 0 || passphrase != "" {
+		if err := checkClearTextFiles([]clearTextFile{
+			{"errors-json", CLI.ErrorsJSON},
+			{"hash-dir", CLI.HashDir},
+			{"misp", CLI.MISP},
+			{"report-html", CLI.ReportHTML},
+			{"report-md", CLI.ReportMarkdown},
+			{"stix", CLI.STIX},
+			{"summary-json", CLI.SummaryJSON},
+		}); err != nil {
+			logger.Fatal(err)
+		}
+	}
+
 	options := &runner.Options{
 		Debug:           CLI.Debug,
+		EncryptTo:       CLI.EncryptTo,
+		ErrorsJSON:      CLI.ErrorsJSON,
+		EscapeFormulas:  CLI.EscapeFormulas,
+		ExpandEmails:    CLI.ExpandEmails,
+		ExportPasswords: CLI.ExportPasswords,
+		FlattenExtra:    CLI.FlattenExtra,
+		Format:          format,
+		HashDir:         CLI.HashDir,
 		Insecure:        CLI.Insecure,
-		JSON:            CLI.JSON,
+		JSON:            format == runner.FormatJSON,
 		ListSources:     CLI.ListSources,
+		MISP:            CLI.MISP,
+		MaxCredits:      CLI.MaxCredits,
+		MaxPages:        CLI.MaxPages,
+		MaxResults:      CLI.MaxResults,
 		NoColor:         CLI.NoColor,
 		NoDeduplication: CLI.NoDeduplication,
 		NoFilter:        CLI.NoFilter,
 		NoRateLimit:     CLI.NoRateLimit,
+		Notify:          CLI.Notify,
+		OutputDir:       CLI.OutputDir,
 		OutputFile:      CLI.Output,
 		Overwrite:       CLI.Overwrite,
+		Passphrase:      passphrase,
+		Phonebook:       CLI.Phonebook,
 		ProviderConfig:  CLI.ProviderConfig,
 		Proxy:           CLI.Proxy,
+		ProxyCooldown:   CLI.ProxyCooldown,
+		ProxyRotation:   CLI.ProxyRotation,
 		Quiet:           CLI.Quiet,
+		Redact:          CLI.Redact,
+		RedactDB:        CLI.RedactDB,
+		RedactKey:       CLI.RedactKey,
+		ReportHTML:      CLI.ReportHTML,
+		ReportMarkdown:  CLI.ReportMarkdown,
 		Sources:         CLI.Sources,
+		STIX:            CLI.STIX,
+		Summary:         CLI.Summary,
+		SummaryJSON:     CLI.SummaryJSON,
 		Targets:         targets,
+		Template:        outputTemplate,
 		Timeout:         CLI.Timeout,
 		Type:            scanType,
 		UserAgent:       CLI.UserAgent,
@@ -204,8 +692,36 @@ func Run() {
 		logger.Fatal(err)
 	}
 
+	if serve {
+		token, tokenErr := resolveAPIToken(CLI.Serve.TokenFile, os.Getenv)
+		if tokenErr != nil {
+			logger.Fatal(tokenErr)
+		}
+		err = r.Serve(runCtx, runner.ServeOptions{
+			Listen:     CLI.Serve.Listen,
+			Token:      token,
+			MaxJobs:    CLI.Serve.MaxJobs,
+			JobWorkers: CLI.Serve.JobWorkers,
+		})
+		if err != nil {
+			logger.Fatal(err)
+		}
+		return
+	}
+
+	if monitor {
+		found, monitorErr := r.Monitor(runCtx, CLI.Monitor.Run.Interval)
+		if monitorErr != nil {
+			logger.Fatal(monitorErr)
+		}
+		os.Exit(r.MonitorExitCode(found))
+	}
+
+	// failed targets are logged as they happen and make the run a
+	// partial failure
 	err = r.RunEnumeration(runCtx)
-	if err != nil {
+	if err != nil && !errors.Is(err, runner.ErrTargetsFailed) {
 		logger.Fatal(err)
 	}
+	os.Exit(r.ExitCode())
 }
@@ -1,6 +1,13 @@
 package cmd
 
-import "testing"
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vflame6/leaker/runner"
+)
 
 func TestResolveDBPathFlagWinsOverEnv(t *testing.T) {
 	got := resolveDBPath("/tmp/flag.db", func(key string) string {
@@ -84,3 +91,114 @@ func TestResolveNoWriteDBInvalidEnvWarnsAndFallsBackFalse(t *testing.T) {
 		t.Fatalf("expected one warning, got %d", warnings)
 	}
 }
+
+func TestResolveAPITokenPrefersFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte("from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	getenv := func(string) string { return "from-env" }
+
+	if got, err := resolveAPIToken(path, getenv); err != nil || got != "from-file" {
+		t.Fatalf("expected the token file to win, got %q, %v", got, err)
+	}
+	if got, err := resolveAPIToken("", getenv); err != nil || got != "from-env" {
+		t.Fatalf("expected LEAKER_API_TOKEN, got %q, %v", got, err)
+	}
+	if _, err := resolveAPIToken("", func(string) string { return "" }); err == nil {
+		t.Fatal("expected an error without any token")
+	}
+}
+
+func TestResolveFormat(t *testing.T) {
+	for _, tc := range []struct {
+		json    bool
+		format  string
+		want    string
+		wantErr bool
+	}{
+		{false, "", "plain", false},
+		{true, "", "json", false},
+		{true, "json", "json", false},
+		{false, "csv", "csv", false},
+		{true, "tsv", "", true},
+	} {
+		got, err := resolveFormat(tc.json, tc.format)
+		if (err != nil) != tc.wantErr || got != tc.want {
+			t.Errorf("resolveFormat(%v, %q) = %q, %v; want %q, error %v", tc.json, tc.format, got, err, tc.want, tc.wantErr)
+		}
+	}
+}
+
+func TestResolveTemplateReadsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "line.tmpl")
+	if err := os.WriteFile(path, []byte("{{.Email}}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	got, err := resolveTemplate("", path)
+	if err != nil || got != "{{.Email}}\n" {
+		t.Errorf("unexpected template %q, %v", got, err)
+	}
+	if _, err := resolveTemplate("{{.Email}}", path); err == nil {
+		t.Error("expected an error when both are set")
+	}
+}
+
+func TestResolvePassphrase(t *testing.T) {
+	getenv := func(string) string { return "s3cret" }
+	if got, err := resolvePassphrase(false, getenv); err != nil || got != "" {
+		t.Errorf("expected no passphrase when disabled, got %q, %v", got, err)
+	}
+	if got, err := resolvePassphrase(true, getenv); err != nil || got != "s3cret" {
+		t.Errorf("expected LEAKER_PASSPHRASE, got %q, %v", got, err)
+	}
+	if _, err := resolvePassphrase(true, func(string) string { return "" }); err == nil {
+		t.Error("expected an error without LEAKER_PASSPHRASE")
+	}
+}
+
+func TestRunReportReadsInputPerOutput(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "results.jsonl")
+	data := `{"source":"leakcheck","target":"example.com","email":"alice@example.com","password":"hunter2"}
+{"source":"snusbase","target":"example.com","email":"bob@example.com","hash":"5f4dcc3b5aa765d61d8327deb882cf99"}
+`
+	if err := os.WriteFile(input, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	outputs := runner.Options{
+		ExportPasswords: runner.ExportPasswordsPlain,
+		HashDir:         filepath.Join(dir, "hashes"),
+		ReportMarkdown:  filepath.Join(dir, "report.md"),
+		STIX:            filepath.Join(dir, "bundle.json"),
+	}
+	if err := runReport(input, outputs); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{outputs.ReportMarkdown, outputs.STIX, filepath.Join(outputs.HashDir, "0-md5.txt")} {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(content) == 0 {
+			t.Errorf("%s is empty", path)
+		}
+	}
+	if report, _ := os.ReadFile(outputs.ReportMarkdown); !strings.Contains(string(report), "alice@example.com") {
+		t.Errorf("report misses the first result:\n%s", report)
+	}
+
+	if err := runReport(filepath.Join(dir, "missing.jsonl"), runner.Options{ReportMarkdown: filepath.Join(dir, "other.md")}); err == nil {
+		t.Error("expected an error for a missing input file")
+	}
+}
+
+func TestCheckClearTextFiles(t *testing.T) {
+	if err := checkClearTextFiles([]clearTextFile{{"stix", ""}, {"misp", ""}}); err != nil {
+		t.Errorf("expected no error without clear-text files, got %v", err)
+	}
+	err := checkClearTextFiles([]clearTextFile{{"stix", ""}, {"report-html", "report.html"}})
+	if err == nil || !strings.Contains(err.Error(), "--report-html") {
+		t.Errorf("expected an error naming --report-html, got %v", err)
+	}
+}
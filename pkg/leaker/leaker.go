@@ -0,0 +1,321 @@
+// Package leaker is the public Go API of leaker. It searches the same
+// sources as the command line tool, but every Client owns its source
+// instances, API keys, HTTP sessions and cache handle, so several clients
+// with different configurations can run in one process. Each client logs
+// through its own logger, set with Options.Logger.
+//
+//	client, err := leaker.New(leaker.Options{
+//		Sources: []string{"leakcheck", "proxynova"},
+//		Keys:    map[string][]string{"leakcheck": {"API_KEY"}},
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	defer client.Close()
+//
+//	for result := range client.Search(ctx, "user@example.com", leaker.TypeEmail) {
+//		if result.Error != nil {
+//			continue
+//		}
+//		fmt.Println(result.Email, result.Password)
+//	}
+package leaker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vflame6/leaker/logger"
+	"github.com/vflame6/leaker/runner"
+	"github.com/vflame6/leaker/runner/sources"
+)
+
+// Result is a single leak, or an error reported by a source when Error is
+// set.
+type Result = sources.Result
+
+// ScanType selects what kind of target a search is for.
+type ScanType = sources.ScanType
+
+// Scan types accepted by Client.Search.
+const (
+	TypeEmail    = sources.TypeEmail
+	TypeUsername = sources.TypeUsername
+	TypeDomain   = sources.TypeDomain
+	TypeKeyword  = sources.TypeKeyword
+	TypePhone    = sources.TypePhone
+	TypeHash     = sources.TypeHash
+	TypeIP       = sources.TypeIP
+	TypeName     = sources.TypeName
+	TypePassword = sources.TypePassword
+)
+
+// DefaultTimeout is the per-request timeout used when Options.Timeout is
+// not set.
+const DefaultTimeout = 30 * time.Second
+
+// Options configures a Client. The zero value searches every online
+// source that does not need an API key.
+type Options struct {
+	// Sources lists the source names to search. Empty selects every online
+	// source that is usable with the given keys; "local" must be listed
+	// explicitly and requires DBPath.
+	Sources []string
+	// Keys maps source names to their API keys.
+	Keys map[string][]string
+	// DBPath is the local SQLite cache searched by the "local" source. It
+	// is opened read-only; the client never writes to it.
+	DBPath string
+
+	Timeout   time.Duration // per-request timeout, DefaultTimeout if zero
+	UserAgent string        // defaults to "leaker"
+	Proxy     string        // http, https, socks5 or socks5h proxy URL
+	Insecure  bool          // disable TLS certificate verification
+
+	MaxPages   int // result pages per source and target, package default if zero
+	MaxResults int // records per source and target, package default if zero
+
+	NoFilter        bool // keep results that do not contain the target
+	NoDeduplication bool // keep duplicate results across sources
+
+	// Logger receives the warnings and debug messages of the client's
+	// sources. Nil logs warnings to standard error.
+	Logger *logger.Logger
+}
+
+// Client searches a fixed set of sources. It is safe for concurrent use.
+type Client struct {
+	options Options
+	sources []sources.Source
+	db      *runner.LeakerDB
+}
+
+// AvailableSources returns the names of every source a Client can use.
+func AvailableSources() []string {
+	return sources.SourceNames()
+}
+
+// New creates a client. It fails on unknown source names, keys for unknown
+// sources, malformed keys, explicitly selected sources that need a missing
+// key, and an invalid proxy.
+func New(options Options) (*Client, error) {
+	c, err := newClient(options)
+	if err != nil {
+		// release the local cache if a source after it was refused
+		_ = c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func newClient(options Options) (*Client, error) {
+	if options.Logger == nil {
+		options.Logger = logger.New(logger.LevelWarning, os.Stderr)
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = DefaultTimeout
+	}
+	if options.UserAgent == "" {
+		options.UserAgent = "leaker"
+	}
+	if options.Proxy != "" {
+		if _, err := sources.ParseProxyURL(options.Proxy); err != nil {
+			return nil, err
+		}
+	}
+
+	available := make(map[string]sources.Source)
+	for _, s := range sources.NewSources() {
+		available[s.Name()] = s
+	}
+
+	keys := make(map[string][]string)
+	for name, sourceKeys := range options.Keys {
+		name = strings.ToLower(strings.TrimSpace(name))
+		s, ok := available[name]
+		if !ok {
+			return nil, fmt.Errorf("keys given for unknown source %q", name)
+		}
+		if checker, ok := s.(sources.KeyChecker); ok {
+			for _, key := range sourceKeys {
+				if err := checker.CheckApiKey(key); err != nil {
+					return nil, err
+				}
+			}
+		}
+		keys[name] = append(keys[name], sourceKeys...)
+	}
+
+	c := &Client{options: options}
+	use := func(s sources.Source) {
+		if len(keys[s.Name()]) > 0 {
+			s.AddApiKeys(keys[s.Name()])
+		}
+		if pl, ok := s.(sources.PageLimiter); ok {
+			pl.SetPageLimits(options.MaxPages, options.MaxResults)
+		}
+		c.sources = append(c.sources, s)
+	}
+
+	if len(options.Sources) == 0 {
+		for _, name := range AvailableSources() {
+			s := available[name]
+			if name == sources.LocalSourceName || (s.NeedsKey() && len(keys[name]) == 0) {
+				continue
+			}
+			use(s)
+		}
+		return c, nil
+	}
+
+	for _, name := range options.Sources {
+		name = strings.ToLower(strings.TrimSpace(name))
+		s, ok := available[name]
+		if !ok {
+			return c, fmt.Errorf("unknown source %q", name)
+		}
+		if slices.Contains(c.sources, s) {
+			continue
+		}
+		if s.NeedsKey() && len(keys[name]) == 0 {
+			return c, fmt.Errorf("source %q needs an API key", name)
+		}
+		if local, ok := s.(*sources.LocalDB); ok {
+			if options.DBPath == "" {
+				return c, fmt.Errorf("source %q needs Options.DBPath", name)
+			}
+			db, err := runner.OpenLeakerDB(options.DBPath, false)
+			if err != nil {
+				return c, err
+			}
+			if db == nil {
+				return c, fmt.Errorf("local DB %s does not exist", options.DBPath)
+			}
+			db.SetLogger(options.Logger)
+			c.db = db
+			local.Lookup = db.Search
+		}
+		use(s)
+	}
+	return c, nil
+}
+
+// Sources returns the names of the sources the client searches.
+func (c *Client) Sources() []string {
+	names := make([]string, 0, len(c.sources))
+	for _, s := range c.sources {
+		names = append(names, s.Name())
+	}
+	return names
+}
+
+// Close releases the local cache handle, if any.
+func (c *Client) Close() error {
+	if c == nil {
+		return nil
+	}
+	return c.db.Close()
+}
+
+// Search queries every source of the client for target and streams the
+// results. Results are trimmed, filtered to those containing the target
+// and deduplicated across sources unless disabled in Options. Source
+// errors are delivered as results with Error set, as is an invalid target,
+// which is normalized and checked as in the CLI. The channel is closed
+// when every source finished or ctx is cancelled.
+func (c *Client) Search(ctx context.Context, target string, scanType ScanType) <-chan Result {
+	out := make(chan Result)
+
+	go func() {
+		defer close(out)
+
+		target, err := runner.ParseTarget(scanType, target)
+		if err != nil {
+			send(ctx, out, Result{Error: err})
+			return
+		}
+
+		session, err := sources.NewSession(c.options.Timeout, c.options.UserAgent, c.options.Proxy, c.options.Insecure)
+		if err != nil {
+			send(ctx, out, Result{Error: err})
+			return
+		}
+		defer session.Close()
+		session.Logger = c.options.Logger
+
+		// Local results go first so they win deduplication, as in the CLI.
+		results := make(chan Result)
+		go func() {
+			defer close(results)
+			wg := &sync.WaitGroup{}
+			for _, s := range c.sources {
+				if s.Name() == sources.LocalSourceName {
+					forward(ctx, s.Run(ctx, target, scanType, session), results)
+				}
+			}
+			for _, s := range c.sources {
+				if s.Name() == sources.LocalSourceName {
+					continue
+				}
+				wg.Add(1)
+				go func(s sources.Source) {
+					defer wg.Done()
+					forward(ctx, s.Run(ctx, target, scanType, session), results)
+				}(s)
+			}
+			wg.Wait()
+		}()
+
+		seen := make(map[string]struct{})
+		for result := range results {
+			if result.Error == nil {
+				result.TrimSpaces()
+				if !result.HasData() {
+					continue
+				}
+				if !c.options.NoFilter && !result.Contains(target) {
+					continue
+				}
+				if !c.options.NoDeduplication {
+					key := result.Checksum()
+					if _, ok := seen[key]; ok {
+						continue
+					}
+					seen[key] = struct{}{}
+				}
+			}
+			if !send(ctx, out, result) {
+				// keep draining so source goroutines can exit
+				for range results {
+				}
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// forward copies results from a source until it is done or ctx ends.
+func forward(ctx context.Context, in <-chan Result, out chan<- Result) {
+	for result := range in {
+		if !send(ctx, out, result) {
+			for range in {
+			}
+			return
+		}
+	}
+}
+
+func send(ctx context.Context, out chan<- Result, result Result) bool {
+	select {
+	case out <- result:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
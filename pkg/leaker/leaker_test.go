@@ -0,0 +1,106 @@
+package leaker
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/vflame6/leaker/runner"
+	"github.com/vflame6/leaker/runner/sources"
+)
+
+func TestNew_ClientsDoNotShareSources(t *testing.T) {
+	withKey, err := New(Options{Keys: map[string][]string{"leakcheck": {"key-a"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	withoutKey, err := New(Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Contains(withKey.Sources(), "leakcheck") {
+		t.Error("expected a keyed source to be selected by default")
+	}
+	if slices.Contains(withoutKey.Sources(), "leakcheck") {
+		t.Error("expected a source without its key to be skipped")
+	}
+	if slices.Contains(withoutKey.Sources(), sources.LocalSourceName) {
+		t.Error("expected local to be opt-in")
+	}
+	// Keyless sources may be zero-sized structs whose pointers compare
+	// equal, so only check the ones that hold keys.
+	for _, a := range withKey.sources {
+		if a.UsesKey() && slices.Contains(withoutKey.sources, a) {
+			t.Errorf("source %s instance is shared", a.Name())
+		}
+	}
+}
+
+func TestNew_RejectsInvalidOptions(t *testing.T) {
+	tests := map[string]Options{
+		"unknown source":     {Sources: []string{"nope"}},
+		"keys for unknown":   {Keys: map[string][]string{"nope": {"k"}}},
+		"missing key":        {Sources: []string{"dehashed"}},
+		"malformed key":      {Keys: map[string][]string{"intelx": {"no-host"}}},
+		"local without path": {Sources: []string{"local"}},
+		"missing local db":   {Sources: []string{"local"}, DBPath: filepath.Join(t.TempDir(), "missing.db")},
+		"invalid proxy":      {Proxy: "127.0.0.1:8080"},
+	}
+	for name, options := range tests {
+		if _, err := New(options); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestSearch_LocalDB(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "leaker.db")
+	db, err := runner.OpenLeakerDB(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range []sources.Result{
+		{Source: "seed", Email: "alice@example.com", Password: "one"},
+		{Source: "seed", Email: "alice@example.com", Password: "two"},
+		{Source: "seed", Email: "bob@example.com", Password: "three"},
+	} {
+		if err := db.Insert(&r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = db.Close()
+
+	client, err := New(Options{Sources: []string{"local"}, DBPath: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = client.Close() }()
+
+	var passwords []string
+	for result := range client.Search(context.Background(), " Alice@Example.com ", TypeEmail) {
+		if result.Error != nil {
+			t.Fatalf("unexpected error: %v", result.Error)
+		}
+		passwords = append(passwords, result.Password)
+	}
+	slices.Sort(passwords)
+	if !slices.Equal(passwords, []string{"one", "two"}) {
+		t.Errorf("unexpected results %v", passwords)
+	}
+}
+
+func TestSearch_InvalidTarget(t *testing.T) {
+	client, err := New(Options{Sources: []string{"proxynova"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var results []Result
+	for result := range client.Search(context.Background(), "not an email", TypeEmail) {
+		results = append(results, result)
+	}
+	if len(results) != 1 || results[0].Error == nil {
+		t.Errorf("expected a single error result, got %+v", results)
+	}
+}
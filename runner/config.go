@@ -1,11 +1,14 @@
 package runner
 
 import (
+	"fmt"
 	"github.com/vflame6/leaker/logger"
+	"github.com/vflame6/leaker/runner/sources"
 	"gopkg.in/yaml.v3"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 // createProviderConfigYAML marshals the input map to the given location on the disk
@@ -31,7 +34,7 @@ func createProviderConfigYAML(configFilePath string) error {
 	}()
 
 	sourcesRequiringApiKeysMap := make(map[string][]string)
-	for _, source := range AllSources {
+	for _, source := range sources.NewSources() {
 		if source.UsesKey() {
 			sourceName := strings.ToLower(source.Name())
 			sourcesRequiringApiKeysMap[sourceName] = []string{}
@@ -41,24 +44,88 @@ func createProviderConfigYAML(configFilePath string) error {
 	return yaml.NewEncoder(configFile).Encode(sourcesRequiringApiKeysMap)
 }
 
-// UnmarshalFrom writes the marshaled yaml config to disk
-func UnmarshalFrom(file string) error {
+// providerSettingsKey is the reserved top-level key of the provider config
+// that holds ProviderSettings instead of API keys.
+const providerSettingsKey = "settings"
+
+// ProviderSettings holds the optional settings section of the provider
+// config:
+//
+//	settings:
+//	  proxy-username: alice
+//	  proxy-password: s3cret
+//	  sources:
+//	    intelx:
+//	      proxy: socks5h://eu-egress.example.com:1080
+//	      timeout: 60s
+//	      budget: 5m
+//	      max-requests: 50
+type ProviderSettings struct {
+	// ProxyUsername and ProxyPassword are applied to every proxy URL that
+	// does not carry its own credentials. LEAKER_PROXY_USERNAME and
+	// LEAKER_PROXY_PASSWORD take precedence.
+	ProxyUsername string                    `yaml:"proxy-username"`
+	ProxyPassword string                    `yaml:"proxy-password"`
+	Sources       map[string]SourceSettings `yaml:"sources"`
+	// Notifications are the sinks --notify sends findings to.
+	Notifications []NotificationSettings `yaml:"notifications"`
+}
+
+// SourceSettings overrides run options for a single source.
+type SourceSettings struct {
+	// Proxy routes the source through its own proxy instead of --proxy.
+	Proxy string `yaml:"proxy"`
+	// Timeout replaces --timeout for each HTTP request of the source.
+	Timeout time.Duration `yaml:"timeout"`
+	// Budget caps the total time the source may spend on one target.
+	Budget time.Duration `yaml:"budget"`
+	// MaxRequests caps the number of HTTP requests the source may send
+	// for one target.
+	MaxRequests int `yaml:"max-requests"`
+}
+
+// validate rejects negative limits.
+func (s SourceSettings) validate() error {
+	if s.Timeout < 0 || s.Budget < 0 || s.MaxRequests < 0 {
+		return fmt.Errorf("timeout, budget and max-requests must not be negative")
+	}
+	return nil
+}
+
+// UnmarshalFrom reads the provider config: the API keys of each source,
+// keyed by lower-cased source name, and the settings section. Keys are
+// returned rather than applied so every runner configures its own source
+// instances.
+func UnmarshalFrom(file string) (map[string][]string, ProviderSettings, error) {
+	var settings ProviderSettings
+	keys := make(map[string][]string)
+
 	reader, err := os.Open(file)
 	if err != nil {
-		return err
+		return keys, settings, err
 	}
+	defer func() {
+		_ = reader.Close()
+	}()
 
-	sourceApiKeysMap := map[string][]string{}
-	err = yaml.NewDecoder(reader).Decode(sourceApiKeysMap)
-	for _, source := range AllSources {
-		sourceName := strings.ToLower(source.Name())
-		apiKeys := sourceApiKeysMap[sourceName]
+	providerConfig := map[string]yaml.Node{}
+	err = yaml.NewDecoder(reader).Decode(providerConfig)
+	if node, ok := providerConfig[providerSettingsKey]; ok {
+		if decodeErr := node.Decode(&settings); decodeErr != nil {
+			logger.Errorf("Could not read %s section of provider config: %s", providerSettingsKey, decodeErr)
+		}
+	}
+	for _, sourceName := range sources.SourceNames() {
+		var apiKeys []string
+		if node, ok := providerConfig[sourceName]; ok {
+			if decodeErr := node.Decode(&apiKeys); decodeErr != nil {
+				logger.Errorf("Could not read API keys for %s: %s", sourceName, decodeErr)
+			}
+		}
 		if len(apiKeys) > 0 {
 			logger.Debugf("API key(s) found for %s.", sourceName)
-			source.AddApiKeys(apiKeys)
-		} else if source.NeedsKey() {
-			logger.Debugf("Cannot use the %s source because there is no API key/secret defined for it.", sourceName)
+			keys[sourceName] = apiKeys
 		}
 	}
-	return err
+	return keys, settings, err
 }
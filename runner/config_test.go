@@ -5,7 +5,9 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/vflame6/leaker/runner/sources"
 	"gopkg.in/yaml.v3"
 )
 
@@ -49,10 +51,10 @@ func TestCreateProviderConfigYAML_ContainsSourcesNeedingKeys(t *testing.T) {
 	data, _ := os.ReadFile(path)
 	content := string(data)
 
-	// At least one source in AllSources requires a key (LeakCheck does).
+	// At least one registered source requires a key (LeakCheck does).
 	// The YAML should contain that source name.
 	foundAny := false
-	for _, source := range AllSources {
+	for _, source := range sources.NewSources() {
 		if source.UsesKey() {
 			if strings.Contains(content, strings.ToLower(source.Name())) {
 				foundAny = true
@@ -77,13 +79,17 @@ func TestUnmarshalFrom_ValidYAML(t *testing.T) {
 	}
 
 	// UnmarshalFrom should not error on a valid file
-	if err := UnmarshalFrom(path); err != nil {
+	keys, _, err := UnmarshalFrom(path)
+	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
+	if len(keys["leakcheck"]) != 1 || keys["leakcheck"][0] != "fakekey123" {
+		t.Errorf("expected the leakcheck key to be returned, got %v", keys)
+	}
 }
 
 func TestUnmarshalFrom_MissingFile(t *testing.T) {
-	err := UnmarshalFrom("/nonexistent/path/config.yaml")
+	_, _, err := UnmarshalFrom("/nonexistent/path/config.yaml")
 	if err == nil {
 		t.Error("expected error for missing config file")
 	}
@@ -96,8 +102,42 @@ func TestUnmarshalFrom_MalformedYAML(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	err := UnmarshalFrom(path)
+	_, _, err := UnmarshalFrom(path)
 	if err == nil {
 		t.Error("expected error for malformed YAML")
 	}
 }
+
+func TestUnmarshalFrom_Settings(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	content := `leakcheck: [fakekey123]
+settings:
+  proxy-username: alice
+  proxy-password: s3cret
+  sources:
+    intelx:
+      proxy: socks5h://eu-egress.example.com:1080
+      timeout: 90s
+      budget: 5m
+      max-requests: 50
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, settings, err := UnmarshalFrom(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if settings.ProxyUsername != "alice" || settings.ProxyPassword != "s3cret" {
+		t.Errorf("unexpected proxy credentials: %+v", settings)
+	}
+	if got := settings.Sources["intelx"].Proxy; got != "socks5h://eu-egress.example.com:1080" {
+		t.Errorf("unexpected intelx proxy: %q", got)
+	}
+	intelx := settings.Sources["intelx"]
+	if intelx.Timeout != 90*time.Second || intelx.Budget != 5*time.Minute || intelx.MaxRequests != 50 {
+		t.Errorf("unexpected intelx limits: %+v", intelx)
+	}
+}
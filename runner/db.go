@@ -62,6 +62,17 @@ var leaksIndexDDLs = []string{
 	`CREATE INDEX idx_leaks_phone    ON leaks(phone)    WHERE phone    != ''`,
 }
 
+// auxiliaryDDLs create bookkeeping tables that live next to leaks. They
+// are not covered by the schema hash and use IF NOT EXISTS, so caches
+// created by older versions gain them on the next writable open.
+var auxiliaryDDLs = []string{
+	quotaUsageDDL,
+	jobsDDL,
+	jobResultsDDL,
+	watchlistDDL,
+	monitorSeenDDL,
+}
+
 // allLeakColumns is the ordered list of data columns on the leaks table,
 // excluding the primary-key checksum and the non-content metadata columns.
 // Used by Search when the scan type maps to "every column" (domain, keyword).
@@ -94,6 +105,19 @@ type LeakerDB struct {
 	path       string
 	writable   bool
 	insertStmt *sql.Stmt
+	log        *logger.Logger // nil uses the package logger
+}
+
+// SetLogger makes Search log through log instead of the package logger.
+func (l *LeakerDB) SetLogger(log *logger.Logger) {
+	l.log = log
+}
+
+func (l *LeakerDB) logger() *logger.Logger {
+	if l.log == nil {
+		return logger.DefaultLogger
+	}
+	return l.log
 }
 
 // OpenLeakerDB opens (or creates) the SQLite database at path, verifies
@@ -158,6 +182,12 @@ func OpenLeakerDB(path string, writable bool) (*LeakerDB, error) {
 	}
 
 	if writable {
+		for _, ddl := range auxiliaryDDLs {
+			if _, err := db.Exec(ddl); err != nil {
+				_ = db.Close()
+				return nil, fmt.Errorf("create auxiliary table: %w", err)
+			}
+		}
 		stmt, err := db.Prepare(insertSQL)
 		if err != nil {
 			_ = db.Close()
@@ -348,13 +378,13 @@ func (l *LeakerDB) Search(ctx context.Context, target string, scanType sources.S
 				&checksum, &_origSrc, &email, &username, &password,
 				&hashField, &salt, &ip, &phone, &name, &database, &url, &extraJSON,
 			); err != nil {
-				logger.Errorf("local DB row scan: %s", err)
+				l.logger().Errorf("local DB row scan: %s", err)
 				continue
 			}
 
 			extra, err := decodeExtra(extraJSON)
 			if err != nil {
-				logger.Errorf("local DB extra decode: %s", err)
+				l.logger().Errorf("local DB extra decode: %s", err)
 				// continue with nil extra rather than skipping the row
 			}
 
@@ -403,6 +433,14 @@ func searchColumnsFor(scanType sources.ScanType) []string {
 		return []string{"phone", "username"}
 	case sources.TypeDomain, sources.TypeKeyword:
 		return allLeakColumns
+	case sources.TypeHash:
+		return []string{"hash"}
+	case sources.TypeIP:
+		return []string{"ip"}
+	case sources.TypeName:
+		return []string{"name"}
+	case sources.TypePassword:
+		return []string{"password"}
 	}
 	return nil
 }
@@ -1,9 +1,11 @@
 package runner
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -347,6 +349,16 @@ func TestLeakerDB_Search_ColumnMapping(t *testing.T) {
 		// keyword: all columns
 		{"keyword→ip", sources.TypeKeyword, "tok.ip", true},
 		{"keyword→salt", sources.TypeKeyword, "toksalt", true},
+
+		// pivots: the matching column only
+		{"hash→hash", sources.TypeHash, "tokhash", true},
+		{"hash→salt", sources.TypeHash, "toksalt", false},
+		{"ip→ip", sources.TypeIP, "tok.ip", true},
+		{"ip→email", sources.TypeIP, "tok-email", false},
+		{"name→name", sources.TypeName, "tok-name", true},
+		{"name→username", sources.TypeName, "tok-user", false},
+		{"password→password", sources.TypePassword, "tok-pass", true},
+		{"password→email", sources.TypePassword, "tok-email", false},
 	}
 
 	for _, c := range cases {
@@ -445,3 +457,54 @@ func collectSearch(t *testing.T, db *LeakerDB, target string, st sources.ScanTyp
 	}
 	return out
 }
+
+func TestLeakerDB_SaveQuotaUsage_Accumulates(t *testing.T) {
+	db, err := OpenLeakerDB(tempDBPath(t), true)
+	if err != nil {
+		t.Fatalf("OpenLeakerDB: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	keyID := sources.QuotaKeyID("key")
+	run := []sources.QuotaUsage{{Source: "dehashed", KeyID: keyID, Consumed: 3, Remaining: 97}}
+	if err := db.SaveQuotaUsage(run); err != nil {
+		t.Fatalf("SaveQuotaUsage: %v", err)
+	}
+	run = []sources.QuotaUsage{{Source: "dehashed", KeyID: keyID, Consumed: 2, Remaining: -1}}
+	if err := db.SaveQuotaUsage(run); err != nil {
+		t.Fatalf("SaveQuotaUsage: %v", err)
+	}
+
+	stored, err := db.QuotaUsage()
+	if err != nil {
+		t.Fatalf("QuotaUsage: %v", err)
+	}
+	if len(stored) != 1 || stored[0].Consumed != 5 || stored[0].Remaining != 97 {
+		t.Errorf("expected 5 credits consumed and the last known balance kept, got %+v", stored)
+	}
+}
+
+func TestWriteQuotaUsage(t *testing.T) {
+	updated := time.Date(2026, 1, 2, 3, 4, 5, 0, time.Local)
+	var out bytes.Buffer
+	err := WriteQuotaUsage(&out, []StoredQuota{
+		{Source: "dehashed", KeyID: "ab12", Consumed: 5, Remaining: 97, UpdatedAt: updated},
+		{Source: "leakradar", KeyID: "cd34", Consumed: 40, Remaining: -1, Exhausted: true, UpdatedAt: updated},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "SOURCE") {
+		t.Fatalf("unexpected table:\n%s", out.String())
+	}
+	if fields := strings.Fields(lines[1]); strings.Join(fields[:5], " ") != "dehashed ab12 5 97 false" {
+		t.Errorf("unexpected row %q", lines[1])
+	}
+	if fields := strings.Fields(lines[2]); strings.Join(fields[:5], " ") != "leakradar cd34 40 unknown true" {
+		t.Errorf("unexpected row %q", lines[2])
+	}
+	if !strings.Contains(lines[1], "2026-01-02 03:04:05") {
+		t.Errorf("expected the update time in %q", lines[1])
+	}
+}
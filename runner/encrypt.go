@@ -0,0 +1,115 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"filippo.io/age"
+)
+
+// ParseAgeRecipients parses --encrypt-to values. Each value is an age
+// X25519 public key ("age1...") or a file of them, one per line, as read
+// by age -R.
+func ParseAgeRecipients(values []string) ([]age.Recipient, error) {
+	var recipients []age.Recipient
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if strings.HasPrefix(value, "age1") {
+			recipient, err := age.ParseX25519Recipient(value)
+			if err != nil {
+				return nil, err
+			}
+			recipients = append(recipients, recipient)
+			continue
+		}
+		file, err := os.Open(value)
+		if err != nil {
+			return nil, fmt.Errorf("recipient %q is neither an age public key nor a readable file: %w", value, err)
+		}
+		parsed, err := age.ParseRecipients(file)
+		_ = file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("could not read recipients from %s: %w", value, err)
+		}
+		recipients = append(recipients, parsed...)
+	}
+	return recipients, nil
+}
+
+// NewAgeWriter encrypts what is written to w to the given recipients or,
+// without recipients, to passphrase. The output is streamed in chunks, so
+// nothing is buffered beyond the current one; Close must be called to
+// write the last chunk.
+func NewAgeWriter(w io.Writer, recipients []string, passphrase string) (io.WriteCloser, error) {
+	if len(recipients) > 0 && passphrase != "" {
+		return nil, errors.New("encrypt to recipients or to a passphrase, not both")
+	}
+	if passphrase != "" {
+		recipient, err := age.NewScryptRecipient(passphrase)
+		if err != nil {
+			return nil, err
+		}
+		return age.Encrypt(w, recipient)
+	}
+	parsed, err := ParseAgeRecipients(recipients)
+	if err != nil {
+		return nil, err
+	}
+	if len(parsed) == 0 {
+		return nil, errors.New("no age recipients")
+	}
+	return age.Encrypt(w, parsed...)
+}
+
+// DecryptAge streams the age-encrypted src to dst, using the identities
+// in identityFiles (as written by age-keygen) and, when set, passphrase.
+func DecryptAge(dst io.Writer, src io.Reader, identityFiles []string, passphrase string) error {
+	var identities []age.Identity
+	for _, path := range identityFiles {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		parsed, err := age.ParseIdentities(file)
+		_ = file.Close()
+		if err != nil {
+			return fmt.Errorf("could not read identities from %s: %w", path, err)
+		}
+		identities = append(identities, parsed...)
+	}
+	if passphrase != "" {
+		identity, err := age.NewScryptIdentity(passphrase)
+		if err != nil {
+			return err
+		}
+		identities = append(identities, identity)
+	}
+	if len(identities) == 0 {
+		return errors.New("decrypting needs an identity file or a passphrase")
+	}
+
+	plaintext, err := age.Decrypt(src, identities...)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, plaintext)
+	return err
+}
+
+// encryptedFile is an output file encrypted with age. Closing it writes
+// the last chunk, then closes the file.
+type encryptedFile struct {
+	io.WriteCloser
+	file *os.File
+}
+
+func (f *encryptedFile) Close() error {
+	err := f.WriteCloser.Close()
+	if closeErr := f.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
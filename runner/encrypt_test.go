@@ -0,0 +1,148 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/vflame6/leaker/runner/sources"
+)
+
+func newTestIdentity(t *testing.T, dir, name string) (*age.X25519Identity, string) {
+	t.Helper()
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte("# created by a test\n"+identity.String()+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return identity, path
+}
+
+func TestAgeWriter_RecipientsRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	alice, aliceKey := newTestIdentity(t, dir, "alice.key")
+	bob, bobKey := newTestIdentity(t, dir, "bob.key")
+	recipientsFile := filepath.Join(dir, "recipients.txt")
+	if err := os.WriteFile(recipientsFile, []byte("# team\n"+bob.Recipient().String()+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// larger than one age chunk, to exercise streaming
+	plaintext := strings.Repeat("alice@example.com:hunter2\n", 5000)
+	var encrypted bytes.Buffer
+	w, err := NewAgeWriter(&encrypted, []string{alice.Recipient().String(), recipientsFile}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.WriteString(w, plaintext); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(encrypted.Bytes(), []byte("hunter2")) {
+		t.Fatal("ciphertext contains the plaintext")
+	}
+
+	for _, key := range []string{aliceKey, bobKey} {
+		var decrypted bytes.Buffer
+		if err := DecryptAge(&decrypted, bytes.NewReader(encrypted.Bytes()), []string{key}, ""); err != nil {
+			t.Fatalf("%s: %v", key, err)
+		}
+		if decrypted.String() != plaintext {
+			t.Errorf("%s: decrypted text differs", key)
+		}
+	}
+
+	_, otherKey := newTestIdentity(t, dir, "other.key")
+	if err := DecryptAge(io.Discard, bytes.NewReader(encrypted.Bytes()), []string{otherKey}, ""); err == nil {
+		t.Error("expected an error for a non-recipient identity")
+	}
+}
+
+func TestAgeWriter_Passphrase(t *testing.T) {
+	var encrypted bytes.Buffer
+	w, err := NewAgeWriter(&encrypted, nil, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = io.WriteString(w, "secret\n")
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var decrypted bytes.Buffer
+	if err := DecryptAge(&decrypted, bytes.NewReader(encrypted.Bytes()), nil, "correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	if decrypted.String() != "secret\n" {
+		t.Errorf("unexpected plaintext %q", decrypted.String())
+	}
+	if err := DecryptAge(io.Discard, bytes.NewReader(encrypted.Bytes()), nil, "wrong"); err == nil {
+		t.Error("expected an error for a wrong passphrase")
+	}
+}
+
+func TestAgeWriter_InvalidConfiguration(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, tc := range map[string]struct {
+		recipients []string
+		passphrase string
+	}{
+		"both":         {[]string{identity.Recipient().String()}, "pass"},
+		"none":         {nil, ""},
+		"bad key":      {[]string{"age1notakey"}, ""},
+		"missing file": {[]string{filepath.Join(t.TempDir(), "missing")}, ""},
+	} {
+		if _, err := NewAgeWriter(io.Discard, tc.recipients, tc.passphrase); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if err := DecryptAge(io.Discard, strings.NewReader(""), nil, ""); err == nil {
+		t.Error("expected an error without identities")
+	}
+}
+
+func TestRunEnumeration_EncryptsOutputFile(t *testing.T) {
+	dir := t.TempDir()
+	identity, key := newTestIdentity(t, dir, "key.txt")
+	r := newTestRunner([]string{})
+	r.scanSources = []sources.Source{&fakeSource{name: "fake", emits: []sources.Result{
+		{Source: "fake", Email: "alice@example.com", Password: "hunter2"},
+	}}}
+	r.options.Type = sources.TypeEmail
+	r.options.Targets = "alice@example.com"
+	r.options.Output = io.Discard
+	r.options.JSON = true
+	r.options.OutputFile = filepath.Join(dir, "results.jsonl.age")
+	r.options.EncryptTo = []string{identity.Recipient().String()}
+
+	if err := r.RunEnumeration(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(r.options.OutputFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("hunter2")) {
+		t.Fatal("output file is not encrypted")
+	}
+	var decrypted bytes.Buffer
+	if err := DecryptAge(&decrypted, bytes.NewReader(data), []string{key}, ""); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(decrypted.String(), `"password":"hunter2"`) {
+		t.Errorf("unexpected decrypted output %q", decrypted.String())
+	}
+}
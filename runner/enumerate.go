@@ -2,13 +2,16 @@ package runner
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/vflame6/leaker/logger"
 	"github.com/vflame6/leaker/runner/sources"
+	"github.com/vflame6/leaker/utils"
 )
 
 // maxDBWriteErrors caps how many consecutive local-DB insert failures
@@ -18,9 +21,19 @@ import (
 const maxDBWriteErrors = 5
 
 func (r *Runner) EnumerateSingleTarget(ctx context.Context, target string, scanType sources.ScanType, timeout time.Duration, writers []io.Writer) error {
+	_, err := r.enumerateTarget(ctx, target, scanType, timeout, writers)
+	return err
+}
+
+// enumerateTarget runs every selected source against a single target and
+// writes the results. When --expand-emails is set and the target is a
+// domain, it also returns the distinct email addresses at that domain
+// found in the written results, in discovery order.
+func (r *Runner) enumerateTarget(ctx context.Context, target string, scanType sources.ScanType, timeout time.Duration, writers []io.Writer) ([]string, error) {
 	var err error
 
 	logger.Infof("Enumerating leaks for %s", target)
+	r.stats.target()
 	results := make(chan sources.Result)
 	numberOfResults := 0
 	timeStart := time.Now()
@@ -30,17 +43,31 @@ func (r *Runner) EnumerateSingleTarget(ctx context.Context, target string, scanT
 	// Process the results in a separate goroutine
 	verifier := NewVerifier(r.options.Verify)
 	seen := make(map[string]struct{})
+	expand := r.options.ExpandEmails && scanType == sources.TypeDomain
+	var discovered []string
+	discoveredSeen := make(map[string]struct{})
 	dbWriteErrors := 0
 	dbWriteSuppressed := false
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
 		for result := range results {
-			// check if error
+			// check if error; a source stopping on its budget is only a warning
+			if result.Error != nil {
+				r.stats.failed(result.Source, result.Error)
+				if sinkErr := r.errorSink.record(target, result); sinkErr != nil {
+					logger.Errorf("could not write to the errors file: %s", sinkErr)
+				}
+			}
+			if errors.Is(result.Error, sources.ErrBudgetExceeded) {
+				logger.Warnf("%s stopped early for %s: %s", result.Source, target, result.Error)
+				continue
+			}
 			if result.Error != nil {
 				logger.Errorf("error on enumerating target %s: %s", target, result.Error)
 				continue
 			}
+			r.stats.returned(result.Source)
 			// Normalize whitespace on every incoming result before any other
 			// check. Sources occasionally return fields that are only spaces
 			// (e.g. `name: " "`), which would otherwise slip past HasData()
@@ -70,7 +97,11 @@ func (r *Runner) EnumerateSingleTarget(ctx context.Context, target string, scanT
 			// so results that originated from the local DB itself are not
 			// re-written (their Source has been overwritten to "local").
 			if r.leakerDB != nil && result.Source != sources.LocalSourceName {
-				if insertErr := r.leakerDB.Insert(&result); insertErr != nil {
+				stored := &result
+				if r.options.RedactDB {
+					stored = r.redact(&result)
+				}
+				if insertErr := r.leakerDB.Insert(stored); insertErr != nil {
 					if !dbWriteSuppressed {
 						dbWriteErrors++
 						logger.Errorf("could not write result to local DB: %s", insertErr)
@@ -89,18 +120,34 @@ func (r *Runner) EnumerateSingleTarget(ctx context.Context, target string, scanT
 
 			// increase number of results
 			numberOfResults++
+			r.stats.kept(result.Source)
+			if r.onResult != nil {
+				r.onResult(target, &result)
+			}
 
-			// write result
+			// collect email addresses for --expand-emails
+			if expand && utils.EmailInDomain(result.Email, target) {
+				email := strings.ToLower(result.Email)
+				if _, ok := discoveredSeen[email]; !ok {
+					discoveredSeen[email] = struct{}{}
+					discovered = append(discovered, email)
+				}
+			}
+
+			// write result, redacted as requested; reports and exports
+			// count the original credentials and redact on their own
+			output := r.redact(&result)
 			for _, writer := range writers {
-				if r.options.JSON {
-					err = WriteJSONResult(writer, r.options.Metadata, &result, target)
-				} else {
-					err = WritePlainResult(writer, r.options.Verbose, r.options.Metadata, &result)
+				written := output
+				switch writer.(type) {
+				case *Report, *Findings:
+					written = &result
 				}
-				if err != nil {
+				if err = r.writeResult(writer, written, target); err != nil {
 					logger.Errorf("could not write results for %s: %s", target, err)
 				}
 			}
+			r.notifier.result(target, output, time.Time{})
 		}
 	}()
 
@@ -120,24 +167,20 @@ func (r *Runner) EnumerateSingleTarget(ctx context.Context, target string, scanT
 			}
 		}
 
-		session, sessErr := sources.NewSession(timeout, r.options.UserAgent, r.options.Proxy, r.options.Insecure)
+		sessions, sessErr := r.newSessionSet(timeout)
 		if sessErr != nil {
 			results <- sources.Result{
 				Error: fmt.Errorf("could not initiate passive session for %s: %w", target, sessErr),
 			}
 			return
 		}
-		defer session.Close()
+		defer sessions.Close()
 
 		// Drain local sources serially. In practice there's at most one,
 		// but the loop handles N defensively.
 		for _, s := range localSources {
-			for result := range s.Run(ctx, target, scanType, session) {
-				select {
-				case results <- result:
-				case <-ctx.Done():
-					return
-				}
+			if !r.runSource(ctx, s, target, scanType, sessions.get(s.Name()), results) {
+				return
 			}
 		}
 
@@ -154,12 +197,8 @@ func (r *Runner) EnumerateSingleTarget(ctx context.Context, target string, scanT
 			go func(s sources.Source) {
 				defer owg.Done()
 
-				for result := range s.Run(ctx, target, scanType, session) {
-					select {
-					case results <- result:
-					case <-ctx.Done():
-						return
-					}
+				if !r.runSource(ctx, s, target, scanType, sessions.get(s.Name()), results) {
+					return
 				}
 
 				// sleep to enable source rate-limiting
@@ -175,10 +214,76 @@ func (r *Runner) EnumerateSingleTarget(ctx context.Context, target string, scanT
 
 	if ctx.Err() != nil {
 		logger.Info("Interrupted")
-		return nil
+		return nil, nil
 	}
 
 	timeElapsed := time.Since(timeStart).Truncate(time.Millisecond)
 	logger.Infof("Found %d leaks for %s in %v", numberOfResults, target, timeElapsed)
-	return nil
+	if len(discovered) > 0 {
+		logger.Infof("Discovered %d email addresses for %s, enumerating them as new targets", len(discovered), target)
+	}
+	return discovered, nil
+}
+
+// runSource streams the results of one source into results. When the
+// source has a per-target time budget it runs under a deadline; errors
+// caused by an exhausted budget or request allowance are collapsed into a
+// single warning result wrapping sources.ErrBudgetExceeded. It returns
+// false if ctx was cancelled.
+func (r *Runner) runSource(ctx context.Context, s sources.Source, target string, scanType sources.ScanType, session *sources.Session, results chan<- sources.Result) bool {
+	start := time.Now()
+	defer func() {
+		r.stats.queried(s.Name(), time.Since(start))
+	}()
+
+	sourceCtx := ctx
+	budget := r.sourceSettings[s.Name()].Budget
+	if budget > 0 {
+		var cancel context.CancelFunc
+		sourceCtx, cancel = context.WithTimeoutCause(ctx, budget, sources.ErrBudgetExceeded)
+		defer cancel()
+	}
+
+	budgetExceeded := func() bool {
+		return ctx.Err() == nil && errors.Is(context.Cause(sourceCtx), sources.ErrBudgetExceeded)
+	}
+	warned := false
+	warn := func(err error) bool {
+		if warned {
+			return true
+		}
+		warned = true
+		select {
+		case results <- sources.Result{Source: s.Name(), Error: err}:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	for result := range s.Run(sourceCtx, target, scanType, session) {
+		if result.Error != nil && errors.Is(result.Error, sources.ErrBudgetExceeded) {
+			if !warn(result.Error) {
+				return false
+			}
+			continue
+		}
+		if result.Error != nil && budgetExceeded() {
+			if !warn(fmt.Errorf("%w: time budget of %s used up", sources.ErrBudgetExceeded, budget)) {
+				return false
+			}
+			continue
+		}
+		select {
+		case results <- result:
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	// Sources that check their context stop silently; still report it.
+	if budgetExceeded() {
+		return warn(fmt.Errorf("%w: time budget of %s used up", sources.ErrBudgetExceeded, budget))
+	}
+	return ctx.Err() == nil
 }
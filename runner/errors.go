@@ -0,0 +1,119 @@
+package runner
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/vflame6/leaker/runner/sources"
+	"github.com/vflame6/leaker/utils"
+)
+
+// Exit codes of a leaker run. Source failures take precedence over found
+// leaks: the results of such a run may be incomplete, and the leaks are
+// still written to the output.
+const (
+	ExitNoLeaks        = 0 // run completed, no leaks found
+	ExitFatal          = 1 // run could not complete
+	ExitLeaksFound     = 2 // run completed and found leaks
+	ExitPartialFailure = 3 // one or more sources failed for at least one target
+)
+
+// ErrTargetsFailed is wrapped by the error of a run in which some targets
+// could not be searched. The results of the other targets were written.
+var ErrTargetsFailed = errors.New("some targets could not be searched")
+
+// ExitCode returns the exit code describing the finished run.
+func (r *Runner) ExitCode() int {
+	summary := r.stats.snapshot(nil)
+	return exitCode(summary.Failures, summary.Results)
+}
+
+// MonitorExitCode returns the exit code describing a finished monitor,
+// which found leaks only if it reported new ones.
+func (r *Runner) MonitorExitCode(reported int) int {
+	return exitCode(r.stats.snapshot(nil).Failures, reported)
+}
+
+func exitCode(failures, leaks int) int {
+	switch {
+	case failures > 0:
+		return ExitPartialFailure
+	case leaks > 0:
+		return ExitLeaksFound
+	default:
+		return ExitNoLeaks
+	}
+}
+
+// SourceError is one error reported by a source, as written to
+// --errors-json.
+type SourceError struct {
+	Target   string             `json:"target"`
+	Source   string             `json:"source"`
+	Category sources.ErrorClass `json:"category"`
+	Error    string             `json:"error"`
+}
+
+// errorSink writes source errors as JSON lines. A nil *errorSink ignores
+// every call.
+type errorSink struct {
+	mu      sync.Mutex
+	file    io.WriteCloser
+	encoder *json.Encoder
+	// typed adds "type": "error" to every record, for streams that mix
+	// errors with results.
+	typed bool
+}
+
+// typedSourceError is a SourceError in a stream that mixes errors with
+// results, which carry no type.
+type typedSourceError struct {
+	Type string `json:"type"`
+	SourceError
+}
+
+// openErrorSink creates the --errors-json file, or returns nil when no
+// file was requested.
+func openErrorSink(path string, overwrite bool) (*errorSink, error) {
+	if path == "" {
+		return nil, nil
+	}
+	file, err := utils.CreateFileWithSafe(path, false, overwrite)
+	if err != nil {
+		return nil, err
+	}
+	return newErrorSink(file), nil
+}
+
+func newErrorSink(file io.WriteCloser) *errorSink {
+	return &errorSink{file: file, encoder: json.NewEncoder(file)}
+}
+
+// record writes the error carried by result.
+func (e *errorSink) record(target string, result sources.Result) error {
+	if e == nil || result.Error == nil {
+		return nil
+	}
+	record := SourceError{
+		Target:   target,
+		Source:   result.Source,
+		Category: sources.ClassifyError(result.Error),
+		Error:    result.Error.Error(),
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.typed {
+		return e.encoder.Encode(typedSourceError{Type: "error", SourceError: record})
+	}
+	return e.encoder.Encode(record)
+}
+
+// Close closes the underlying file.
+func (e *errorSink) Close() error {
+	if e == nil {
+		return nil
+	}
+	return e.file.Close()
+}
@@ -0,0 +1,158 @@
+package runner
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vflame6/leaker/runner/sources"
+)
+
+func TestExitCode(t *testing.T) {
+	leak := sources.Result{Source: "one", Email: "a@b.com", Password: "p"}
+	unauthorized := sources.Result{Source: "two", Error: errors.New("two returned status 401: bad key")}
+	budget := sources.Result{Source: "two", Error: fmt.Errorf("%w: request limit of 1 reached", sources.ErrBudgetExceeded)}
+
+	tests := []struct {
+		name  string
+		emits []sources.Result
+		want  int
+	}{
+		{"no leaks", nil, ExitNoLeaks},
+		{"leaks found", []sources.Result{leak}, ExitLeaksFound},
+		{"budget stop is not a failure", []sources.Result{leak, budget}, ExitLeaksFound},
+		{"source failure", []sources.Result{unauthorized}, ExitPartialFailure},
+		{"failure wins over leaks", []sources.Result{leak, unauthorized}, ExitPartialFailure},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRunner([]string{})
+			r.stats = newRunStats()
+			r.scanSources = []sources.Source{&fakeSource{name: "one", emits: tt.emits}}
+			var out bytes.Buffer
+			if err := r.EnumerateSingleTarget(context.Background(), "a@b.com", sources.TypeEmail, 5*time.Second, []io.Writer{&out}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := r.ExitCode(); got != tt.want {
+				t.Errorf("expected exit code %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestMonitorExitCode(t *testing.T) {
+	r := newTestRunner([]string{})
+	r.stats = newRunStats()
+	if got := r.MonitorExitCode(0); got != ExitNoLeaks {
+		t.Errorf("expected %d without new leaks, got %d", ExitNoLeaks, got)
+	}
+	if got := r.MonitorExitCode(2); got != ExitLeaksFound {
+		t.Errorf("expected %d with new leaks, got %d", ExitLeaksFound, got)
+	}
+	r.stats.failed("two", errors.New("two returned status 401: bad key"))
+	if got := r.MonitorExitCode(2); got != ExitPartialFailure {
+		t.Errorf("expected %d after a source failure, got %d", ExitPartialFailure, got)
+	}
+}
+
+// refusingWriter is an output writer that can't be opened for a target.
+type refusingWriter struct{ io.Writer }
+
+func (refusingWriter) OpenTarget(target string) error {
+	return fmt.Errorf("cannot write %s", target)
+}
+
+func TestEnumerateMultipleTargets_FailedTargetIsPartialFailure(t *testing.T) {
+	r := newTestRunner([]string{})
+	r.stats = newRunStats()
+	r.scanSources = []sources.Source{&fakeSource{name: "one", emits: []sources.Result{
+		{Source: "one", Email: "a@b.com", Password: "p"},
+	}}}
+	r.options.Type = sources.TypeEmail
+	err := r.EnumerateMultipleTargets(context.Background(), strings.NewReader("a@b.com\n"), []io.Writer{refusingWriter{io.Discard}})
+	if !errors.Is(err, ErrTargetsFailed) {
+		t.Fatalf("expected ErrTargetsFailed, got %v", err)
+	}
+	if got := r.ExitCode(); got != ExitPartialFailure {
+		t.Errorf("expected exit code %d, got %d", ExitPartialFailure, got)
+	}
+}
+
+func TestErrorSink_WritesSourceErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "errors.jsonl")
+	sink, err := openErrorSink(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := newTestRunner([]string{})
+	r.errorSink = sink
+	r.scanSources = []sources.Source{&fakeSource{name: "two", emits: []sources.Result{
+		{Source: "two", Error: errors.New("two returned status 429: slow down")},
+		{Source: "two", Email: "a@b.com", Password: "p"},
+	}}}
+	var out bytes.Buffer
+	if err := r.EnumerateSingleTarget(context.Background(), "a@b.com", sources.TypeEmail, 5*time.Second, []io.Writer{&out}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+	var got []SourceError
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e SourceError
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("invalid JSON line %q: %v", scanner.Text(), err)
+		}
+		got = append(got, e)
+	}
+	if len(got) != 1 || got[0].Target != "a@b.com" || got[0].Source != "two" || got[0].Category != sources.ErrorRateLimit {
+		t.Errorf("unexpected errors file contents: %+v", got)
+	}
+
+	if _, err := openErrorSink(path, false); err == nil {
+		t.Error("expected an existing errors file to be refused without --overwrite")
+	}
+}
+
+func TestRunEnumeration_ErrorSinkCheckedBeforeOutput(t *testing.T) {
+	dir := t.TempDir()
+	errorsPath := filepath.Join(dir, "errors.jsonl")
+	if err := os.WriteFile(errorsPath, []byte("keep me\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	searches := 0
+	r := newTestRunner([]string{})
+	r.scanSources = []sources.Source{&fakeSource{name: "one", onStart: func() { searches++ }}}
+	r.options.Type = sources.TypeEmail
+	r.options.Targets = "a@b.com"
+	r.options.Output = io.Discard
+	r.options.OutputFile = filepath.Join(dir, "results.txt")
+	r.options.ErrorsJSON = errorsPath
+
+	if err := r.RunEnumeration(context.Background()); err == nil {
+		t.Fatal("expected an error for the existing errors file")
+	}
+	if searches != 0 {
+		t.Errorf("expected no searches, got %d", searches)
+	}
+	if _, err := os.Stat(r.options.OutputFile); !os.IsNotExist(err) {
+		t.Errorf("expected no output file, got %v", err)
+	}
+}
@@ -0,0 +1,138 @@
+package runner
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/vflame6/leaker/runner/sources"
+	"github.com/vflame6/leaker/utils"
+)
+
+// How STIX and MISP exports carry clear-text passwords, set by
+// --export-passwords.
+const (
+	ExportPasswordsPlain = "plain" // as found
+	ExportPasswordsHash  = "hash"  // SHA-256 hex digest
+	ExportPasswordsOmit  = "omit"  // left out
+)
+
+// Finding is a result together with the target it was found for.
+type Finding struct {
+	Target string
+	Result sources.Result
+}
+
+// Findings collects the results of a run for the STIX and MISP exporters.
+// Unlike Report it keeps every field as found, so it is only held in
+// memory until the exports are written. Clear-text passwords are redacted
+// as they are exported.
+type Findings struct {
+	io.Writer
+	Generated time.Time
+	Results   []Finding
+	// Redact and RedactKey apply a --redact level to exported passwords.
+	Redact    string
+	RedactKey string
+}
+
+// NewFindings creates an empty collection.
+func NewFindings() *Findings {
+	return &Findings{Writer: io.Discard, Generated: time.Now()}
+}
+
+// Add records a result found for target.
+func (f *Findings) Add(result *sources.Result, target string) {
+	f.Results = append(f.Results, Finding{Target: target, Result: *result})
+}
+
+// ReadJSONLFindings collects the results of leaker JSONL output.
+func ReadJSONLFindings(r io.Reader) (*Findings, error) {
+	findings := NewFindings()
+	return findings, findings.ReadJSONL(r)
+}
+
+// ReadJSONL adds the results of leaker JSONL output to the collection.
+func (f *Findings) ReadJSONL(r io.Reader) error {
+	return readJSONL(r, f.Add)
+}
+
+// readJSONL calls add for every result of leaker JSONL output.
+func readJSONL(r io.Reader, add func(*sources.Result, string)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var jr jsonResult
+		if err := json.Unmarshal([]byte(text), &jr); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		add(&sources.Result{
+			Source:   jr.Source,
+			Email:    jr.Email,
+			Username: jr.Username,
+			Password: jr.Password,
+			Hash:     jr.Hash,
+			Salt:     jr.Salt,
+			IP:       jr.IP,
+			Phone:    jr.Phone,
+			Name:     jr.Name,
+			Database: jr.Database,
+			URL:      jr.URL,
+			Extra:    jr.Extra,
+		}, jr.Target)
+	}
+	return scanner.Err()
+}
+
+// exportPassword returns password as it should appear in an export, and
+// whether it was hashed. Passwords exported in clear text are redacted.
+func (f *Findings) exportPassword(password, mode string) (string, bool) {
+	switch {
+	case password == "" || mode == ExportPasswordsOmit:
+		return "", false
+	case mode == ExportPasswordsHash:
+		digest, _ := hashString("sha256", password)
+		return digest, true
+	}
+	return redactPassword(password, f.Redact, f.RedactKey), false
+}
+
+// WriteExportFiles writes the STIX 2.1 bundle and the MISP event of
+// findings to the given paths; an empty path skips that export.
+func WriteExportFiles(findings *Findings, stixPath, mispPath, passwords string, overwrite bool) error {
+	for _, out := range []struct {
+		path  string
+		build func(*Findings, string) any
+	}{
+		{stixPath, func(f *Findings, p string) any { return NewSTIXBundle(f, p) }},
+		{mispPath, func(f *Findings, p string) any { return NewMISPEvent(f, p) }},
+	} {
+		if out.path == "" {
+			continue
+		}
+		file, err := utils.CreateFileWithSafe(out.path, false, overwrite)
+		if err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(file)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(out.build(findings, passwords))
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("could not write export %s: %w", out.path, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,397 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vflame6/leaker/runner/sources"
+)
+
+func testFindings() *Findings {
+	findings := NewFindings()
+	findings.Generated = time.Date(2026, 5, 4, 3, 2, 1, 0, time.UTC)
+	findings.Add(&sources.Result{Source: "leakcheck", Email: "alice@example.com", Password: "hunter2", Database: "Combo"}, "example.com")
+	findings.Add(&sources.Result{Source: "snusbase", Email: "alice@example.com", Username: "alice", Hash: "5f4dcc3b5aa765d61d8327deb882cf99", Salt: "s4lt", Database: "Forum"}, "example.com")
+	findings.Add(&sources.Result{Source: "proxynova", Username: "bob", Password: "pw"}, "example.com")
+	findings.Add(&sources.Result{Source: "seed", IP: "10.0.0.1"}, "example.com")
+	return findings
+}
+
+// decodeObjects round-trips v through JSON so the tests check what is
+// written rather than the Go structs.
+func decodeObjects(t *testing.T, v any) map[string]any {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	return decoded
+}
+
+var (
+	stixTimestamp   = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?Z$`)
+	stixCustomName  = regexp.MustCompile(`^x_[a-z0-9_]{3,250}$`)
+	stixCommonProps = []string{"type", "spec_version", "id", "created_by_ref", "created", "modified", "labels"}
+)
+
+// stixProperties are the properties STIX 2.1 defines for each exported
+// object type, besides the common ones.
+var stixProperties = map[string][]string{
+	"identity":      {"name", "identity_class"},
+	"email-addr":    {"value", "belongs_to_ref"},
+	"user-account":  {"user_id", "display_name", "credential"},
+	"observed-data": {"first_observed", "last_observed", "number_observed", "object_refs"},
+}
+
+// validateSTIXID checks that id is "<type>--<UUID>" with the given UUID
+// version.
+func validateSTIXID(t *testing.T, id, objectType string, version uuid.Version) {
+	t.Helper()
+	prefix, rest, ok := strings.Cut(id, "--")
+	if !ok || prefix != objectType {
+		t.Errorf("id %q does not start with %s--", id, objectType)
+		return
+	}
+	parsed, err := uuid.Parse(rest)
+	if err != nil || parsed.Version() != version || parsed.Variant() != uuid.RFC4122 {
+		t.Errorf("id %q is not a UUIDv%d", id, version)
+	}
+}
+
+func TestSTIXBundle_MatchesSpec(t *testing.T) {
+	bundle := decodeObjects(t, NewSTIXBundle(testFindings(), ExportPasswordsPlain))
+	if bundle["type"] != "bundle" {
+		t.Fatalf("unexpected bundle type %v", bundle["type"])
+	}
+	validateSTIXID(t, bundle["id"].(string), "bundle", 4)
+	if _, ok := bundle["spec_version"]; ok {
+		t.Error("a STIX 2.1 bundle has no spec_version")
+	}
+
+	objects := bundle["objects"].([]any)
+	ids := make(map[string]string)
+	counts := make(map[string]int)
+	for _, raw := range objects {
+		object := raw.(map[string]any)
+		objectType, _ := object["type"].(string)
+		id, _ := object["id"].(string)
+		if _, dup := ids[id]; dup {
+			t.Errorf("duplicate id %s", id)
+		}
+		ids[id] = objectType
+		counts[objectType]++
+
+		if object["spec_version"] != "2.1" {
+			t.Errorf("%s: spec_version is %v", id, object["spec_version"])
+		}
+		allowed, known := stixProperties[objectType]
+		if !known {
+			t.Errorf("unexpected object type %q", objectType)
+			continue
+		}
+		for name := range object {
+			if !slices.Contains(stixCommonProps, name) && !slices.Contains(allowed, name) && !stixCustomName.MatchString(name) {
+				t.Errorf("%s: property %q is neither defined for %s nor a valid custom property", id, name, objectType)
+			}
+		}
+
+		switch objectType {
+		case "identity", "observed-data":
+			validateSTIXID(t, id, objectType, 4)
+			for _, name := range []string{"created", "modified"} {
+				if value, _ := object[name].(string); !stixTimestamp.MatchString(value) {
+					t.Errorf("%s: %s %q is not a STIX timestamp", id, name, value)
+				}
+			}
+		case "user-account":
+			// accounts with a credential are kept apart by a random ID
+			version := uuid.Version(5)
+			if object["credential"] != nil || object["x_leaker_password_hash"] != nil {
+				version = 4
+			}
+			validateSTIXID(t, id, objectType, version)
+		default:
+			validateSTIXID(t, id, objectType, 5)
+		}
+
+		switch objectType {
+		case "identity":
+			if object["name"] != "leaker" || object["identity_class"] != "system" {
+				t.Errorf("unexpected identity %v", object)
+			}
+		case "email-addr":
+			if object["value"] == "" {
+				t.Errorf("%s: email-addr without value", id)
+			}
+		case "user-account":
+			if object["user_id"] == nil && object["account_login"] == nil {
+				t.Errorf("%s: user-account without user_id", id)
+			}
+		case "observed-data":
+			if n, _ := object["number_observed"].(float64); n < 1 || n > 999999999 {
+				t.Errorf("%s: number_observed %v is out of range", id, object["number_observed"])
+			}
+			for _, name := range []string{"first_observed", "last_observed"} {
+				if value, _ := object[name].(string); !stixTimestamp.MatchString(value) {
+					t.Errorf("%s: %s %q is not a STIX timestamp", id, name, value)
+				}
+			}
+			if object["x_leaker_source"] == nil || object["x_leaker_target"] != "example.com" {
+				t.Errorf("%s: observed-data without provenance: %v", id, object)
+			}
+		}
+	}
+
+	// references must resolve within the bundle to the right object types
+	for _, raw := range objects {
+		object := raw.(map[string]any)
+		if ref, ok := object["created_by_ref"].(string); ok && ids[ref] != "identity" {
+			t.Errorf("created_by_ref %s does not point to an identity", ref)
+		}
+		if ref, ok := object["belongs_to_ref"].(string); ok && ids[ref] != "user-account" {
+			t.Errorf("belongs_to_ref %s does not point to a user-account", ref)
+		}
+		if refs, ok := object["object_refs"].([]any); ok {
+			if len(refs) == 0 {
+				t.Error("observed-data with empty object_refs")
+			}
+			for _, ref := range refs {
+				if objectType := ids[ref.(string)]; objectType != "email-addr" && objectType != "user-account" {
+					t.Errorf("object_refs entry %v does not point to an observable", ref)
+				}
+			}
+		}
+	}
+
+	// the IP-only result has no observable to export; alice's email is shared
+	want := map[string]int{"identity": 1, "email-addr": 1, "user-account": 3, "observed-data": 3}
+	for objectType, n := range want {
+		if counts[objectType] != n {
+			t.Errorf("expected %d %s objects, got %d", n, objectType, counts[objectType])
+		}
+	}
+}
+
+func TestSTIXBundle_ObservableIDsAreDeterministic(t *testing.T) {
+	findings := testFindings()
+	findings.Add(&sources.Result{Source: "seed", Username: "carol", Name: "Carol"}, "example.com")
+	first := NewSTIXBundle(findings, ExportPasswordsPlain)
+	second := NewSTIXBundle(findings, ExportPasswordsPlain)
+	for i, object := range first.Objects {
+		deterministic := object.Type == "email-addr" || (object.Type == "user-account" && object.Credential == "" && object.PasswordHash == "")
+		if deterministic && second.Objects[i].ID != object.ID {
+			t.Errorf("%s id changed between exports: %s != %s", object.Type, object.ID, second.Objects[i].ID)
+		}
+	}
+
+	// user-account IDs only use the properties STIX 2.1 defines for them
+	want := "user-account--" + uuid.NewSHA1(stixNamespace, []byte(`{"user_id":"carol"}`)).String()
+	if !slices.ContainsFunc(first.Objects, func(o *STIXObject) bool { return o.ID == want }) {
+		t.Errorf("expected carol's user-account to have id %s", want)
+	}
+
+	// the name is the compact JSON of the contributing properties, sorted
+	want = "email-addr--" + uuid.NewSHA1(stixNamespace, []byte(`{"value":"john@example.com"}`)).String()
+	if id := stixObservableID("email-addr", map[string]string{"value": "john@example.com", "display_name": ""}); id != want {
+		t.Errorf("unexpected email-addr id %s, want %s", id, want)
+	}
+}
+
+func TestExports_PasswordModes(t *testing.T) {
+	for mode, check := range map[string]func(string) bool{
+		ExportPasswordsPlain: func(out string) bool { return strings.Contains(out, "hunter2") },
+		ExportPasswordsHash: func(out string) bool {
+			// sha256("hunter2")
+			return !strings.Contains(out, `"hunter2"`) && strings.Contains(out, "f52fbd32b2b3b86ff88ef6c490628285f482af15ddcb29541f94bcf526a3f6c7")
+		},
+		ExportPasswordsOmit: func(out string) bool {
+			return !strings.Contains(out, "hunter2") && !strings.Contains(out, "f52fbd32b2b3b86f")
+		},
+	} {
+		for name, export := range map[string]any{
+			"stix": NewSTIXBundle(testFindings(), mode),
+			"misp": NewMISPEvent(testFindings(), mode),
+		} {
+			data, err := json.Marshal(export)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !check(string(data)) {
+				t.Errorf("%s with passwords %s: unexpected output %s", name, mode, data)
+			}
+		}
+	}
+
+	bundle := NewSTIXBundle(testFindings(), ExportPasswordsHash)
+	for _, object := range bundle.Objects {
+		if object.Credential != "" {
+			t.Errorf("hashed passwords must not be exported as the clear-text credential: %+v", object)
+		}
+	}
+}
+
+// mispCredentialRelations are the object relations of the MISP credential
+// template.
+var mispCredentialRelations = []string{"username", "password", "type", "origin", "format", "notification", "text"}
+
+func TestMISPEvent_MatchesSpec(t *testing.T) {
+	event := decodeObjects(t, NewMISPEvent(testFindings(), ExportPasswordsPlain))["Event"].(map[string]any)
+	for _, name := range []string{"uuid", "info", "date", "threat_level_id", "analysis", "distribution"} {
+		if value, _ := event[name].(string); value == "" {
+			t.Errorf("event %s is missing", name)
+		}
+	}
+	if _, err := time.Parse("2006-01-02", event["date"].(string)); err != nil {
+		t.Errorf("event date: %v", err)
+	}
+	if event["published"] != false || event["distribution"] != "0" {
+		t.Errorf("expected an unpublished organisation-only event, got %v / %v", event["published"], event["distribution"])
+	}
+	if event["info"] != "leaker: 4 leaked credentials for example.com" {
+		t.Errorf("unexpected info %q", event["info"])
+	}
+
+	uuids := make(map[string]bool)
+	checkUUID := func(value any) string {
+		id, _ := value.(string)
+		if _, err := uuid.Parse(id); err != nil {
+			t.Errorf("invalid uuid %q", id)
+		}
+		if uuids[id] {
+			t.Errorf("duplicate uuid %s", id)
+		}
+		uuids[id] = true
+		return id
+	}
+	checkAttribute := func(attribute map[string]any) {
+		checkUUID(attribute["uuid"])
+		for _, name := range []string{"type", "category", "value", "distribution"} {
+			if value, _ := attribute[name].(string); value == "" {
+				t.Errorf("attribute %s is missing: %v", name, attribute)
+			}
+		}
+		if _, ok := attribute["to_ids"].(bool); !ok {
+			t.Errorf("attribute to_ids is missing: %v", attribute)
+		}
+	}
+
+	emails := make(map[string]bool)
+	for _, raw := range event["Attribute"].([]any) {
+		attribute := raw.(map[string]any)
+		checkAttribute(attribute)
+		if attribute["type"] != "email" || attribute["category"] != "Social network" {
+			t.Errorf("unexpected event attribute %v", attribute)
+		}
+		emails[attribute["uuid"].(string)] = true
+	}
+	if len(emails) != 1 {
+		t.Errorf("expected one email attribute, got %d", len(emails))
+	}
+
+	objects := event["Object"].([]any)
+	if len(objects) != 3 {
+		t.Fatalf("expected 3 credential objects, got %d", len(objects))
+	}
+	for _, raw := range objects {
+		object := raw.(map[string]any)
+		checkUUID(object["uuid"])
+		if object["name"] != "credential" || object["meta-category"] != "misc" || object["template_uuid"] != mispCredentialTemplate {
+			t.Errorf("unexpected object header %v", object)
+		}
+		if comment, _ := object["comment"].(string); !strings.HasPrefix(comment, "source: ") {
+			t.Errorf("object without provenance comment: %v", object)
+		}
+		relations := make(map[string]string)
+		for _, rawAttribute := range object["Attribute"].([]any) {
+			attribute := rawAttribute.(map[string]any)
+			checkAttribute(attribute)
+			relation, _ := attribute["object_relation"].(string)
+			if !slices.Contains(mispCredentialRelations, relation) {
+				t.Errorf("relation %q is not part of the credential template", relation)
+			}
+			relations[relation] = attribute["value"].(string)
+		}
+		if relations["username"] == "" && relations["password"] == "" && relations["text"] == "" {
+			t.Errorf("credential object needs a username, password or text: %v", relations)
+		}
+		if relations["origin"] != "leak" {
+			t.Errorf("expected the leak origin, got %q", relations["origin"])
+		}
+		if format := relations["format"]; format != "" && format != "clear-text" && format != "hashed" {
+			t.Errorf("unexpected credential format %q", format)
+		}
+		if references, ok := object["ObjectReference"].([]any); ok {
+			for _, rawReference := range references {
+				reference := rawReference.(map[string]any)
+				if !emails[reference["referenced_uuid"].(string)] || reference["relationship_type"] == "" {
+					t.Errorf("dangling object reference %v", reference)
+				}
+			}
+		}
+		if relations["username"] == "alice" && (relations["password"] != "5f4dcc3b5aa765d61d8327deb882cf99" || relations["format"] != "hashed" || relations["text"] != "salt: s4lt") {
+			t.Errorf("unexpected hashed credential %v", relations)
+		}
+	}
+}
+
+func TestReadJSONLFindings(t *testing.T) {
+	input := `{"source":"leakcheck","target":"example.com","email":"alice@example.com","password":"hunter2"}
+
+{"source":"snusbase","target":"example.com","email":"bob@example.com","hash":"abc","extra":{"k":"v"}}
+`
+	findings, err := ReadJSONLFindings(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(findings.Results) != 2 || findings.Results[0].Result.Password != "hunter2" || findings.Results[1].Result.Extra["k"] != "v" {
+		t.Errorf("unexpected findings: %+v", findings.Results)
+	}
+	if _, err := ReadJSONLFindings(strings.NewReader("{not json}\n")); err == nil {
+		t.Error("expected an error for invalid JSON")
+	}
+}
+
+func TestRunEnumeration_WritesExports(t *testing.T) {
+	dir := t.TempDir()
+	r := newTestRunner([]string{})
+	r.scanSources = []sources.Source{&fakeSource{name: "fake", emits: []sources.Result{
+		{Source: "fake", Email: "alice@example.com", Password: "hunter2"},
+	}}}
+	r.options.Type = sources.TypeEmail
+	r.options.Targets = "alice@example.com"
+	r.options.Output = io.Discard
+	r.options.STIX = filepath.Join(dir, "bundle.json")
+	r.options.MISP = filepath.Join(dir, "event.json")
+	r.options.ExportPasswords = ExportPasswordsOmit
+
+	if err := r.RunEnumeration(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{r.options.STIX, r.options.MISP} {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !json.Valid(data) || !strings.Contains(string(data), "alice@example.com") || strings.Contains(string(data), "hunter2") {
+			t.Errorf("%s: unexpected export %s", path, data)
+		}
+	}
+
+	// existing export files are refused before the run starts
+	if err := r.RunEnumeration(context.Background()); err == nil {
+		t.Error("expected an error for an existing export file")
+	}
+}
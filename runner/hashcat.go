@@ -0,0 +1,267 @@
+package runner
+
+import (
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/vflame6/leaker/logger"
+	"github.com/vflame6/leaker/utils"
+)
+
+// HashMode is a hashcat mode a leaked hash can be cracked with.
+type HashMode struct {
+	Mode int    // hashcat -m
+	Name string // hash type, as reported in the hash_type extra field
+	// John is the John the Ripper --format. John reads unsalted lines as
+	// they are, but takes "hash:salt" for "user:hash", so salted modes
+	// map to a dynamic format with its own "$dynamic_N$hash$salt" file.
+	John string
+	// Salted modes take the breach salt as hash:salt; the others either
+	// have no salt or embed it in the hash.
+	Salted bool
+}
+
+// Modular crypt formats embed their salt, so one mode fits each prefix.
+var cryptHashModes = []struct {
+	pattern *regexp.Regexp
+	mode    HashMode
+}{
+	{regexp.MustCompile(`^\$2[aby]\$`), HashMode{Mode: 3200, Name: "bcrypt", John: "bcrypt"}},
+	{regexp.MustCompile(`^\$1\$`), HashMode{Mode: 500, Name: "md5crypt", John: "md5crypt"}},
+	{regexp.MustCompile(`^\$5\$`), HashMode{Mode: 7400, Name: "sha256crypt", John: "sha256crypt"}},
+	{regexp.MustCompile(`^\$6\$`), HashMode{Mode: 1800, Name: "sha512crypt", John: "sha512crypt"}},
+	{regexp.MustCompile(`^\$argon2`), HashMode{Mode: 34000, Name: "argon2", John: "argon2"}},
+}
+
+// rawHashModes are the candidate modes of unsalted hex hashes by length.
+// A 32 character hash is as likely MD5 as NTLM, so both are tried.
+var rawHashModes = map[int][]HashMode{
+	32:  {{Mode: 0, Name: "md5", John: "raw-md5"}, {Mode: 1000, Name: "ntlm", John: "nt"}},
+	40:  {{Mode: 100, Name: "sha1", John: "raw-sha1"}},
+	64:  {{Mode: 1400, Name: "sha256", John: "raw-sha256"}},
+	128: {{Mode: 1700, Name: "sha512", John: "raw-sha512"}},
+}
+
+// saltedHashModes are the candidate modes of hex hashes that come with a
+// salt. Breaches rarely say whether the salt was appended or prepended,
+// so both orders are tried.
+var saltedHashModes = map[int][]HashMode{
+	32: {
+		{Mode: 10, Name: "md5($pass.$salt)", John: "dynamic_1", Salted: true},
+		{Mode: 20, Name: "md5($salt.$pass)", John: "dynamic_4", Salted: true},
+	},
+	40: {
+		{Mode: 110, Name: "sha1($pass.$salt)", John: "dynamic_24", Salted: true},
+		{Mode: 120, Name: "sha1($salt.$pass)", John: "dynamic_25", Salted: true},
+	},
+	64: {
+		{Mode: 1410, Name: "sha256($pass.$salt)", John: "dynamic_62", Salted: true},
+		{Mode: 1420, Name: "sha256($salt.$pass)", John: "dynamic_61", Salted: true},
+	},
+	128: {
+		{Mode: 1710, Name: "sha512($pass.$salt)", John: "dynamic_82", Salted: true},
+		{Mode: 1720, Name: "sha512($salt.$pass)", John: "dynamic_81", Salted: true},
+	},
+}
+
+// hashModes returns the hashcat modes hash may be cracked with, most
+// likely first. More than one mode means the hash is ambiguous; none
+// means its type is unknown.
+func hashModes(hash, salt string) []HashMode {
+	for _, crypt := range cryptHashModes {
+		if crypt.pattern.MatchString(hash) {
+			return []HashMode{crypt.mode}
+		}
+	}
+	if !isHex(hash) {
+		return nil
+	}
+	if salt != "" {
+		return saltedHashModes[len(hash)]
+	}
+	return rawHashModes[len(hash)]
+}
+
+// hashLine is the hash[:salt] line of hash for mode.
+func hashLine(hash, salt string, mode HashMode) string {
+	if !mode.Salted {
+		return hash
+	}
+	return hash + ":" + salt
+}
+
+// johnLine is the "$dynamic_N$hash$salt" line of a salted hash for John.
+// Salts John would split on are hex encoded as "$HEX$...".
+func johnLine(hash, salt string, mode HashMode) string {
+	if strings.ContainsAny(salt, "$:\r\n") {
+		salt = "HEX$" + hex.EncodeToString([]byte(salt))
+	}
+	return "$" + mode.John + "$" + hash + "$" + salt
+}
+
+// hashFile collects the lines of one hashcat mode.
+type hashFile struct {
+	HashMode
+	lines     []string
+	johnLines []string
+	seen      map[string]bool
+}
+
+// hashDirFiles are the names of every file WriteHashFiles may write.
+func hashDirFiles() []string {
+	names := []string{ambiguousHashFile}
+	for _, crypt := range cryptHashModes {
+		names = append(names, hashFileName(crypt.mode))
+	}
+	for _, modes := range rawHashModes {
+		for _, mode := range modes {
+			names = append(names, hashFileName(mode))
+		}
+	}
+	for _, modes := range saltedHashModes {
+		for _, mode := range modes {
+			names = append(names, hashFileName(mode), johnFileName(mode))
+		}
+	}
+	return names
+}
+
+// existingHashFile returns the path of a file in dir that WriteHashFiles
+// would overwrite, or "" if there is none.
+func existingHashFile(dir string) string {
+	for _, name := range hashDirFiles() {
+		if path := filepath.Join(dir, name); utils.FileExists(path) {
+			return path
+		}
+	}
+	return ""
+}
+
+// WriteHashFiles writes the hashes of findings to dir, one file per
+// hashcat mode named "<mode>-<type>.txt" with one hash[:salt] line per
+// hash. Salted modes also get a "<mode>-<type>.john.txt" file in John's
+// dynamic format. Ambiguous hashes go to every candidate mode and are
+// also listed in ambiguous.txt with their candidates; hashes of unknown
+// type are skipped. Unless overwrite is set, nothing is written when any
+// of the files exists.
+func WriteHashFiles(findings *Findings, dir string, overwrite bool) error {
+	if !overwrite {
+		if path := existingHashFile(dir); path != "" {
+			return fmt.Errorf("file already exists: %s", path)
+		}
+	}
+
+	files := make(map[int]*hashFile)
+	var ambiguous []string
+	seenAmbiguous := make(map[string]bool)
+	unknown := 0
+	for _, finding := range findings.Results {
+		hash, salt := strings.TrimSpace(finding.Result.Hash), finding.Result.Salt
+		if hash == "" {
+			continue
+		}
+		modes := hashModes(hash, salt)
+		if len(modes) == 0 {
+			unknown++
+			continue
+		}
+		for _, mode := range modes {
+			file, ok := files[mode.Mode]
+			if !ok {
+				file = &hashFile{HashMode: mode, seen: make(map[string]bool)}
+				files[mode.Mode] = file
+			}
+			if line := hashLine(hash, salt, mode); !file.seen[line] {
+				file.seen[line] = true
+				file.lines = append(file.lines, line)
+				if mode.Salted {
+					file.johnLines = append(file.johnLines, johnLine(hash, salt, mode))
+				}
+			}
+		}
+		if len(modes) > 1 {
+			line := hashLine(hash, salt, modes[0])
+			if !seenAmbiguous[line] {
+				seenAmbiguous[line] = true
+				candidates := make([]string, 0, len(modes))
+				for _, mode := range modes {
+					candidates = append(candidates, fmt.Sprintf("%d (%s)", mode.Mode, mode.Name))
+				}
+				ambiguous = append(ambiguous, line+"\t"+strings.Join(candidates, ", "))
+			}
+		}
+	}
+
+	modes := make([]int, 0, len(files))
+	for mode := range files {
+		modes = append(modes, mode)
+	}
+	slices.Sort(modes)
+	for _, mode := range modes {
+		file := files[mode]
+		path := filepath.Join(dir, hashFileName(file.HashMode))
+		if err := writeLines(path, file.lines, overwrite); err != nil {
+			return err
+		}
+		if !file.Salted {
+			logger.Infof("Wrote %d hashes for hashcat -m %d and john --format=%s to %s", len(file.lines), file.Mode, file.John, path)
+			continue
+		}
+		logger.Infof("Wrote %d hashes for hashcat -m %d to %s", len(file.lines), file.Mode, path)
+		johnPath := filepath.Join(dir, johnFileName(file.HashMode))
+		if err := writeLines(johnPath, file.johnLines, overwrite); err != nil {
+			return err
+		}
+		logger.Infof("Wrote %d hashes for john --format=%s to %s", len(file.johnLines), file.John, johnPath)
+	}
+	if len(ambiguous) > 0 {
+		path := filepath.Join(dir, ambiguousHashFile)
+		if err := writeLines(path, ambiguous, overwrite); err != nil {
+			return err
+		}
+		logger.Warnf("%d hashes match more than one hash type and were written to every candidate mode, see %s", len(ambiguous), path)
+	}
+	if unknown > 0 {
+		logger.Warnf("Skipped %d hashes of unknown type", unknown)
+	}
+	return nil
+}
+
+// ambiguousHashFile lists the hashes written to more than one mode.
+const ambiguousHashFile = "ambiguous.txt"
+
+// hashFileName is the file name of a hashcat mode, e.g. "1000-ntlm.txt".
+func hashFileName(mode HashMode) string {
+	return hashFileStem(mode) + ".txt"
+}
+
+// johnFileName is the file name of a salted mode's John lines, e.g.
+// "10-md5-pass-salt.john.txt".
+func johnFileName(mode HashMode) string {
+	return hashFileStem(mode) + ".john.txt"
+}
+
+func hashFileStem(mode HashMode) string {
+	name := strings.NewReplacer("($", "-", ".$", "-", ")", "").Replace(mode.Name)
+	return strconv.Itoa(mode.Mode) + "-" + name
+}
+
+func writeLines(path string, lines []string, overwrite bool) error {
+	file, err := utils.CreateFileWithSafe(path, false, overwrite)
+	if err != nil {
+		return err
+	}
+	_, err = file.WriteString(strings.Join(lines, "\n") + "\n")
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("could not write %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package runner
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/vflame6/leaker/runner/sources"
+)
+
+func TestHashModes(t *testing.T) {
+	for _, tc := range []struct {
+		hash, salt string
+		want       []int
+	}{
+		{"5f4dcc3b5aa765d61d8327deb882cf99", "", []int{0, 1000}},
+		{"5F4DCC3B5AA765D61D8327DEB882CF99", "", []int{0, 1000}},
+		{"5f4dcc3b5aa765d61d8327deb882cf99", "s4lt", []int{10, 20}},
+		{"5baa61e4c9b93f3f0682250b6cf8331b7ee68fd8", "", []int{100}},
+		{"5baa61e4c9b93f3f0682250b6cf8331b7ee68fd8", "s4lt", []int{110, 120}},
+		{strings.Repeat("a", 64), "", []int{1400}},
+		{strings.Repeat("a", 128), "x", []int{1710, 1720}},
+		{"$2y$10$abcdefghijklmnopqrstuu5JWNrHe1a8a5AhQ3b2wYvYmbXyXOqG6", "ignored", []int{3200}},
+		{"$1$salt$qJH7.N4xYta3aEG/dfqo/0", "", []int{500}},
+		{"$5$salt$Gcm6FsVtF/Qa77ZKD.iwsJlCVPY0XSMgLJL0Hnww/c1", "", []int{7400}},
+		{"$6$salt$IxDD3jeSOb5eB1CX5LBsqZFVkJdido3OUILO5Ifz5iwMuTS4XMS130MTSuDDl3aCI6WouIL9AjRbLCelDCy.g.", "", []int{1800}},
+		{"$argon2id$v=19$m=65536,t=3,p=4$c2FsdA$aGFzaA", "", []int{34000}},
+		{"not-a-hash", "", nil},
+		{strings.Repeat("a", 31), "", nil},
+	} {
+		var got []int
+		for _, mode := range hashModes(tc.hash, tc.salt) {
+			got = append(got, mode.Mode)
+		}
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("hashModes(%q, %q) = %v, want %v", tc.hash, tc.salt, got, tc.want)
+		}
+	}
+}
+
+func TestIdentifyHash_KeepsMostLikelyType(t *testing.T) {
+	for hash, want := range map[string]string{
+		"5f4dcc3b5aa765d61d8327deb882cf99":            "md5",
+		"5baa61e4c9b93f3f0682250b6cf8331b7ee68fd8":    "sha1",
+		"$2a$10$N9qo8uLOickgx2ZMRZoMyeIjZAgcfl7p92ld": "bcrypt",
+		"$6$salt$hash": "sha512crypt",
+		"zzz":          "unknown",
+	} {
+		if got := identifyHash(hash); got != want {
+			t.Errorf("identifyHash(%q) = %q, want %q", hash, got, want)
+		}
+	}
+	if got := hashcatModes("5f4dcc3b5aa765d61d8327deb882cf99", ""); got != "0,1000" {
+		t.Errorf("unexpected hashcat modes %q", got)
+	}
+}
+
+func TestWriteHashFiles(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "hashes")
+	findings := NewFindings()
+	for _, result := range []sources.Result{
+		{Source: "a", Email: "alice@example.com", Hash: "5f4dcc3b5aa765d61d8327deb882cf99"},
+		{Source: "b", Email: "alice@example.com", Hash: "5f4dcc3b5aa765d61d8327deb882cf99"},
+		{Source: "a", Email: "bob@example.com", Hash: "0d107d09f5bbe40cade3de5c71e9e9b7", Salt: "s4lt"},
+		{Source: "a", Email: "carol@example.com", Hash: "$2b$10$abcdefghijklmnopqrstuu5JWNrHe1a8a5AhQ3b2wYvYmbXyXOqG6", Salt: "unused"},
+		{Source: "a", Email: "dave@example.com", Hash: "garbage"},
+		{Source: "a", Email: "erin@example.com", Password: "plain"},
+	} {
+		findings.Add(&result, "example.com")
+	}
+
+	if err := WriteHashFiles(findings, dir, false); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"0-md5.txt":                 "5f4dcc3b5aa765d61d8327deb882cf99\n",
+		"1000-ntlm.txt":             "5f4dcc3b5aa765d61d8327deb882cf99\n",
+		"10-md5-pass-salt.txt":      "0d107d09f5bbe40cade3de5c71e9e9b7:s4lt\n",
+		"20-md5-salt-pass.txt":      "0d107d09f5bbe40cade3de5c71e9e9b7:s4lt\n",
+		"10-md5-pass-salt.john.txt": "$dynamic_1$0d107d09f5bbe40cade3de5c71e9e9b7$s4lt\n",
+		"20-md5-salt-pass.john.txt": "$dynamic_4$0d107d09f5bbe40cade3de5c71e9e9b7$s4lt\n",
+		"3200-bcrypt.txt":           "$2b$10$abcdefghijklmnopqrstuu5JWNrHe1a8a5AhQ3b2wYvYmbXyXOqG6\n",
+		"ambiguous.txt": "5f4dcc3b5aa765d61d8327deb882cf99\t0 (md5), 1000 (ntlm)\n" +
+			"0d107d09f5bbe40cade3de5c71e9e9b7:s4lt\t10 (md5($pass.$salt)), 20 (md5($salt.$pass))\n",
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != len(want) {
+		var names []string
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+		t.Errorf("unexpected files %v", names)
+	}
+	for name, content := range want {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if string(data) != content {
+			t.Errorf("%s: got %q, want %q", name, data, content)
+		}
+	}
+
+	if err := WriteHashFiles(findings, dir, false); err == nil {
+		t.Error("expected an error for existing hash files")
+	}
+	if err := WriteHashFiles(findings, dir, true); err != nil {
+		t.Errorf("overwrite: %v", err)
+	}
+}
+
+func TestWriteHashFiles_ExistingFileWritesNothing(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "1000-ntlm.txt")
+	if err := os.WriteFile(existing, []byte("keep me\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	findings := NewFindings()
+	findings.Add(&sources.Result{Source: "a", Email: "alice@example.com", Hash: "5f4dcc3b5aa765d61d8327deb882cf99"}, "example.com")
+
+	if err := WriteHashFiles(findings, dir, false); err == nil {
+		t.Error("expected an error for an existing hash file")
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected no hash files written next to the existing one, got %d files", len(entries))
+	}
+}
+
+func TestJohnLine_HexEncodesSalt(t *testing.T) {
+	mode := saltedHashModes[32][0]
+	if got := johnLine("0d107d09f5bbe40cade3de5c71e9e9b7", "s4lt", mode); got != "$dynamic_1$0d107d09f5bbe40cade3de5c71e9e9b7$s4lt" {
+		t.Errorf("unexpected line %q", got)
+	}
+	if got := johnLine("0d107d09f5bbe40cade3de5c71e9e9b7", "a:$b", mode); got != "$dynamic_1$0d107d09f5bbe40cade3de5c71e9e9b7$HEX$613a2462" {
+		t.Errorf("unexpected line %q", got)
+	}
+}
+
+func TestRunEnumeration_ExistingHashFileFailsBeforeSearching(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "0-md5.txt"), []byte("keep me\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	searches := 0
+	r := newTestRunner([]string{})
+	r.scanSources = []sources.Source{&fakeSource{name: "alpha", onStart: func() { searches++ }}}
+	r.options.Type = sources.TypeEmail
+	r.options.Targets = "alice@example.com"
+	r.options.Output = io.Discard
+	r.options.HashDir = dir
+	if err := r.RunEnumeration(context.Background()); err == nil {
+		t.Error("expected an error for an existing hash file")
+	}
+	if searches != 0 {
+		t.Errorf("expected no searches, got %d", searches)
+	}
+}
@@ -0,0 +1,196 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/vflame6/leaker/logger"
+	"github.com/vflame6/leaker/runner/sources"
+)
+
+// DefaultJobWorkers is the number of jobs run at once when no worker
+// count is given.
+const DefaultJobWorkers = 2
+
+// jobPollInterval is how often idle workers look for new jobs and running
+// jobs check whether they were cancelled from another process.
+const jobPollInterval = 2 * time.Second
+
+// JobQueue runs the jobs stored in the local DB on a pool of workers.
+// Jobs survive restarts: a job interrupted by shutdown is queued again and
+// resumes at the first target it had not completed.
+type JobQueue struct {
+	runner  *Runner
+	workers int
+	poll    time.Duration
+	wake    chan struct{}
+}
+
+// NewJobQueue creates a queue on top of a configured runner. Jobs are
+// stored in the runner's local DB, which must be writable.
+func (r *Runner) NewJobQueue(workers int) (*JobQueue, error) {
+	if r.leakerDB == nil || !r.leakerDB.writable {
+		return nil, errJobsReadOnly
+	}
+	if workers <= 0 {
+		workers = DefaultJobWorkers
+	}
+	return &JobQueue{runner: r, workers: workers, poll: jobPollInterval, wake: make(chan struct{}, 1)}, nil
+}
+
+// Submit validates and stores a new job and wakes an idle worker.
+func (q *JobQueue) Submit(scanType sources.ScanType, targets []string, options SearchOptions) (*Job, error) {
+	if len(targets) == 0 {
+		return nil, errors.New("a job needs at least one target")
+	}
+	normalized := make([]string, 0, len(targets))
+	for _, raw := range targets {
+		target, err := ParseTarget(scanType, raw)
+		if err != nil {
+			return nil, err
+		}
+		normalized = append(normalized, target)
+	}
+	// Reject unknown sources now rather than when a worker picks the job up.
+	if _, err := q.runner.fork(func(o *Options) { options.apply(o, scanType) }); err != nil {
+		return nil, err
+	}
+
+	job := &Job{Type: scanType.String(), Targets: normalized, Options: options}
+	if err := q.runner.leakerDB.CreateJob(job); err != nil {
+		return nil, err
+	}
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return job, nil
+}
+
+// Run requeues jobs left running by a previous process and executes jobs
+// until ctx is cancelled.
+func (q *JobQueue) Run(ctx context.Context) {
+	if n, err := q.runner.leakerDB.RequeueRunningJobs(); err != nil {
+		logger.Errorf("could not requeue interrupted jobs: %s", err)
+	} else if n > 0 {
+		logger.Infof("Resuming %d interrupted jobs", n)
+	}
+
+	wg := &sync.WaitGroup{}
+	for range q.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.work(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+func (q *JobQueue) work(ctx context.Context) {
+	ticker := time.NewTicker(q.poll)
+	defer ticker.Stop()
+	for {
+		job, err := q.runner.leakerDB.ClaimJob()
+		if err != nil {
+			logger.Errorf("could not claim a job: %s", err)
+		}
+		if job != nil {
+			q.execute(ctx, job)
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-q.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// execute runs a claimed job from its first incomplete target.
+func (q *JobQueue) execute(ctx context.Context, job *Job) {
+	db := q.runner.leakerDB
+	scanType, ok := sources.ParseScanType(job.Type)
+	if !ok {
+		q.fail(job, fmt.Errorf("unknown scan type %q", job.Type))
+		return
+	}
+
+	var checksums []string
+	search, err := q.runner.fork(func(o *Options) { job.Options.apply(o, scanType) })
+	if err != nil {
+		q.fail(job, err)
+		return
+	}
+	search.onResult = func(_ string, result *sources.Result) {
+		checksums = append(checksums, result.Checksum())
+	}
+
+	jobCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go q.watchCancellation(jobCtx, job.ID, cancel)
+
+	logger.Infof("Running job %s (%d of %d targets done)", job.ID, job.Progress, len(job.Targets))
+	for i := job.Progress; i < len(job.Targets); i++ {
+		target := job.Targets[i]
+		failures := search.stats.snapshot(nil).Failures
+		checksums = checksums[:0]
+		if _, err := search.enumerateTarget(jobCtx, target, scanType, q.runner.options.Timeout, []io.Writer{io.Discard}); err != nil {
+			logger.Errorf("job %s: error enumerating %s: %s", job.ID, target, err)
+		}
+		// An interrupted target is searched again when the job resumes.
+		if jobCtx.Err() != nil {
+			break
+		}
+		errorCount := search.stats.snapshot(nil).Failures - failures
+		if err := db.CompleteJobTarget(job.ID, i+1, target, checksums, errorCount); err != nil {
+			q.fail(job, err)
+			return
+		}
+	}
+
+	switch {
+	case ctx.Err() != nil:
+		// Shutdown: leave the job for the next process to resume.
+		if err := db.SetJobStatus(job.ID, JobQueued, ""); err != nil {
+			logger.Errorf("could not requeue job %s: %s", job.ID, err)
+		}
+	case jobCtx.Err() != nil:
+		logger.Infof("Job %s was cancelled", job.ID)
+	default:
+		if err := db.SetJobStatus(job.ID, JobDone, ""); err != nil {
+			logger.Errorf("could not finish job %s: %s", job.ID, err)
+		}
+		logger.Infof("Job %s finished", job.ID)
+	}
+}
+
+// watchCancellation cancels a running job once its status in the DB says
+// so, e.g. after `leaker jobs cancel` from another process.
+func (q *JobQueue) watchCancellation(ctx context.Context, id string, cancel context.CancelFunc) {
+	ticker := time.NewTicker(q.poll)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if job, err := q.runner.leakerDB.Job(id); err == nil && job.Status == JobCancelled {
+				cancel()
+				return
+			}
+		}
+	}
+}
+
+func (q *JobQueue) fail(job *Job, err error) {
+	logger.Errorf("job %s failed: %s", job.ID, err)
+	if setErr := q.runner.leakerDB.SetJobStatus(job.ID, JobFailed, err.Error()); setErr != nil {
+		logger.Errorf("could not mark job %s as failed: %s", job.ID, setErr)
+	}
+}
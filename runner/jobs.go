@@ -0,0 +1,341 @@
+package runner
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/vflame6/leaker/logger"
+	"github.com/vflame6/leaker/runner/sources"
+)
+
+// JobStatus is the lifecycle state of a queued search.
+type JobStatus string
+
+const (
+	JobQueued    JobStatus = "queued"
+	JobRunning   JobStatus = "running"
+	JobDone      JobStatus = "done"
+	JobFailed    JobStatus = "failed"
+	JobCancelled JobStatus = "cancelled"
+)
+
+// finished reports whether a job in this state will not run again.
+func (s JobStatus) finished() bool {
+	return s == JobDone || s == JobFailed || s == JobCancelled
+}
+
+// jobsDDL stores submitted jobs. progress is the number of targets
+// completed, so an interrupted job resumes at the next target.
+const jobsDDL = `CREATE TABLE IF NOT EXISTS jobs (
+    id         TEXT PRIMARY KEY NOT NULL,
+    status     TEXT NOT NULL,
+    scan_type  TEXT NOT NULL,
+    targets    TEXT NOT NULL,
+    options    TEXT NOT NULL,
+    progress   INTEGER NOT NULL DEFAULT 0,
+    results    INTEGER NOT NULL DEFAULT 0,
+    errors     INTEGER NOT NULL DEFAULT 0,
+    error      TEXT NOT NULL DEFAULT '',
+    created_at INTEGER NOT NULL,
+    updated_at INTEGER NOT NULL
+)`
+
+// jobResultsDDL links the results of a job to their rows in leaks.
+const jobResultsDDL = `CREATE TABLE IF NOT EXISTS job_results (
+    job_id   TEXT NOT NULL,
+    target   TEXT NOT NULL,
+    checksum TEXT NOT NULL,
+    PRIMARY KEY (job_id, target, checksum)
+)`
+
+const jobColumns = `id, status, scan_type, targets, options, progress, results, errors, error, created_at, updated_at`
+
+// Job is a search over one or more targets that runs in the background.
+type Job struct {
+	ID        string        `json:"id"`
+	Status    JobStatus     `json:"status"`
+	Type      string        `json:"type"`
+	Targets   []string      `json:"targets"`
+	Options   SearchOptions `json:"options"`
+	Progress  int           `json:"progress"`
+	Results   int           `json:"results"`
+	Errors    int           `json:"errors"`
+	Error     string        `json:"error,omitempty"`
+	CreatedAt time.Time     `json:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
+}
+
+// JobResult is a result linked to a job.
+type JobResult struct {
+	Target string
+	Result sources.Result
+}
+
+// ErrJobNotFound is returned for unknown job IDs.
+var ErrJobNotFound = errors.New("job not found")
+
+// errJobsReadOnly is returned when jobs are written to a read-only cache.
+var errJobsReadOnly = errors.New("jobs need a writable local DB")
+
+func newJobID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// CreateJob stores a new queued job and fills in its ID and timestamps.
+func (l *LeakerDB) CreateJob(job *Job) error {
+	if l == nil || !l.writable {
+		return errJobsReadOnly
+	}
+	targets, err := json.Marshal(job.Targets)
+	if err != nil {
+		return err
+	}
+	options, err := json.Marshal(job.Options)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	job.ID = newJobID()
+	job.Status = JobQueued
+	job.CreatedAt, job.UpdatedAt = now, now
+	_, err = l.db.Exec(`INSERT INTO jobs (id, status, scan_type, targets, options, created_at, updated_at)
+VALUES (?, ?, ?, ?, ?, ?, ?)`, job.ID, job.Status, job.Type, string(targets), string(options), now.Unix(), now.Unix())
+	return err
+}
+
+// Job returns the job with the given ID.
+func (l *LeakerDB) Job(id string) (*Job, error) {
+	if l == nil {
+		return nil, ErrJobNotFound
+	}
+	job, err := scanJob(l.db.QueryRow(`SELECT `+jobColumns+` FROM jobs WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) || isMissingTable(err) {
+		return nil, ErrJobNotFound
+	}
+	return job, err
+}
+
+// Jobs returns every job, newest first, optionally only those in status.
+func (l *LeakerDB) Jobs(status JobStatus) ([]Job, error) {
+	if l == nil {
+		return nil, nil
+	}
+	rows, err := l.db.Query(`SELECT `+jobColumns+` FROM jobs
+WHERE ? = '' OR status = ? ORDER BY created_at DESC, id`, status, status)
+	if err != nil {
+		if isMissingTable(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var jobs []Job
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, *job)
+	}
+	return jobs, rows.Err()
+}
+
+// ClaimJob marks the oldest queued job as running and returns it, or nil
+// when no job is queued.
+func (l *LeakerDB) ClaimJob() (*Job, error) {
+	for {
+		var id string
+		err := l.db.QueryRow(`SELECT id FROM jobs WHERE status = ? ORDER BY created_at, id LIMIT 1`, JobQueued).Scan(&id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		res, err := l.db.Exec(`UPDATE jobs SET status = ?, updated_at = ? WHERE id = ? AND status = ?`,
+			JobRunning, time.Now().Unix(), id, JobQueued)
+		if err != nil {
+			return nil, err
+		}
+		// Another worker or process claimed it first; try the next one.
+		if n, _ := res.RowsAffected(); n == 0 {
+			continue
+		}
+		return l.Job(id)
+	}
+}
+
+// CompleteJobTarget records that the target at index progress-1 finished:
+// it links the target's results and updates the job counters in one
+// transaction, so a resumed job never double counts.
+func (l *LeakerDB) CompleteJobTarget(id string, progress int, target string, checksums []string, errorCount int) error {
+	tx, err := l.db.Begin()
+	if err != nil {
+		return err
+	}
+	linked := 0
+	for _, checksum := range checksums {
+		res, err := tx.Exec(`INSERT OR IGNORE INTO job_results (job_id, target, checksum) VALUES (?, ?, ?)`, id, target, checksum)
+		if err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+		n, _ := res.RowsAffected()
+		linked += int(n)
+	}
+	_, err = tx.Exec(`UPDATE jobs SET progress = ?, results = results + ?, errors = errors + ?, updated_at = ? WHERE id = ?`,
+		progress, linked, errorCount, time.Now().Unix(), id)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// SetJobStatus moves a job to status unless it was cancelled meanwhile.
+func (l *LeakerDB) SetJobStatus(id string, status JobStatus, message string) error {
+	_, err := l.db.Exec(`UPDATE jobs SET status = ?, error = ?, updated_at = ? WHERE id = ? AND status != ?`,
+		status, message, time.Now().Unix(), id, JobCancelled)
+	return err
+}
+
+// CancelJob cancels a queued or running job. Running jobs stop at the
+// worker's next status check.
+func (l *LeakerDB) CancelJob(id string) error {
+	if l == nil || !l.writable {
+		return errJobsReadOnly
+	}
+	job, err := l.Job(id)
+	if err != nil {
+		return err
+	}
+	if job.Status.finished() {
+		return fmt.Errorf("job %s is already %s", id, job.Status)
+	}
+	_, err = l.db.Exec(`UPDATE jobs SET status = ?, updated_at = ? WHERE id = ? AND status IN (?, ?)`,
+		JobCancelled, time.Now().Unix(), id, JobQueued, JobRunning)
+	return err
+}
+
+// RequeueRunningJobs puts jobs left running by a stopped process back in
+// the queue so they resume from their last completed target.
+func (l *LeakerDB) RequeueRunningJobs() (int, error) {
+	res, err := l.db.Exec(`UPDATE jobs SET status = ?, updated_at = ? WHERE status = ?`,
+		JobQueued, time.Now().Unix(), JobRunning)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// JobResults returns the results linked to a job, in the order they were
+// found.
+func (l *LeakerDB) JobResults(ctx context.Context, id string) ([]JobResult, error) {
+	if l == nil {
+		return nil, nil
+	}
+	rows, err := l.db.QueryContext(ctx, `SELECT r.target, l.checksum, l.source, l.email, l.username, l.password,
+       l.hash, l.salt, l.ip, l.phone, l.name, l.database, l.url, l.extra
+  FROM job_results r JOIN leaks l ON l.checksum = r.checksum
+ WHERE r.job_id = ? ORDER BY r.rowid`, id)
+	if err != nil {
+		if isMissingTable(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var results []JobResult
+	for rows.Next() {
+		var jr JobResult
+		var checksum, extraJSON string
+		r := &jr.Result
+		if err := rows.Scan(&jr.Target, &checksum, &r.Source, &r.Email, &r.Username, &r.Password,
+			&r.Hash, &r.Salt, &r.IP, &r.Phone, &r.Name, &r.Database, &r.URL, &extraJSON); err != nil {
+			return nil, err
+		}
+		if r.Extra, err = decodeExtra(extraJSON); err != nil {
+			logger.Errorf("local DB extra decode: %s", err)
+		}
+		r.SetCachedChecksum(checksum)
+		results = append(results, jr)
+	}
+	return results, rows.Err()
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanJob(row rowScanner) (*Job, error) {
+	var job Job
+	var targets, options string
+	var created, updated int64
+	if err := row.Scan(&job.ID, &job.Status, &job.Type, &targets, &options, &job.Progress,
+		&job.Results, &job.Errors, &job.Error, &created, &updated); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(targets), &job.Targets); err != nil {
+		return nil, fmt.Errorf("job %s targets: %w", job.ID, err)
+	}
+	if err := json.Unmarshal([]byte(options), &job.Options); err != nil {
+		return nil, fmt.Errorf("job %s options: %w", job.ID, err)
+	}
+	job.CreatedAt = time.Unix(created, 0)
+	job.UpdatedAt = time.Unix(updated, 0)
+	return &job, nil
+}
+
+func isMissingTable(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "no such table")
+}
+
+// WriteJobList writes jobs as an aligned table.
+func WriteJobList(w io.Writer, jobs []Job) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "ID\tSTATUS\tTYPE\tPROGRESS\tRESULTS\tERRORS\tCREATED")
+	for _, job := range jobs {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%d/%d\t%d\t%d\t%s\n", job.ID, job.Status, job.Type,
+			job.Progress, len(job.Targets), job.Results, job.Errors, job.CreatedAt.Format(time.DateTime))
+	}
+	return tw.Flush()
+}
+
+// WriteJob writes the details of a job.
+func WriteJob(w io.Writer, job *Job) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintf(tw, "ID:\t%s\n", job.ID)
+	_, _ = fmt.Fprintf(tw, "Status:\t%s\n", job.Status)
+	_, _ = fmt.Fprintf(tw, "Type:\t%s\n", job.Type)
+	_, _ = fmt.Fprintf(tw, "Targets:\t%s\n", strings.Join(job.Targets, ", "))
+	if len(job.Options.Sources) > 0 {
+		_, _ = fmt.Fprintf(tw, "Sources:\t%s\n", strings.Join(job.Options.Sources, ", "))
+	}
+	_, _ = fmt.Fprintf(tw, "Progress:\t%d/%d targets\n", job.Progress, len(job.Targets))
+	_, _ = fmt.Fprintf(tw, "Results:\t%d\n", job.Results)
+	_, _ = fmt.Fprintf(tw, "Errors:\t%d\n", job.Errors)
+	if job.Error != "" {
+		_, _ = fmt.Fprintf(tw, "Error:\t%s\n", job.Error)
+	}
+	_, _ = fmt.Fprintf(tw, "Created:\t%s\n", job.CreatedAt.Format(time.DateTime))
+	_, _ = fmt.Fprintf(tw, "Updated:\t%s\n", job.UpdatedAt.Format(time.DateTime))
+	return tw.Flush()
+}
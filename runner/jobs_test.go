@@ -0,0 +1,285 @@
+package runner
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vflame6/leaker/runner/sources"
+)
+
+func openTestLeakerDB(t *testing.T) *LeakerDB {
+	t.Helper()
+	db, err := OpenLeakerDB(tempDBPath(t), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+func TestLeakerDB_JobLifecycle(t *testing.T) {
+	db := openTestLeakerDB(t)
+	seed := sources.Result{Source: "seed", Email: "alice@example.com", Password: "one"}
+	if err := db.Insert(&seed); err != nil {
+		t.Fatal(err)
+	}
+
+	job := &Job{Type: "email", Targets: []string{"alice@example.com", "bob@example.com"}}
+	if err := db.CreateJob(job); err != nil {
+		t.Fatal(err)
+	}
+	if job.ID == "" || job.Status != JobQueued {
+		t.Fatalf("unexpected new job: %+v", job)
+	}
+
+	claimed, err := db.ClaimJob()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claimed == nil || claimed.ID != job.ID || claimed.Status != JobRunning {
+		t.Fatalf("unexpected claimed job: %+v", claimed)
+	}
+	if again, err := db.ClaimJob(); err != nil || again != nil {
+		t.Fatalf("expected no second claim, got %+v, %v", again, err)
+	}
+
+	if err := db.CompleteJobTarget(job.ID, 1, "alice@example.com", []string{seed.Checksum()}, 1); err != nil {
+		t.Fatal(err)
+	}
+	got, err := db.Job(job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Progress != 1 || got.Results != 1 || got.Errors != 1 {
+		t.Errorf("unexpected progress: %+v", got)
+	}
+
+	results, err := db.JobResults(context.Background(), job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Target != "alice@example.com" || results[0].Result.Password != "one" {
+		t.Errorf("unexpected job results: %+v", results)
+	}
+
+	if err := db.SetJobStatus(job.ID, JobDone, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.CancelJob(job.ID); err == nil {
+		t.Error("expected an error cancelling a finished job")
+	}
+}
+
+func TestLeakerDB_CancelledJobStaysCancelled(t *testing.T) {
+	db := openTestLeakerDB(t)
+	job := &Job{Type: "email", Targets: []string{"alice@example.com"}}
+	if err := db.CreateJob(job); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.CancelJob(job.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SetJobStatus(job.ID, JobDone, ""); err != nil {
+		t.Fatal(err)
+	}
+	got, err := db.Job(job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != JobCancelled {
+		t.Errorf("expected cancelled, got %s", got.Status)
+	}
+	if claimed, err := db.ClaimJob(); err != nil || claimed != nil {
+		t.Errorf("cancelled job must not be claimed, got %+v, %v", claimed, err)
+	}
+}
+
+func TestLeakerDB_RequeueRunningJobs(t *testing.T) {
+	db := openTestLeakerDB(t)
+	job := &Job{Type: "email", Targets: []string{"alice@example.com"}}
+	if err := db.CreateJob(job); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.ClaimJob(); err != nil {
+		t.Fatal(err)
+	}
+	n, err := db.RequeueRunningJobs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("expected 1 requeued job, got %d", n)
+	}
+	queued, err := db.Jobs(JobQueued)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queued) != 1 || queued[0].ID != job.ID {
+		t.Errorf("unexpected queued jobs: %+v", queued)
+	}
+}
+
+func TestLeakerDB_JobNotFound(t *testing.T) {
+	db := openTestLeakerDB(t)
+	if _, err := db.Job("missing"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("expected ErrJobNotFound, got %v", err)
+	}
+}
+
+func TestNewJobQueue_RequiresWritableDB(t *testing.T) {
+	if _, err := newTestRunner(nil).NewJobQueue(1); err == nil {
+		t.Error("expected an error without a local DB")
+	}
+}
+
+func TestJobQueue_RunsJobFromLocalDB(t *testing.T) {
+	db := openTestLeakerDB(t)
+	for _, r := range []sources.Result{
+		{Source: "seed", Email: "alice@example.com", Password: "one"},
+		{Source: "seed", Email: "alice@example.com", Password: "two"},
+	} {
+		if err := db.Insert(&r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r := newTestRunner([]string{sources.LocalSourceName})
+	r.leakerDB = db
+
+	queue, err := r.NewJobQueue(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	queue.poll = 10 * time.Millisecond
+	job, err := queue.Submit(sources.TypeEmail, []string{" Alice@Example.com ", "bob@example.com"}, SearchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Targets[0] != "alice@example.com" {
+		t.Errorf("expected a normalized target, got %q", job.Targets[0])
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		queue.Run(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		got, err := db.Job(job.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Status == JobDone {
+			if got.Progress != 2 || got.Results != 2 {
+				t.Errorf("unexpected finished job: %+v", got)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job did not finish, status %s", got.Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	results, err := db.JobResults(context.Background(), job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Errorf("expected 2 job results, got %d", len(results))
+	}
+}
+
+func TestJobQueue_SubmitRejectsInvalidTargets(t *testing.T) {
+	r := newTestRunner([]string{sources.LocalSourceName})
+	r.leakerDB = openTestLeakerDB(t)
+	queue, err := r.NewJobQueue(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := queue.Submit(sources.TypeEmail, nil, SearchOptions{}); err == nil {
+		t.Error("expected an error without targets")
+	}
+	if _, err := queue.Submit(sources.TypeEmail, []string{"not-an-email"}, SearchOptions{}); err == nil {
+		t.Error("expected an error for an invalid email")
+	}
+	if _, err := queue.Submit(sources.TypeEmail, []string{"alice@example.com"}, SearchOptions{Sources: []string{"nope"}}); err == nil {
+		t.Error("expected an error for an unknown source")
+	}
+}
+
+func TestServer_JobEndpoints(t *testing.T) {
+	server, ts := newTestServer(t, 1)
+	server.queue.poll = 10 * time.Millisecond
+
+	resp := apiRequest(t, http.MethodPost, ts.URL+"/api/v1/jobs",
+		`{"type":"email","targets":["alice@example.com"],"sources":["local"]}`, nil)
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", resp.StatusCode)
+	}
+	var job Job
+	if err := json.NewDecoder(resp.Body).Decode(&job); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		server.queue.Run(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		resp := apiRequest(t, http.MethodGet, ts.URL+"/api/v1/jobs/"+job.ID, "", nil)
+		var got Job
+		if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Status == JobDone {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job did not finish, status %s", got.Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	resp = apiRequest(t, http.MethodGet, ts.URL+"/api/v1/jobs/"+job.ID+"/results", "", nil)
+	lines := 0
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if !strings.Contains(scanner.Text(), `"target":"alice@example.com"`) {
+			t.Errorf("unexpected result line: %s", scanner.Text())
+		}
+		lines++
+	}
+	if lines != 2 {
+		t.Errorf("expected 2 results, got %d", lines)
+	}
+
+	resp = apiRequest(t, http.MethodDelete, ts.URL+"/api/v1/jobs/"+job.ID, "", nil)
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("expected 409 cancelling a finished job, got %d", resp.StatusCode)
+	}
+	resp = apiRequest(t, http.MethodGet, ts.URL+"/api/v1/jobs/missing", "", nil)
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,223 @@
+package runner
+
+import (
+	"cmp"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/vflame6/leaker/runner/sources"
+)
+
+// mispCredentialTemplate is the UUID of the MISP "credential" object
+// template.
+const mispCredentialTemplate = "a27e98c9-9b0e-414c-8076-d201e039ca09"
+
+// MISP enumerations used by the export.
+const (
+	mispThreatLevelLow         = "3"
+	mispAnalysisCompleted      = "2"
+	mispDistributionOrgOnly    = "0"
+	mispDistributionInheriting = "5"
+)
+
+// MISPEvent is a MISP event in the JSON format of the MISP API and
+// event imports.
+type MISPEvent struct {
+	Event MISPEventBody `json:"Event"`
+}
+
+// MISPEventBody holds the properties of a MISP event.
+type MISPEventBody struct {
+	UUID          string          `json:"uuid"`
+	Info          string          `json:"info"`
+	Date          string          `json:"date"`
+	Timestamp     string          `json:"timestamp"`
+	ThreatLevelID string          `json:"threat_level_id"`
+	Analysis      string          `json:"analysis"`
+	Distribution  string          `json:"distribution"`
+	Published     bool            `json:"published"`
+	Tag           []MISPTag       `json:"Tag"`
+	Attribute     []MISPAttribute `json:"Attribute"`
+	Object        []MISPObject    `json:"Object"`
+}
+
+// MISPTag is a tag attached to an event.
+type MISPTag struct {
+	Name string `json:"name"`
+}
+
+// MISPAttribute is an event attribute or an attribute of an object.
+type MISPAttribute struct {
+	UUID           string `json:"uuid"`
+	Type           string `json:"type"`
+	Category       string `json:"category"`
+	ObjectRelation string `json:"object_relation,omitempty"`
+	Value          string `json:"value"`
+	ToIDS          bool   `json:"to_ids"`
+	Distribution   string `json:"distribution"`
+	Comment        string `json:"comment,omitempty"`
+}
+
+// MISPObject is an object built from a MISP object template.
+type MISPObject struct {
+	UUID            string                `json:"uuid"`
+	Name            string                `json:"name"`
+	MetaCategory    string                `json:"meta-category"`
+	TemplateUUID    string                `json:"template_uuid"`
+	Distribution    string                `json:"distribution"`
+	Comment         string                `json:"comment,omitempty"`
+	Attribute       []MISPAttribute       `json:"Attribute"`
+	ObjectReference []MISPObjectReference `json:"ObjectReference,omitempty"`
+}
+
+// MISPObjectReference relates an object to an attribute or another object.
+type MISPObjectReference struct {
+	UUID             string `json:"uuid"`
+	ReferencedUUID   string `json:"referenced_uuid"`
+	RelationshipType string `json:"relationship_type"`
+}
+
+// NewMISPEvent converts findings to an unpublished MISP event shared with
+// the organisation only. Every result becomes a "credential" object whose
+// comment names the source and breach database; leaked email addresses
+// become email attributes, one per address, that the credentials refer
+// to. MISP's "email" object template describes messages rather than
+// addresses, which is why it is not used here.
+func NewMISPEvent(findings *Findings, passwords string) *MISPEvent {
+	event := MISPEventBody{
+		UUID:          uuid.NewString(),
+		Info:          mispEventInfo(findings),
+		Date:          findings.Generated.UTC().Format("2006-01-02"),
+		Timestamp:     strconv.FormatInt(findings.Generated.Unix(), 10),
+		ThreatLevelID: mispThreatLevelLow,
+		Analysis:      mispAnalysisCompleted,
+		Distribution:  mispDistributionOrgOnly,
+		Tag:           []MISPTag{{Name: "tlp:amber"}, {Name: "leaker"}},
+		Attribute:     []MISPAttribute{},
+		Object:        []MISPObject{},
+	}
+
+	emails := make(map[string]string)
+	for _, finding := range findings.Results {
+		result := &finding.Result
+		comment := mispProvenance(result, finding.Target)
+
+		var emailUUID string
+		if result.Email != "" {
+			var ok bool
+			if emailUUID, ok = emails[result.Email]; !ok {
+				emailUUID = uuid.NewString()
+				emails[result.Email] = emailUUID
+				event.Attribute = append(event.Attribute, MISPAttribute{
+					UUID:         emailUUID,
+					Type:         "email",
+					Category:     "Social network",
+					Value:        result.Email,
+					Distribution: mispDistributionInheriting,
+					Comment:      comment,
+				})
+			}
+		}
+
+		object, ok := mispCredential(findings, result, passwords, comment)
+		if !ok {
+			continue
+		}
+		if emailUUID != "" {
+			object.ObjectReference = append(object.ObjectReference, MISPObjectReference{
+				UUID:             uuid.NewString(),
+				ReferencedUUID:   emailUUID,
+				RelationshipType: "associated-with",
+			})
+		}
+		event.Object = append(event.Object, object)
+	}
+	return &MISPEvent{Event: event}
+}
+
+// mispCredential builds the credential object of result, if it has an
+// account name or a password or hash.
+func mispCredential(findings *Findings, result *sources.Result, passwords, comment string) (MISPObject, bool) {
+	object := MISPObject{
+		UUID:         uuid.NewString(),
+		Name:         "credential",
+		MetaCategory: "misc",
+		TemplateUUID: mispCredentialTemplate,
+		Distribution: mispDistributionInheriting,
+		Comment:      comment,
+	}
+	add := func(relation, value string) {
+		if value != "" {
+			object.Attribute = append(object.Attribute, MISPAttribute{
+				UUID:           uuid.NewString(),
+				Type:           "text",
+				Category:       "Other",
+				ObjectRelation: relation,
+				Value:          value,
+				Distribution:   mispDistributionInheriting,
+			})
+		}
+	}
+
+	username := cmp.Or(result.Username, result.Email, result.Phone)
+	password, hashed := findings.exportPassword(result.Password, passwords)
+	format := "clear-text"
+	if hashed || (password == "" && result.Hash != "") {
+		format = "hashed"
+	}
+	var notes []string
+	if password == "" {
+		password = result.Hash
+	} else if result.Hash != "" {
+		notes = append(notes, "hash: "+result.Hash)
+	}
+	if result.Salt != "" {
+		notes = append(notes, "salt: "+result.Salt)
+	}
+	if username == "" && password == "" {
+		return object, false
+	}
+
+	add("username", username)
+	add("password", password)
+	if password != "" {
+		add("type", "password")
+		add("format", format)
+	}
+	add("origin", "leak")
+	add("text", strings.Join(notes, ", "))
+	return object, true
+}
+
+// mispProvenance describes where a result was found.
+func mispProvenance(result *sources.Result, target string) string {
+	parts := []string{"source: " + result.Source}
+	if result.Database != "" {
+		parts = append(parts, "database: "+result.Database)
+	}
+	if target != "" {
+		parts = append(parts, "target: "+target)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// mispEventInfo summarizes findings as the event title.
+func mispEventInfo(findings *Findings) string {
+	var targets []string
+	seen := make(map[string]bool)
+	for _, finding := range findings.Results {
+		if !seen[finding.Target] {
+			seen[finding.Target] = true
+			targets = append(targets, finding.Target)
+		}
+	}
+	switch len(targets) {
+	case 0:
+		return "leaker: no leaks found"
+	case 1:
+		return fmt.Sprintf("leaker: %d leaked credentials for %s", len(findings.Results), targets[0])
+	}
+	return fmt.Sprintf("leaker: %d leaked credentials for %d targets", len(findings.Results), len(targets))
+}
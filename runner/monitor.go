@@ -0,0 +1,367 @@
+package runner
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/vflame6/leaker/logger"
+	"github.com/vflame6/leaker/runner/sources"
+	"github.com/vflame6/leaker/utils"
+)
+
+// watchlistDDL stores the targets leaker monitor searches. last_run is
+// zero until the target was searched once.
+const watchlistDDL = `CREATE TABLE IF NOT EXISTS watchlist (
+    scan_type TEXT NOT NULL,
+    target    TEXT NOT NULL,
+    added_at  INTEGER NOT NULL,
+    last_run  INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (scan_type, target)
+)`
+
+// monitorSeenDDL records every leak leaker monitor reported for a target,
+// so a restarted monitor does not report it again.
+const monitorSeenDDL = `CREATE TABLE IF NOT EXISTS monitor_seen (
+    scan_type  TEXT NOT NULL,
+    target     TEXT NOT NULL,
+    checksum   TEXT NOT NULL,
+    first_seen INTEGER NOT NULL,
+    PRIMARY KEY (scan_type, target, checksum)
+)`
+
+// monitorPollInterval caps how long the monitor sleeps, so targets added
+// to the watchlist while it runs are picked up.
+const monitorPollInterval = time.Minute
+
+var errMonitorReadOnly = errors.New("monitoring needs a writable local DB")
+
+// WatchEntry is one target on the watchlist.
+type WatchEntry struct {
+	Type    string    `json:"type"`
+	Target  string    `json:"target"`
+	AddedAt time.Time `json:"added_at"`
+	LastRun time.Time `json:"last_run"`
+}
+
+// due reports whether the entry should be searched at now.
+func (e WatchEntry) due(now time.Time, interval time.Duration) bool {
+	return e.LastRun.IsZero() || !now.Before(e.LastRun.Add(interval))
+}
+
+// AddWatch adds a target to the watchlist. It reports false when the
+// target was already watched.
+func (l *LeakerDB) AddWatch(scanType sources.ScanType, target string) (bool, error) {
+	if l == nil || !l.writable {
+		return false, errMonitorReadOnly
+	}
+	normalized, err := ParseTarget(scanType, target)
+	if err != nil {
+		return false, err
+	}
+	res, err := l.db.Exec(`INSERT OR IGNORE INTO watchlist (scan_type, target, added_at) VALUES (?, ?, ?)`,
+		scanType.String(), normalized, time.Now().Unix())
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// RemoveWatch removes a target from the watchlist along with the record
+// of its reported leaks.
+func (l *LeakerDB) RemoveWatch(scanType sources.ScanType, target string) error {
+	if l == nil || !l.writable {
+		return errMonitorReadOnly
+	}
+	target = normalizeTarget(scanType, target)
+	tx, err := l.db.Begin()
+	if err != nil {
+		return err
+	}
+	res, err := tx.Exec(`DELETE FROM watchlist WHERE scan_type = ? AND target = ?`, scanType.String(), target)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		_ = tx.Rollback()
+		return fmt.Errorf("%s %s is not on the watchlist", scanType, target)
+	}
+	if _, err := tx.Exec(`DELETE FROM monitor_seen WHERE scan_type = ? AND target = ?`, scanType.String(), target); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// Watchlist returns every watched target, ordered by type and target.
+func (l *LeakerDB) Watchlist() ([]WatchEntry, error) {
+	if l == nil {
+		return nil, nil
+	}
+	rows, err := l.db.Query(`SELECT scan_type, target, added_at, last_run FROM watchlist ORDER BY scan_type, target`)
+	if err != nil {
+		if isMissingTable(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var entries []WatchEntry
+	for rows.Next() {
+		var entry WatchEntry
+		var added, lastRun int64
+		if err := rows.Scan(&entry.Type, &entry.Target, &added, &lastRun); err != nil {
+			return nil, err
+		}
+		entry.AddedAt = time.Unix(added, 0)
+		if lastRun > 0 {
+			entry.LastRun = time.Unix(lastRun, 0)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// RecordWatchRun stores the checksums found for a watched target and
+// marks it as searched at now, in one transaction. It returns the
+// checksums that had not been seen for the target before.
+func (l *LeakerDB) RecordWatchRun(entry WatchEntry, checksums []string, now time.Time) (map[string]bool, error) {
+	tx, err := l.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	fresh := make(map[string]bool)
+	for _, checksum := range checksums {
+		res, err := tx.Exec(`INSERT OR IGNORE INTO monitor_seen (scan_type, target, checksum, first_seen) VALUES (?, ?, ?, ?)`,
+			entry.Type, entry.Target, checksum, now.Unix())
+		if err != nil {
+			_ = tx.Rollback()
+			return nil, err
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			fresh[checksum] = true
+		}
+	}
+	if _, err := tx.Exec(`UPDATE watchlist SET last_run = ? WHERE scan_type = ? AND target = ?`,
+		now.Unix(), entry.Type, entry.Target); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	return fresh, tx.Commit()
+}
+
+// AddWatchTargets adds every target read from targets, a single target or
+// a file with one target per line. It returns the number of targets added.
+func (l *LeakerDB) AddWatchTargets(scanType sources.ScanType, targets string) (int, error) {
+	reader, err := utils.ParseTargets(targets, false)
+	if err != nil {
+		return 0, err
+	}
+	added := 0
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		ok, err := l.AddWatch(scanType, line)
+		if err != nil {
+			return added, err
+		}
+		if ok {
+			added++
+		}
+	}
+	return added, scanner.Err()
+}
+
+// WriteWatchlist writes the watchlist as an aligned table.
+func WriteWatchlist(w io.Writer, entries []WatchEntry) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "TYPE\tTARGET\tADDED\tLAST RUN")
+	for _, entry := range entries {
+		lastRun := "never"
+		if !entry.LastRun.IsZero() {
+			lastRun = entry.LastRun.Format(time.DateTime)
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", entry.Type, entry.Target, entry.AddedAt.Format(time.DateTime), lastRun)
+	}
+	return tw.Flush()
+}
+
+// Monitor searches the watchlist and writes only leaks that were not
+// reported for a target before, with the time they were first seen. With
+// a zero interval it makes a single pass; otherwise it searches each
+// target again once interval passed since its last search, until ctx is
+// cancelled. The watchlist and reported leaks live in the local DB, so a
+// restarted monitor neither repeats searches that are not due nor reports
+// old leaks again. It returns the number of new leaks reported.
+func (r *Runner) Monitor(ctx context.Context, interval time.Duration) (int, error) {
+	if r.leakerDB == nil || !r.leakerDB.writable {
+		return 0, errMonitorReadOnly
+	}
+
+	outputs := []io.Writer{r.options.Output}
+	if r.options.OutputFile != "" {
+		file, err := r.createOutputFile(r.options.OutputFile, true)
+		if err != nil {
+			return 0, err
+		}
+		defer func() {
+			if closeErr := file.Close(); closeErr != nil {
+				logger.Errorf("could not write output file: %s", closeErr)
+			}
+		}()
+		outputs = append(outputs, file)
+	}
+	if r.options.FlattenExtra && interval > 0 {
+		return 0, errors.New("extra columns can only be flattened for a single pass")
+	}
+	outputs, err := r.formatOutputs(outputs)
+	if err != nil {
+		return 0, fmt.Errorf("could not write output header: %w", err)
+	}
+
+	r.errorSink, err = openErrorSink(r.options.ErrorsJSON, r.options.Overwrite)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = r.errorSink.Close()
+	}()
+	defer r.reportQuota()
+	defer r.notifier.Close()
+
+	searches := make(map[string]*Runner)
+	reported := 0
+	for {
+		entries, err := r.leakerDB.Watchlist()
+		if err != nil {
+			return reported, err
+		}
+		if len(entries) == 0 && interval == 0 {
+			logger.Warn("The watchlist is empty, add targets with `leaker monitor add`")
+			return 0, nil
+		}
+
+		next := monitorPollInterval
+		for _, entry := range entries {
+			now := time.Now()
+			if !entry.due(now, interval) {
+				next = min(next, entry.LastRun.Add(interval).Sub(now))
+				continue
+			}
+			search, ok := searches[entry.Type]
+			if !ok {
+				scanType, valid := sources.ParseScanType(entry.Type)
+				if !valid {
+					logger.Errorf("skipping watched %s with unknown type %q", entry.Target, entry.Type)
+					continue
+				}
+				if search, err = r.fork(func(o *Options) { o.Type = scanType }); err != nil {
+					return reported, err
+				}
+				search.errorSink = r.errorSink
+				search.stats = r.stats
+				searches[entry.Type] = search
+			}
+			n, err := search.monitorTarget(ctx, entry, outputs, r.notifier)
+			reported += n
+			if err != nil {
+				return reported, err
+			}
+			if ctx.Err() != nil {
+				return reported, nil
+			}
+		}
+
+		if err := flushOutputs(outputs); err != nil {
+			logger.Errorf("could not write results: %s", err)
+		}
+		r.notifier.flush()
+		if interval == 0 {
+			return reported, nil
+		}
+		select {
+		case <-ctx.Done():
+			return reported, nil
+		case <-time.After(max(next, time.Second)):
+		}
+	}
+}
+
+// monitorTarget searches one watched target, writes its new leaks and
+// passes them to notify. A search interrupted by ctx records nothing, so
+// the target is searched again on the next run.
+func (r *Runner) monitorTarget(ctx context.Context, entry WatchEntry, writers []io.Writer, notify *notifier) (int, error) {
+	var results []sources.Result
+	r.onResult = func(_ string, result *sources.Result) {
+		results = append(results, *result)
+	}
+	if _, err := r.enumerateTarget(ctx, entry.Target, r.options.Type, r.options.Timeout, []io.Writer{io.Discard}); err != nil {
+		logger.Errorf("error on monitoring %s: %s", entry.Target, err)
+	}
+	if ctx.Err() != nil {
+		return 0, nil
+	}
+
+	checksums := make([]string, 0, len(results))
+	for i := range results {
+		checksums = append(checksums, results[i].Checksum())
+	}
+	now := time.Now()
+	fresh, err := r.leakerDB.RecordWatchRun(entry, checksums, now)
+	if err != nil {
+		return 0, err
+	}
+
+	reported := 0
+	for i := range results {
+		result := &results[i]
+		if !fresh[result.Checksum()] {
+			continue
+		}
+		// a result returned twice in one search is only reported once
+		delete(fresh, result.Checksum())
+		reported++
+		output := r.redact(result)
+		for _, writer := range writers {
+			if err := r.writeMonitorResult(writer, output, entry.Target, now); err != nil {
+				logger.Errorf("could not write results for %s: %s", entry.Target, err)
+			}
+		}
+		notify.result(entry.Target, output, now)
+	}
+	logger.Infof("Found %d new leaks for %s", reported, entry.Target)
+	return reported, nil
+}
+
+// writeMonitorResult writes a newly seen leak with the time it was first
+// seen, as a prefix in plain output.
+func (r *Runner) writeMonitorResult(writer io.Writer, result *sources.Result, target string, firstSeen time.Time) error {
+	jr := newJSONResult(r.options.Metadata, result, target)
+	jr.FirstSeen = firstSeen.Format(time.RFC3339)
+	switch w := writer.(type) {
+	case *ResultTable:
+		return w.writeRow(jr)
+	case *ResultTemplate:
+		return w.render(templateData{Result: result, Target: target, FirstSeen: jr.FirstSeen})
+	}
+	if r.options.JSON {
+		return writeJSON(writer, jr)
+	}
+	if _, err := fmt.Fprintf(writer, "[%s] ", firstSeen.Format(time.DateTime)); err != nil {
+		return err
+	}
+	return WritePlainResult(writer, r.options.Verbose, r.options.Metadata, result)
+}
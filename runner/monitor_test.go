@@ -0,0 +1,211 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vflame6/leaker/runner/sources"
+)
+
+func TestLeakerDB_Watchlist(t *testing.T) {
+	db := openTestLeakerDB(t)
+	added, err := db.AddWatch(sources.TypeEmail, " Alice@Example.com ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !added {
+		t.Error("expected the target to be added")
+	}
+	if added, err = db.AddWatch(sources.TypeEmail, "alice@example.com"); err != nil || added {
+		t.Errorf("expected a duplicate to be ignored, got %v, %v", added, err)
+	}
+	if _, err := db.AddWatch(sources.TypeEmail, "not-an-email"); err == nil {
+		t.Error("expected an error for an invalid email")
+	}
+
+	entries, err := db.Watchlist()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Target != "alice@example.com" || !entries[0].LastRun.IsZero() {
+		t.Fatalf("unexpected watchlist: %+v", entries)
+	}
+
+	if err := db.RemoveWatch(sources.TypeEmail, "alice@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.RemoveWatch(sources.TypeEmail, "alice@example.com"); err == nil {
+		t.Error("expected an error removing a target that is not watched")
+	}
+}
+
+func TestLeakerDB_RecordWatchRunReturnsOnlyNewChecksums(t *testing.T) {
+	db := openTestLeakerDB(t)
+	if _, err := db.AddWatch(sources.TypeEmail, "alice@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	entry := WatchEntry{Type: "email", Target: "alice@example.com"}
+	now := time.Now()
+
+	fresh, err := db.RecordWatchRun(entry, []string{"a", "b"}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fresh) != 2 {
+		t.Errorf("expected 2 new checksums, got %v", fresh)
+	}
+	fresh, err = db.RecordWatchRun(entry, []string{"b", "c"}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fresh) != 1 || !fresh["c"] {
+		t.Errorf("expected only c to be new, got %v", fresh)
+	}
+
+	entries, err := db.Watchlist()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entries[0].LastRun.Unix() != now.Unix() {
+		t.Errorf("expected last run %v, got %v", now, entries[0].LastRun)
+	}
+}
+
+func TestWatchEntry_Due(t *testing.T) {
+	now := time.Now()
+	if !(WatchEntry{}).due(now, time.Hour) {
+		t.Error("a target never searched must be due")
+	}
+	if (WatchEntry{LastRun: now.Add(-time.Minute)}).due(now, time.Hour) {
+		t.Error("a target searched a minute ago must not be due")
+	}
+	if !(WatchEntry{LastRun: now.Add(-time.Minute)}).due(now, 0) {
+		t.Error("every target must be due with a zero interval")
+	}
+}
+
+func TestMonitor_ReportsOnlyNewLeaks(t *testing.T) {
+	db := openTestLeakerDB(t)
+	insert := func(password string) {
+		t.Helper()
+		if err := db.Insert(&sources.Result{Source: "seed", Email: "alice@example.com", Password: password}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	insert("one")
+	insert("two")
+	if _, err := db.AddWatch(sources.TypeEmail, "alice@example.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	output := &bytes.Buffer{}
+	r := newTestRunner([]string{sources.LocalSourceName})
+	r.options.Output = output
+	r.options.JSON = true
+	r.leakerDB = db
+
+	found, err := r.Monitor(context.Background(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found != 2 {
+		t.Errorf("expected 2 new leaks on the first run, got %d", found)
+	}
+
+	output.Reset()
+	if found, err = r.Monitor(context.Background(), 0); err != nil {
+		t.Fatal(err)
+	}
+	if found != 0 || output.Len() != 0 {
+		t.Errorf("expected no new leaks on the second run, got %d: %s", found, output)
+	}
+
+	insert("three")
+	if found, err = r.Monitor(context.Background(), 0); err != nil {
+		t.Fatal(err)
+	}
+	if found != 1 {
+		t.Fatalf("expected 1 new leak, got %d", found)
+	}
+	var got jsonResult
+	if err := json.Unmarshal(bytes.TrimSpace(output.Bytes()), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Password != "three" || got.Target != "alice@example.com" {
+		t.Errorf("unexpected new leak: %+v", got)
+	}
+	if _, err := time.Parse(time.RFC3339, got.FirstSeen); err != nil {
+		t.Errorf("expected an RFC 3339 first_seen, got %q", got.FirstSeen)
+	}
+}
+
+func TestMonitor_PlainOutputIncludesFirstSeen(t *testing.T) {
+	db := openTestLeakerDB(t)
+	if err := db.Insert(&sources.Result{Source: "seed", Email: "alice@example.com", Password: "one"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.AddWatch(sources.TypeEmail, "alice@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	output := &bytes.Buffer{}
+	r := newTestRunner([]string{sources.LocalSourceName})
+	r.options.Output = output
+	r.leakerDB = db
+
+	if _, err := r.Monitor(context.Background(), 0); err != nil {
+		t.Fatal(err)
+	}
+	line := strings.TrimSpace(output.String())
+	if !strings.HasPrefix(line, "[") || !strings.Contains(line, "alice@example.com") {
+		t.Errorf("unexpected plain output: %q", line)
+	}
+}
+
+func TestMonitor_RequiresWritableDB(t *testing.T) {
+	if _, err := newTestRunner(nil).Monitor(context.Background(), 0); err == nil {
+		t.Error("expected an error without a local DB")
+	}
+}
+
+func TestMonitor_EncryptsOutputFile(t *testing.T) {
+	db := openTestLeakerDB(t)
+	if err := db.Insert(&sources.Result{Source: "seed", Email: "alice@example.com", Password: "hunter2"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.AddWatch(sources.TypeEmail, "alice@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	identity, key := newTestIdentity(t, dir, "key.txt")
+	r := newTestRunner([]string{sources.LocalSourceName})
+	r.options.Output = io.Discard
+	r.options.JSON = true
+	r.options.OutputFile = filepath.Join(dir, "new.jsonl.age")
+	r.options.EncryptTo = []string{identity.Recipient().String()}
+	r.leakerDB = db
+
+	if _, err := r.Monitor(context.Background(), 0); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(r.options.OutputFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("hunter2")) {
+		t.Fatal("monitor output file is not encrypted")
+	}
+	var decrypted bytes.Buffer
+	if err := DecryptAge(&decrypted, bytes.NewReader(data), []string{key}, ""); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(decrypted.String(), `"password":"hunter2"`) {
+		t.Errorf("unexpected decrypted output %q", decrypted.String())
+	}
+}
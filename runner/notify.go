@@ -0,0 +1,408 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/vflame6/leaker/logger"
+	"github.com/vflame6/leaker/runner/sources"
+)
+
+// Notification sink types.
+const (
+	NotifyWebhook = "webhook"
+	NotifySlack   = "slack"
+	NotifyDiscord = "discord"
+	NotifyTeams   = "teams"
+)
+
+// Notification delivery modes.
+const (
+	NotifyPerResult = "result"
+	NotifyDigest    = "digest"
+)
+
+// notifySignatureHeader carries the hex HMAC-SHA256 of a webhook body,
+// keyed with the sink's secret, as "sha256=<hex>".
+const notifySignatureHeader = "X-Leaker-Signature"
+
+// Default message templates. Digest messages list at most
+// notifyDigestLines results; webhook payloads always carry every result.
+const (
+	defaultResultTemplate = `New leak for {{.Target}} from {{.Source}}: {{.Value}}`
+	defaultDigestTemplate = `leaker found {{.Count}} leaks for {{.Targets}} targets` +
+		`{{range .Lines}}` + "\n" + `- {{.Target}} ({{.Source}}): {{.Value}}{{end}}` +
+		`{{if .More}}` + "\n" + `... and {{.More}} more{{end}}`
+	notifyDigestLines = 50
+)
+
+// notifyTimeout bounds every notification request.
+const notifyTimeout = 10 * time.Second
+
+// notifyQueueSize is how many per-result notifications may wait for
+// delivery before further ones are dropped.
+const notifyQueueSize = 64
+
+// NotificationSettings configures one notification sink in the settings
+// section of the provider config:
+//
+//	settings:
+//	  notifications:
+//	    - type: webhook
+//	      url: https://hooks.example.com/leaker
+//	      secret: s3cret
+//	    - type: slack
+//	      url: https://hooks.slack.com/services/T000/B000/XXXX
+//	      mode: digest
+//	      template: "{{.Count}} new leaks"
+type NotificationSettings struct {
+	// Type is webhook, slack, discord or teams.
+	Type string `yaml:"type"`
+	URL  string `yaml:"url"`
+	// Secret signs webhook bodies with HMAC-SHA256.
+	Secret string `yaml:"secret"`
+	// Mode is result (default) to notify on every result, or digest to
+	// send one message at the end of each run.
+	Mode string `yaml:"mode"`
+	// Template is a text/template for the message text.
+	Template string `yaml:"template"`
+	// ShowPasswords disables password masking for this sink.
+	ShowPasswords bool `yaml:"show-passwords"`
+}
+
+// notifySink is a validated notification sink.
+type notifySink struct {
+	settings NotificationSettings
+	template *template.Template
+}
+
+func newNotifySink(settings NotificationSettings) (*notifySink, error) {
+	settings.Type = strings.ToLower(strings.TrimSpace(settings.Type))
+	switch settings.Type {
+	case NotifyWebhook, NotifySlack, NotifyDiscord, NotifyTeams:
+	default:
+		return nil, fmt.Errorf("unknown notification type %q", settings.Type)
+	}
+	switch settings.Mode {
+	case "":
+		settings.Mode = NotifyPerResult
+	case NotifyPerResult, NotifyDigest:
+	default:
+		return nil, fmt.Errorf("unknown notification mode %q", settings.Mode)
+	}
+	if u, err := url.Parse(settings.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("%s notification needs an http(s) url", settings.Type)
+	}
+
+	text := settings.Template
+	if text == "" {
+		text = defaultResultTemplate
+		if settings.Mode == NotifyDigest {
+			text = defaultDigestTemplate
+		}
+	}
+	tmpl, err := template.New(settings.Type).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("%s notification template: %w", settings.Type, err)
+	}
+	return &notifySink{settings: settings, template: tmpl}, nil
+}
+
+// notifyResult is a result as exposed to notification templates and
+// webhook payloads, with the password masked unless the sink shows it.
+type notifyResult struct {
+	jsonResult
+	Value string `json:"-"`
+}
+
+func newNotifyResult(target string, result *sources.Result, firstSeen time.Time, showPasswords bool) notifyResult {
+	masked := *result
+	if !showPasswords {
+		masked.Password = maskPassword(masked.Password)
+	}
+	nr := notifyResult{jsonResult: newJSONResult(true, &masked, target), Value: masked.Value()}
+	if !firstSeen.IsZero() {
+		nr.FirstSeen = firstSeen.Format(time.RFC3339)
+	}
+	return nr
+}
+
+// notifyDigest is the data of a digest message template.
+type notifyDigest struct {
+	Count   int
+	Targets int
+	Results []notifyResult
+	// Lines are the first notifyDigestLines results; More counts the rest.
+	Lines []notifyResult
+	More  int
+}
+
+// maskPassword keeps the first and last character of a password and
+// replaces the rest with asterisks.
+func maskPassword(password string) string {
+	runes := []rune(password)
+	if len(runes) <= 2 {
+		return strings.Repeat("*", len(runes))
+	}
+	return string(runes[0]) + strings.Repeat("*", len(runes)-2) + string(runes[len(runes)-1])
+}
+
+// notifyEvent is a result to deliver, or a digest flush of the results in
+// digest when flushed is set.
+type notifyEvent struct {
+	target    string
+	result    sources.Result
+	firstSeen time.Time
+	digest    []notifyEvent
+	flushed   chan struct{}
+}
+
+// notifier delivers results to the configured sinks from a background
+// goroutine, so slow receivers do not hold up enumeration: per-result
+// notifications that find the queue full are dropped and counted. Delivery
+// errors are logged and never fail a run. A nil *notifier ignores every
+// call.
+type notifier struct {
+	sinks  []*notifySink
+	client *http.Client
+	events chan notifyEvent
+	done   chan struct{}
+	// perResult and digest tell whether any sink uses that mode.
+	perResult bool
+	digest    bool
+	// redacted is set when --redact already redacted the passwords, which
+	// are then sent as they are instead of masked again.
+	redacted bool
+
+	mu sync.Mutex
+	// pending are the results of the next digest, kept only when a
+	// digest sink exists.
+	pending []notifyEvent
+	// dropped counts the per-result notifications dropped since the last
+	// flush.
+	dropped int
+}
+
+// newNotifier validates the sinks and starts the delivery goroutine.
+func newNotifier(settings []NotificationSettings, client *http.Client) (*notifier, error) {
+	n := &notifier{client: client, events: make(chan notifyEvent, notifyQueueSize), done: make(chan struct{})}
+	for i, s := range settings {
+		sink, err := newNotifySink(s)
+		if err != nil {
+			return nil, fmt.Errorf("notification %d: %w", i+1, err)
+		}
+		n.sinks = append(n.sinks, sink)
+		if sink.settings.Mode == NotifyDigest {
+			n.digest = true
+		} else {
+			n.perResult = true
+		}
+	}
+	go n.run()
+	return n, nil
+}
+
+// configureNotifications enables the notification sinks of the provider
+// config when --notify is set.
+func (r *Runner) configureNotifications() error {
+	if !r.options.Notify {
+		return nil
+	}
+	settings := r.options.ProviderSettings.Notifications
+	if len(settings) == 0 {
+		return fmt.Errorf("--notify needs at least one entry under settings.notifications in the provider config")
+	}
+	n, err := newNotifier(settings, &http.Client{Timeout: notifyTimeout})
+	if err != nil {
+		return err
+	}
+	n.redacted = r.options.Redact != RedactNone
+	r.notifier = n
+	return nil
+}
+
+// result queues a result for delivery. It never blocks: when the
+// per-result queue is full the notification is dropped.
+func (n *notifier) result(target string, result *sources.Result, firstSeen time.Time) {
+	if n == nil {
+		return
+	}
+	event := notifyEvent{target: target, result: *result, firstSeen: firstSeen}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.digest {
+		n.pending = append(n.pending, event)
+	}
+	if !n.perResult {
+		return
+	}
+	select {
+	case n.events <- event:
+	default:
+		n.dropped++
+	}
+}
+
+// flush sends the digest of the results queued since the last flush and
+// waits until every queued notification was delivered.
+func (n *notifier) flush() {
+	if n == nil {
+		return
+	}
+	n.mu.Lock()
+	pending, dropped := n.pending, n.dropped
+	n.pending, n.dropped = nil, 0
+	n.mu.Unlock()
+	if dropped > 0 {
+		logger.Warnf("Dropped %d result notifications because the notification sinks could not keep up", dropped)
+	}
+
+	flushed := make(chan struct{})
+	n.events <- notifyEvent{digest: pending, flushed: flushed}
+	<-flushed
+}
+
+// Close flushes pending notifications and stops the delivery goroutine.
+func (n *notifier) Close() {
+	if n == nil {
+		return
+	}
+	n.flush()
+	close(n.events)
+	<-n.done
+}
+
+func (n *notifier) run() {
+	defer close(n.done)
+	for event := range n.events {
+		if event.flushed != nil {
+			if len(event.digest) > 0 {
+				n.sendDigest(event.digest)
+			}
+			close(event.flushed)
+			continue
+		}
+		for _, sink := range n.sinks {
+			if sink.settings.Mode != NotifyPerResult {
+				continue
+			}
+			nr := newNotifyResult(event.target, &event.result, event.firstSeen, sink.settings.ShowPasswords || n.redacted)
+			n.deliver(sink, "result", nr, []notifyResult{nr})
+		}
+	}
+}
+
+func (n *notifier) sendDigest(events []notifyEvent) {
+	for _, sink := range n.sinks {
+		if sink.settings.Mode != NotifyDigest {
+			continue
+		}
+		digest := notifyDigest{Count: len(events)}
+		targets := make(map[string]struct{})
+		for _, event := range events {
+			targets[event.target] = struct{}{}
+			digest.Results = append(digest.Results,
+				newNotifyResult(event.target, &event.result, event.firstSeen, sink.settings.ShowPasswords || n.redacted))
+		}
+		digest.Targets = len(targets)
+		digest.Lines = digest.Results[:min(len(digest.Results), notifyDigestLines)]
+		digest.More = len(digest.Results) - len(digest.Lines)
+		n.deliver(sink, "digest", digest, digest.Results)
+	}
+}
+
+// deliver renders the message for data and posts it to sink.
+func (n *notifier) deliver(sink *notifySink, event string, data any, results []notifyResult) {
+	text := &strings.Builder{}
+	if err := sink.template.Execute(text, data); err != nil {
+		logger.Errorf("could not render %s notification: %s", sink.settings.Type, err)
+		return
+	}
+	body, err := notificationPayload(sink.settings.Type, event, text.String(), results)
+	if err != nil {
+		logger.Errorf("could not build %s notification: %s", sink.settings.Type, err)
+		return
+	}
+	if err := n.post(sink, body); err != nil {
+		logger.Errorf("could not send %s notification: %s", sink.settings.Type, err)
+	}
+}
+
+func (n *notifier) post(sink *notifySink, body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sink.settings.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if sink.settings.Type == NotifyWebhook && sink.settings.Secret != "" {
+		req.Header.Set(notifySignatureHeader, "sha256="+signPayload(sink.settings.Secret, body))
+	}
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("status %d from %s", resp.StatusCode, req.URL.Host)
+	}
+	return nil
+}
+
+// signPayload returns the hex HMAC-SHA256 of body keyed with secret.
+func signPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// discordContentLimit is the maximum length of a Discord message.
+const discordContentLimit = 2000
+
+// notificationPayload builds the JSON body each sink type expects.
+func notificationPayload(sinkType, event, text string, results []notifyResult) ([]byte, error) {
+	switch sinkType {
+	case NotifySlack:
+		return json.Marshal(map[string]string{"text": text})
+	case NotifyDiscord:
+		if runes := []rune(text); len(runes) > discordContentLimit {
+			text = string(runes[:discordContentLimit-1]) + "…"
+		}
+		return json.Marshal(map[string]string{"content": text})
+	case NotifyTeams:
+		return json.Marshal(map[string]any{
+			"type": "message",
+			"attachments": []map[string]any{{
+				"contentType": "application/vnd.microsoft.card.adaptive",
+				"content": map[string]any{
+					"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+					"type":    "AdaptiveCard",
+					"version": "1.4",
+					"body":    []map[string]any{{"type": "TextBlock", "text": text, "wrap": true}},
+				},
+			}},
+		})
+	default:
+		return json.Marshal(struct {
+			Event   string         `json:"event"`
+			Text    string         `json:"text"`
+			Results []notifyResult `json:"results"`
+		}{event, text, results})
+	}
+}
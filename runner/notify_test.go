@@ -0,0 +1,259 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vflame6/leaker/runner/sources"
+)
+
+// receivedRequest is a notification captured by a test receiver.
+type receivedRequest struct {
+	header http.Header
+	body   []byte
+}
+
+// newTestReceiver records every POST it receives.
+func newTestReceiver(t *testing.T) (*httptest.Server, func() []receivedRequest) {
+	t.Helper()
+	var mu sync.Mutex
+	var received []receivedRequest
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		mu.Lock()
+		received = append(received, receivedRequest{header: req.Header.Clone(), body: body})
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(ts.Close)
+	return ts, func() []receivedRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]receivedRequest{}, received...)
+	}
+}
+
+var testLeak = sources.Result{Source: "seed", Email: "alice@example.com", Password: "hunter2"}
+
+func TestNotifier_WebhookIsSignedAndMasked(t *testing.T) {
+	ts, received := newTestReceiver(t)
+	n, err := newNotifier([]NotificationSettings{{Type: NotifyWebhook, URL: ts.URL, Secret: "s3cret"}}, ts.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.result("alice@example.com", &testLeak, time.Time{})
+	n.Close()
+
+	got := received()
+	if len(got) != 1 {
+		t.Fatalf("expected 1 notification, got %d", len(got))
+	}
+	want := "sha256=" + signPayload("s3cret", got[0].body)
+	if sig := got[0].header.Get(notifySignatureHeader); sig != want {
+		t.Errorf("expected signature %s, got %s", want, sig)
+	}
+	if bytes.Contains(got[0].body, []byte("hunter2")) {
+		t.Errorf("password leaked into the notification: %s", got[0].body)
+	}
+
+	var payload struct {
+		Event   string       `json:"event"`
+		Text    string       `json:"text"`
+		Results []jsonResult `json:"results"`
+	}
+	if err := json.Unmarshal(got[0].body, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Event != "result" || len(payload.Results) != 1 || payload.Results[0].Password != "h*****2" {
+		t.Errorf("unexpected payload: %+v", payload)
+	}
+	if !strings.Contains(payload.Text, "alice@example.com") {
+		t.Errorf("unexpected text: %q", payload.Text)
+	}
+}
+
+func TestNotifier_ShowPasswords(t *testing.T) {
+	ts, received := newTestReceiver(t)
+	n, err := newNotifier([]NotificationSettings{{Type: NotifySlack, URL: ts.URL, ShowPasswords: true}}, ts.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.result("alice@example.com", &testLeak, time.Time{})
+	n.Close()
+
+	got := received()
+	if len(got) != 1 || !bytes.Contains(got[0].body, []byte("hunter2")) {
+		t.Errorf("expected the clear password, got %+v", got)
+	}
+}
+
+func TestNotifier_DigestIsSentOnFlush(t *testing.T) {
+	ts, received := newTestReceiver(t)
+	n, err := newNotifier([]NotificationSettings{{
+		Type:     NotifyDiscord,
+		URL:      ts.URL,
+		Mode:     NotifyDigest,
+		Template: "{{.Count}} leaks for {{.Targets}} targets{{range .Results}} {{.Password}}{{end}}",
+	}}, ts.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := sources.Result{Source: "seed", Email: "bob@example.com", Password: "pw"}
+	n.result("alice@example.com", &testLeak, time.Time{})
+	n.result("bob@example.com", &other, time.Time{})
+	if len(received()) != 0 {
+		t.Fatal("digest sinks must not be notified per result")
+	}
+	n.flush()
+	n.flush() // nothing pending: no second message
+	n.Close()
+
+	got := received()
+	if len(got) != 1 {
+		t.Fatalf("expected 1 digest, got %d", len(got))
+	}
+	var payload map[string]string
+	if err := json.Unmarshal(got[0].body, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload["content"] != "2 leaks for 2 targets h*****2 **" {
+		t.Errorf("unexpected digest: %q", payload["content"])
+	}
+}
+
+func TestNotifier_SlowSinkDoesNotBlock(t *testing.T) {
+	release := make(chan struct{})
+	var mu sync.Mutex
+	delivered := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		<-release
+		mu.Lock()
+		delivered++
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+	n, err := newNotifier([]NotificationSettings{{Type: NotifyWebhook, URL: ts.URL}}, ts.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sent := make(chan struct{})
+	go func() {
+		defer close(sent)
+		for range 3 * notifyQueueSize {
+			n.result("alice@example.com", &testLeak, time.Time{})
+		}
+	}()
+	select {
+	case <-sent:
+	case <-time.After(5 * time.Second):
+		t.Fatal("a stalled sink blocked result notifications")
+	}
+	if n.pending != nil {
+		t.Errorf("expected no digest buffer without a digest sink, got %d results", len(n.pending))
+	}
+	close(release)
+	n.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if delivered == 0 || delivered > notifyQueueSize+1 {
+		t.Errorf("expected at most %d notifications delivered, got %d", notifyQueueSize+1, delivered)
+	}
+}
+
+func TestNotificationPayload_Teams(t *testing.T) {
+	body, err := notificationPayload(NotifyTeams, "result", "hello", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var payload struct {
+		Type        string `json:"type"`
+		Attachments []struct {
+			ContentType string `json:"contentType"`
+			Content     struct {
+				Type string `json:"type"`
+				Body []struct {
+					Text string `json:"text"`
+				} `json:"body"`
+			} `json:"content"`
+		} `json:"attachments"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Type != "message" || len(payload.Attachments) != 1 ||
+		payload.Attachments[0].Content.Type != "AdaptiveCard" || payload.Attachments[0].Content.Body[0].Text != "hello" {
+		t.Errorf("unexpected Teams payload: %s", body)
+	}
+}
+
+func TestNewNotifySink_Validates(t *testing.T) {
+	for name, settings := range map[string]NotificationSettings{
+		"type":     {Type: "pager", URL: "https://example.com"},
+		"mode":     {Type: NotifySlack, URL: "https://example.com", Mode: "hourly"},
+		"url":      {Type: NotifySlack, URL: "ftp://example.com"},
+		"template": {Type: NotifySlack, URL: "https://example.com", Template: "{{.Target"},
+	} {
+		if _, err := newNotifySink(settings); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestMaskPassword(t *testing.T) {
+	for password, want := range map[string]string{
+		"":        "",
+		"ab":      "**",
+		"abc":     "a*c",
+		"pässwör": "p*****r",
+	} {
+		if got := maskPassword(password); got != want {
+			t.Errorf("maskPassword(%q) = %q, want %q", password, got, want)
+		}
+	}
+}
+
+func TestMonitor_NotifiesOnlyNewLeaks(t *testing.T) {
+	ts, received := newTestReceiver(t)
+	db := openTestLeakerDB(t)
+	if err := db.Insert(&testLeak); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.AddWatch(sources.TypeEmail, "alice@example.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	run := func() {
+		t.Helper()
+		r := newTestRunner([]string{sources.LocalSourceName})
+		r.leakerDB = db
+		n, err := newNotifier([]NotificationSettings{{Type: NotifyWebhook, URL: ts.URL}}, ts.Client())
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.notifier = n
+		if _, err := r.Monitor(context.Background(), 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	run()
+	run()
+
+	got := received()
+	if len(got) != 1 {
+		t.Fatalf("expected 1 notification across both runs, got %d", len(got))
+	}
+	if !bytes.Contains(got[0].body, []byte(`"first_seen"`)) {
+		t.Errorf("expected first_seen in the payload: %s", got[0].body)
+	}
+}
@@ -22,32 +22,61 @@ var (
 
 // Options struct is used to store leaker options. Sort alphabetically
 type Options struct {
-	DBPath          string // DBPath is the local SQLite cache path (empty = use default)
-	Debug           bool
-	Metadata        bool // Metadata includes metadata fields (database) in output
-	Insecure        bool // Insecure disables TLS certificate verification when true
-	JSON            bool // JSON outputs results as JSONL (one JSON object per line)
-	ListSources     bool
-	NoColor         bool // NoColor disables colored output
-	NoDeduplication bool // NoDeduplication disables deduplication of results across sources
-	NoFilter        bool
-	NoRateLimit     bool
-	NoWriteDB       bool // NoWriteDB disables writing to the local SQLite cache
-	Output          io.Writer
-	OutputFile      string
-	Overwrite       bool
-	ProviderConfig  string // ProviderConfig contains the location of the provider config file
-	Proxy           string
-	Quiet           bool
-	Sources         []string
-	Stdin           bool
-	Targets         string
-	Timeout         time.Duration
-	Type            sources.ScanType
-	UserAgent       string
-	Verbose         bool
-	Verify          bool // Verify enriches results with HIBP password checks and hash identification
-	Version         string
+	DBPath           string // DBPath is the local SQLite cache path (empty = use default)
+	Debug            bool
+	EncryptTo        []string // EncryptTo encrypts the output files with age to these recipients or recipient files
+	ErrorsJSON       string   // ErrorsJSON is the file every source error is written to as JSON lines
+	EscapeFormulas   bool     // EscapeFormulas prefixes CSV/TSV values that start like a spreadsheet formula with '
+	ExpandEmails     bool     // ExpandEmails enumerates emails discovered during domain scans as new targets
+	ExportPasswords  string   // ExportPasswords is how STIX and MISP exports carry passwords: plain, hash or omit
+	FlattenExtra     bool     // FlattenExtra writes each Extra key as its own CSV/TSV column
+	Format           string   // Format is the output format: plain, json, csv or tsv
+	HashDir          string   // HashDir is the directory hashcat and John hash files are written to, one per mode
+	Metadata         bool     // Metadata includes metadata fields (database) in output
+	Insecure         bool     // Insecure disables TLS certificate verification when true
+	JSON             bool     // JSON outputs results as JSONL (one JSON object per line)
+	ListSources      bool
+	MISP             string         // MISP is the file the MISP event is written to at the end of the run
+	MaxCredits       map[string]int // MaxCredits caps the credits each source may consume during the run
+	MaxPages         int            // MaxPages caps the number of result pages requested per source and target
+	MaxResults       int            // MaxResults caps the number of records fetched per source and target
+	NoColor          bool           // NoColor disables colored output
+	NoDeduplication  bool           // NoDeduplication disables deduplication of results across sources
+	NoFilter         bool
+	NoRateLimit      bool
+	NoWriteDB        bool // NoWriteDB disables writing to the local SQLite cache
+	Notify           bool // Notify sends findings to the notification sinks of the provider config
+	Output           io.Writer
+	OutputDir        string // OutputDir is the directory one output file per target is written to
+	OutputFile       string
+	Overwrite        bool
+	Passphrase       string              // Passphrase encrypts the output files with age to this passphrase instead
+	Phonebook        bool                // Phonebook enables IntelX phonebook email discovery for domain targets
+	ProviderConfig   string              // ProviderConfig contains the location of the provider config file
+	ProviderKeys     map[string][]string // ProviderKeys are the API keys loaded from the provider config, per source
+	ProviderSettings ProviderSettings    // ProviderSettings is the settings section loaded from the provider config
+	Proxy            string              // Proxy is a proxy URL or a file with one proxy URL per line
+	ProxyCooldown    time.Duration       // ProxyCooldown is how long a failing pooled proxy stays evicted
+	ProxyRotation    string              // ProxyRotation is "request" or "target" for pooled proxies
+	Quiet            bool
+	Redact           string // Redact is the password redaction level of the output: partial, length, hash or remove
+	RedactDB         bool   // RedactDB also redacts the passwords written to the local DB
+	RedactKey        string // RedactKey turns --redact hash into HMAC-SHA256 with this key
+	ReportHTML       string // ReportHTML is the file the HTML report is written to at the end of the run
+	ReportMarkdown   string // ReportMarkdown is the file the Markdown report is written to at the end of the run
+	Sources          []string
+	STIX             string // STIX is the file the STIX 2.1 bundle is written to at the end of the run
+	Summary          bool   // Summary prints a per-source run summary to stderr
+	SummaryJSON      string // SummaryJSON is the file the JSON run summary is written to
+	Stdin            bool
+	Targets          string
+	Template         string // Template is a text/template rendered for each result instead of the format
+	Timeout          time.Duration
+	Type             sources.ScanType
+	UserAgent        string
+	Verbose          bool
+	Verify           bool // Verify enriches results with HIBP password checks and hash identification
+	Version          string
 }
 
 // ResolvedDBPath returns the effective local DB path: the user-provided
@@ -75,7 +104,8 @@ func ListSources(options *Options) {
 }
 
 func listSources(options *Options) {
-	logger.Infof("Current list of available sources. [%d]", len(AllSources))
+	all := sources.NewSources()
+	logger.Infof("Current list of available sources. [%d]", len(all))
 	logger.Infof("Sources marked with an * require key(s) or token(s) to work.")
 	logger.Infof("You can modify %s to configure your keys/tokens.\n", options.ProviderConfig)
 
@@ -86,13 +116,11 @@ func listSources(options *Options) {
 	fmt.Println()
 	fmt.Println("Available sources:")
 
-	sorted := make([]sources.Source, len(AllSources))
-	copy(sorted, AllSources[:])
-	slices.SortFunc(sorted, func(a, b sources.Source) int {
+	slices.SortFunc(all, func(a, b sources.Source) int {
 		return strings.Compare(strings.ToLower(a.Name()), strings.ToLower(b.Name()))
 	})
 
-	for _, source := range sorted {
+	for _, source := range all {
 		sourceName := source.Name()
 		if source.NeedsKey() {
 			_, _ = fmt.Fprintf(options.Output, "  %s *\n", sourceName)
@@ -104,7 +132,10 @@ func listSources(options *Options) {
 
 // loadProvidersFrom runs the app with source config
 func (options *Options) loadProvidersFrom(location string) {
-	if err := UnmarshalFrom(location); err != nil && (!strings.Contains(err.Error(), "file doesn't exist") || errors.Is(err, os.ErrNotExist)) {
+	keys, settings, err := UnmarshalFrom(location)
+	options.ProviderKeys = keys
+	options.ProviderSettings = settings
+	if err != nil && (!strings.Contains(err.Error(), "file doesn't exist") || errors.Is(err, os.ErrNotExist)) {
 		logger.Errorf("Could not read providers from %s: %s\n", location, err)
 	}
 }
@@ -0,0 +1,255 @@
+package runner
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/vflame6/leaker/runner/sources"
+)
+
+// maxTargetFileName caps the length of a target's file name, without its
+// extension, well below common file system limits.
+const maxTargetFileName = 200
+
+// TargetDirectory writes the results of each target to its own file in a
+// directory, in the selected output format, and an index of the result
+// counts per target and source when closed. A target's file is created
+// before the target is searched, so a file that can't be written costs
+// no requests, and closed once it is searched, so only the counts are
+// kept for the index. It stands in for the directory in the runner's
+// output writers.
+type TargetDirectory struct {
+	io.Writer
+	runner  *Runner
+	dir     string
+	targets []*targetFile
+	byName  map[string]*targetFile
+	// names holds the lower-cased file names in use, so targets that only
+	// differ in case or sanitized characters don't share a file.
+	names map[string]bool
+}
+
+// targetFile is the output file of one target.
+type targetFile struct {
+	Target  string         `json:"target"`
+	File    string         `json:"file"`
+	Results int            `json:"results"`
+	Sources map[string]int `json:"sources"`
+
+	file   io.WriteCloser // nil once the target is closed
+	writer io.Writer      // file wrapped in the output format
+}
+
+// newTargetDirectory creates a per-target output directory for the runner.
+func (r *Runner) newTargetDirectory(dir string) *TargetDirectory {
+	return &TargetDirectory{
+		Writer: io.Discard,
+		runner: r,
+		dir:    dir,
+		byName: make(map[string]*targetFile),
+		names:  make(map[string]bool),
+	}
+}
+
+// OpenTarget creates the file of target, unless it is open already.
+// Existing files are refused unless --overwrite is set, and then replaced.
+func (d *TargetDirectory) OpenTarget(target string) error {
+	if _, ok := d.byName[target]; ok {
+		return nil
+	}
+	tf := &targetFile{Target: target, File: d.fileName(target), Sources: make(map[string]int)}
+	file, err := d.runner.createOutputFile(filepath.Join(d.dir, tf.File), false)
+	if err != nil {
+		return err
+	}
+	writers, err := d.runner.formatOutputs([]io.Writer{file})
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+	tf.file = file
+	tf.writer = writers[0]
+	d.byName[target] = tf
+	d.targets = append(d.targets, tf)
+	return nil
+}
+
+// CloseTarget flushes and closes the file of target once it is searched.
+func (d *TargetDirectory) CloseTarget(target string) error {
+	tf, ok := d.byName[target]
+	if !ok || tf.file == nil {
+		return nil
+	}
+	return tf.close()
+}
+
+func (tf *targetFile) close() error {
+	var errs []error
+	if table, ok := tf.writer.(*ResultTable); ok {
+		errs = append(errs, table.Flush())
+	}
+	errs = append(errs, tf.file.Close())
+	tf.file, tf.writer = nil, nil
+	return errors.Join(errs...)
+}
+
+// WriteResult writes a result to the file of its target.
+func (d *TargetDirectory) WriteResult(result *sources.Result, target string) error {
+	if err := d.OpenTarget(target); err != nil {
+		return err
+	}
+	tf := d.byName[target]
+	if tf.file == nil {
+		return fmt.Errorf("file of %s is already closed", target)
+	}
+	tf.Results++
+	tf.Sources[result.Source]++
+	return d.runner.writeResult(tf.writer, result, target)
+}
+
+// fileName returns a file name for target that is safe on every platform
+// and not used by another target yet.
+func (d *TargetDirectory) fileName(target string) string {
+	base := sanitizeFileName(target)
+	ext := d.extension()
+	name := base + ext
+	for i := 2; d.names[strings.ToLower(name)] || strings.EqualFold(name, d.indexName()); i++ {
+		name = base + "-" + strconv.Itoa(i) + ext
+	}
+	d.names[strings.ToLower(name)] = true
+	return name
+}
+
+// extension is the file extension of the output format, with ".age" for
+// encrypted files.
+func (d *TargetDirectory) extension() string {
+	ext := ".txt"
+	switch {
+	case d.runner.outputTemplate != nil:
+	case d.runner.options.Format == FormatCSV:
+		ext = ".csv"
+	case d.runner.options.Format == FormatTSV:
+		ext = ".tsv"
+	case d.runner.options.JSON:
+		ext = ".jsonl"
+	}
+	if d.runner.encryptOutput() {
+		ext += ".age"
+	}
+	return ext
+}
+
+func (d *TargetDirectory) indexName() string {
+	return "index" + d.extension()
+}
+
+// IndexPath is the path of the index file.
+func (d *TargetDirectory) IndexPath() string {
+	return filepath.Join(d.dir, d.indexName())
+}
+
+// Close flushes and closes the target files still open and writes the
+// index.
+func (d *TargetDirectory) Close() error {
+	var errs []error
+	for _, tf := range d.targets {
+		if tf.file != nil {
+			errs = append(errs, tf.close())
+		}
+	}
+	errs = append(errs, d.writeIndex())
+	return errors.Join(errs...)
+}
+
+// writeIndex writes the result counts per target and source in the output
+// format: JSON lines, CSV or TSV rows per target and source, or one plain
+// line per target. Targets without results are listed with a count of 0.
+func (d *TargetDirectory) writeIndex() error {
+	file, err := d.runner.createOutputFile(d.IndexPath(), false)
+	if err != nil {
+		return err
+	}
+	switch {
+	case d.runner.outputTemplate == nil && (d.runner.options.Format == FormatCSV || d.runner.options.Format == FormatTSV):
+		writer := csv.NewWriter(file)
+		if d.runner.options.Format == FormatTSV {
+			writer.Comma = '\t'
+		}
+		_ = writer.Write([]string{"target", "file", "source", "results"})
+		for _, tf := range d.targets {
+			if tf.Results == 0 {
+				_ = writer.Write([]string{tf.Target, tf.File, "", "0"})
+			}
+			for _, source := range slices.Sorted(maps.Keys(tf.Sources)) {
+				_ = writer.Write([]string{tf.Target, tf.File, source, strconv.Itoa(tf.Sources[source])})
+			}
+		}
+		writer.Flush()
+		err = writer.Error()
+	case d.runner.outputTemplate == nil && d.runner.options.JSON:
+		encoder := json.NewEncoder(file)
+		for _, tf := range d.targets {
+			if err = encoder.Encode(tf); err != nil {
+				break
+			}
+		}
+	default:
+		for _, tf := range d.targets {
+			counts := make([]string, 0, len(tf.Sources))
+			for _, source := range slices.Sorted(maps.Keys(tf.Sources)) {
+				counts = append(counts, fmt.Sprintf("%s: %d", source, tf.Sources[source]))
+			}
+			if _, err = fmt.Fprintf(file, "%s\t%d\t%s\t%s\n", tf.Target, tf.Results, tf.File, strings.Join(counts, ", ")); err != nil {
+				break
+			}
+		}
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("could not write index %s: %w", d.IndexPath(), err)
+	}
+	return nil
+}
+
+// sanitizeFileName maps a target to a file name: characters outside
+// letters, digits and "._@+-" become "_", leading dots are dropped so the
+// file is not hidden, long names are cut and Windows device names get a
+// "_" prefix.
+func sanitizeFileName(target string) string {
+	var b strings.Builder
+	for _, r := range target {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', strings.ContainsRune("._@+-", r):
+			b.WriteRune(r)
+		default:
+			b.WriteRune('_')
+		}
+	}
+	name := strings.TrimLeft(b.String(), ".")
+	if len(name) > maxTargetFileName {
+		name = name[:maxTargetFileName]
+	}
+	stem, _, _ := strings.Cut(strings.ToUpper(name), ".")
+	if name == "" || slices.Contains(reservedFileNames, stem) {
+		name = "_" + name
+	}
+	return name
+}
+
+// reservedFileNames are device names Windows refuses as file names, with
+// any extension.
+var reservedFileNames = []string{
+	"CON", "PRN", "AUX", "NUL",
+	"COM1", "COM2", "COM3", "COM4", "COM5", "COM6", "COM7", "COM8", "COM9",
+	"LPT1", "LPT2", "LPT3", "LPT4", "LPT5", "LPT6", "LPT7", "LPT8", "LPT9",
+}
@@ -0,0 +1,273 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vflame6/leaker/runner/sources"
+)
+
+func TestSanitizeFileName(t *testing.T) {
+	for target, want := range map[string]string{
+		"alice@example.com":    "alice@example.com",
+		"Alice Smith":          "Alice_Smith",
+		"../../etc/passwd":     "_.._etc_passwd",
+		".hidden":              "hidden",
+		"a/b\\c:d*e?f\"g<h>i|": "a_b_c_d_e_f_g_h_i_",
+		"+1 555-0100":          "+1_555-0100",
+		"":                     "_",
+		"...":                  "_",
+		"con":                  "_con",
+		"NUL.example":          "_NUL.example",
+		"console":              "console",
+		"пароль":               "______",
+	} {
+		if got := sanitizeFileName(target); got != want {
+			t.Errorf("sanitizeFileName(%q) = %q, want %q", target, got, want)
+		}
+	}
+	if got := sanitizeFileName(strings.Repeat("a", 300)); len(got) != maxTargetFileName {
+		t.Errorf("expected long names cut to %d characters, got %d", maxTargetFileName, len(got))
+	}
+}
+
+func TestTargetDirectory_FileNames(t *testing.T) {
+	d := newTestRunner([]string{}).newTargetDirectory(t.TempDir())
+	for _, tc := range []struct{ target, want string }{
+		{"alice@example.com", "alice@example.com.txt"},
+		{"Alice@example.com", "Alice@example.com-2.txt"},
+		{"alice example", "alice_example.txt"},
+		{"alice/example", "alice_example-2.txt"},
+		{"index", "index-2.txt"},
+	} {
+		if got := d.fileName(tc.target); got != tc.want {
+			t.Errorf("fileName(%q) = %q, want %q", tc.target, got, tc.want)
+		}
+	}
+}
+
+// runOutputDir searches three targets into dir and returns the number of
+// searches made, and the run's error.
+func runOutputDir(t *testing.T, dir string, configure func(*Options)) (int, error) {
+	t.Helper()
+	searches := 0
+	r := newTestRunner([]string{})
+	r.scanSources = []sources.Source{
+		&fakeSource{name: "alpha", onStart: func() { searches++ }, emits: []sources.Result{
+			{Source: "alpha", Email: "alice@example.com", Password: "hunter2"},
+			{Source: "alpha", Email: "bob@example.com", Password: "letmein"},
+		}},
+		&fakeSource{name: "beta", emits: []sources.Result{
+			{Source: "beta", Email: "alice@example.com", Hash: "5f4dcc3b5aa765d61d8327deb882cf99"},
+		}},
+	}
+	r.options.Type = sources.TypeEmail
+	r.options.Targets = "alice@example.com\nbob@example.com\ncarol@example.com"
+	r.options.Output = io.Discard
+	r.options.OutputDir = dir
+	configure(r.options)
+	err := r.RunEnumeration(context.Background())
+	return searches, err
+}
+
+func readTestFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestRunEnumeration_OutputDir(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := runOutputDir(t, dir, func(*Options) {}); err != nil {
+		t.Fatal(err)
+	}
+
+	alice := readTestFile(t, filepath.Join(dir, "alice@example.com.txt"))
+	if !strings.Contains(alice, "hunter2") || !strings.Contains(alice, "5f4dcc3b5aa765d61d8327deb882cf99") || strings.Contains(alice, "letmein") {
+		t.Errorf("unexpected results for alice: %q", alice)
+	}
+	bob := readTestFile(t, filepath.Join(dir, "bob@example.com.txt"))
+	if !strings.Contains(bob, "letmein") || strings.Contains(bob, "hunter2") {
+		t.Errorf("unexpected results for bob: %q", bob)
+	}
+	if got := readTestFile(t, filepath.Join(dir, "carol@example.com.txt")); got != "" {
+		t.Errorf("expected an empty file for a target without results, got %q", got)
+	}
+
+	index := readTestFile(t, filepath.Join(dir, "index.txt"))
+	for _, line := range []string{
+		"alice@example.com\t2\talice@example.com.txt\talpha: 1, beta: 1\n",
+		"bob@example.com\t1\tbob@example.com.txt\talpha: 1\n",
+		"carol@example.com\t0\tcarol@example.com.txt\t\n",
+	} {
+		if !strings.Contains(index, line) {
+			t.Errorf("index misses %q:\n%s", line, index)
+		}
+	}
+}
+
+func TestTargetDirectory_ClosesSearchedTargets(t *testing.T) {
+	r := newTestRunner([]string{})
+	r.scanSources = []sources.Source{&fakeSource{name: "alpha", emits: []sources.Result{
+		{Source: "alpha", Email: "alice@example.com", Password: "hunter2"},
+	}}}
+	r.options.Type = sources.TypeEmail
+	r.options.Format = FormatCSV
+	d := r.newTargetDirectory(t.TempDir())
+
+	// alice is repeated later in the input
+	input := "alice@example.com\nbob@example.com\nalice@example.com\n"
+	if err := r.EnumerateMultipleTargets(context.Background(), strings.NewReader(input), []io.Writer{d}); err != nil {
+		t.Fatal(err)
+	}
+	if len(d.targets) != 2 {
+		t.Fatalf("expected 2 targets, got %d", len(d.targets))
+	}
+	for _, tf := range d.targets {
+		if tf.file != nil {
+			t.Errorf("expected the file of %s closed after its search", tf.Target)
+		}
+	}
+	if got := d.byName["alice@example.com"].Results; got != 1 {
+		t.Errorf("expected the repeated target searched once, got %d results", got)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := readTestFile(t, filepath.Join(d.dir, "alice@example.com.csv")); strings.Count(got, "source,") != 1 || strings.Count(got, "hunter2") != 1 {
+		t.Errorf("unexpected file for the repeated target: %q", got)
+	}
+	if got := readTestFile(t, d.IndexPath()); !strings.Contains(got, "alice@example.com,alice@example.com.csv,alpha,1\n") {
+		t.Errorf("unexpected index: %q", got)
+	}
+}
+
+func TestRunEnumeration_OutputDirFormats(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		configure func(*Options)
+		file      string
+		result    string
+		index     string
+		indexLine string
+		empty     string
+	}{
+		{
+			name:      "json",
+			configure: func(o *Options) { o.JSON = true },
+			file:      "alice@example.com.jsonl",
+			result:    `"password":"hunter2"`,
+			index:     "index.jsonl",
+			indexLine: `{"target":"alice@example.com","file":"alice@example.com.jsonl","results":2,"sources":{"alpha":1,"beta":1}}`,
+		},
+		{
+			name:      "csv",
+			configure: func(o *Options) { o.Format = FormatCSV },
+			file:      "alice@example.com.csv",
+			result:    "hunter2",
+			index:     "index.csv",
+			indexLine: "target,file,source,results\nalice@example.com,alice@example.com.csv,alpha,1\nalice@example.com,alice@example.com.csv,beta,1\nbob@example.com,bob@example.com.csv,alpha,1\ncarol@example.com,carol@example.com.csv,,0\n",
+			empty:     "carol@example.com.csv",
+		},
+		{
+			name:      "tsv",
+			configure: func(o *Options) { o.Format = FormatTSV },
+			file:      "alice@example.com.tsv",
+			result:    "hunter2",
+			index:     "index.tsv",
+			indexLine: "target\tfile\tsource\tresults\nalice@example.com\talice@example.com.tsv\talpha\t1\n",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if _, err := runOutputDir(t, dir, tc.configure); err != nil {
+				t.Fatal(err)
+			}
+			if got := readTestFile(t, filepath.Join(dir, tc.file)); !strings.Contains(got, tc.result) {
+				t.Errorf("unexpected results %q", got)
+			}
+			if got := readTestFile(t, filepath.Join(dir, tc.index)); !strings.Contains(got, tc.indexLine) {
+				t.Errorf("index misses %q:\n%s", tc.indexLine, got)
+			}
+			if tc.empty != "" {
+				if got := readTestFile(t, filepath.Join(dir, tc.empty)); !strings.HasPrefix(got, "source,") || strings.Count(got, "\n") != 1 {
+					t.Errorf("expected only a header for a target without results, got %q", got)
+				}
+			}
+		})
+	}
+}
+
+func TestRunEnumeration_OutputDirOverwrite(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "alice@example.com.txt")
+	if err := os.WriteFile(existing, []byte("keep me\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	searches, err := runOutputDir(t, dir, func(*Options) {})
+	if err == nil {
+		t.Error("expected an error for the existing target file")
+	}
+	if searches != 2 {
+		t.Errorf("expected the target with an existing file skipped before searching, got %d searches", searches)
+	}
+	if got := readTestFile(t, existing); got != "keep me\n" {
+		t.Errorf("existing target file was overwritten: %q", got)
+	}
+	if got := readTestFile(t, filepath.Join(dir, "bob@example.com.txt")); !strings.Contains(got, "letmein") {
+		t.Errorf("expected other targets to be written, got %q", got)
+	}
+
+	// the index now exists, so a second run fails before searching
+	if searches, err := runOutputDir(t, dir, func(*Options) {}); err == nil || searches != 0 {
+		t.Errorf("expected an error for an existing index before searching, got %v after %d searches", err, searches)
+	}
+
+	// --overwrite replaces the files instead of appending to them
+	for range 2 {
+		if _, err := runOutputDir(t, dir, func(o *Options) { o.Overwrite = true }); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := readTestFile(t, existing); strings.Contains(got, "keep me") || strings.Count(got, "hunter2") != 1 {
+		t.Errorf("expected --overwrite to replace the file, got %q", got)
+	}
+	if got := readTestFile(t, filepath.Join(dir, "index.txt")); strings.Count(got, "alice@example.com\t") != 1 {
+		t.Errorf("expected --overwrite to replace the index, got %q", got)
+	}
+}
+
+func TestRunEnumeration_OutputDirEncrypted(t *testing.T) {
+	dir := t.TempDir()
+	identity, key := newTestIdentity(t, t.TempDir(), "key.txt")
+	if _, err := runOutputDir(t, dir, func(o *Options) {
+		o.EncryptTo = []string{identity.Recipient().String()}
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"alice@example.com.txt.age", "index.txt.age"} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Contains(data, []byte("alice@example.com")) {
+			t.Errorf("%s is not encrypted", name)
+		}
+		var decrypted bytes.Buffer
+		if err := DecryptAge(&decrypted, bytes.NewReader(data), []string{key}, ""); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !strings.Contains(decrypted.String(), "alice@example.com") {
+			t.Errorf("%s: unexpected plaintext %q", name, decrypted.String())
+		}
+	}
+}
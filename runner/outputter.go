@@ -42,9 +42,15 @@ type jsonResult struct {
 	Database string            `json:"database,omitempty"`
 	URL      string            `json:"url,omitempty"`
 	Extra    map[string]string `json:"extra,omitempty"`
+	// FirstSeen is set by leaker monitor for newly discovered leaks.
+	FirstSeen string `json:"first_seen,omitempty"`
 }
 
 func WriteJSONResult(writer io.Writer, includeMetadata bool, result *sources.Result, target string) error {
+	return writeJSON(writer, newJSONResult(includeMetadata, result, target))
+}
+
+func newJSONResult(includeMetadata bool, result *sources.Result, target string) jsonResult {
 	jr := jsonResult{
 		Source:   result.Source,
 		Target:   target,
@@ -62,6 +68,10 @@ func WriteJSONResult(writer io.Writer, includeMetadata bool, result *sources.Res
 	if includeMetadata {
 		jr.Database = result.Database
 	}
+	return jr
+}
+
+func writeJSON(writer io.Writer, jr jsonResult) error {
 	data, err := json.Marshal(jr)
 	if err != nil {
 		return err
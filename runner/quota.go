@@ -0,0 +1,156 @@
+package runner
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/vflame6/leaker/logger"
+	"github.com/vflame6/leaker/runner/sources"
+)
+
+// quotaUsageDDL stores credit spend per source and API key fingerprint
+// across runs. remaining is NULL when the provider never reported it.
+const quotaUsageDDL = `CREATE TABLE IF NOT EXISTS quota_usage (
+    source     TEXT NOT NULL,
+    key_id     TEXT NOT NULL,
+    consumed   INTEGER NOT NULL DEFAULT 0,
+    remaining  INTEGER,
+    exhausted  INTEGER NOT NULL DEFAULT 0,
+    updated_at INTEGER NOT NULL,
+    PRIMARY KEY (source, key_id)
+)`
+
+const upsertQuotaSQL = `INSERT INTO quota_usage (source, key_id, consumed, remaining, exhausted, updated_at)
+VALUES (?, ?, ?, ?, ?, ?)
+ON CONFLICT (source, key_id) DO UPDATE SET
+    consumed   = consumed + excluded.consumed,
+    remaining  = COALESCE(excluded.remaining, remaining),
+    exhausted  = excluded.exhausted,
+    updated_at = excluded.updated_at`
+
+// StoredQuota is the accumulated spend of one API key across runs.
+type StoredQuota struct {
+	Source    string
+	KeyID     string
+	Consumed  int
+	Remaining int // -1 if unknown
+	Exhausted bool
+	UpdatedAt time.Time
+}
+
+// SaveQuotaUsage adds the spend of a run to the stored totals. A no-op on
+// read-only or nil handles.
+func (l *LeakerDB) SaveQuotaUsage(usage []sources.QuotaUsage) error {
+	if l == nil || !l.writable || len(usage) == 0 {
+		return nil
+	}
+	tx, err := l.db.Begin()
+	if err != nil {
+		return err
+	}
+	now := time.Now().Unix()
+	for _, u := range usage {
+		var remaining any
+		if u.Remaining >= 0 {
+			remaining = u.Remaining
+		}
+		if _, err := tx.Exec(upsertQuotaSQL, u.Source, u.KeyID, u.Consumed, remaining, u.Exhausted, now); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("save quota usage: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// QuotaUsage returns the stored spend of every API key, ordered by source
+// and key. A nil handle or a cache without the table yields no rows.
+func (l *LeakerDB) QuotaUsage() ([]StoredQuota, error) {
+	if l == nil {
+		return nil, nil
+	}
+	rows, err := l.db.Query(`SELECT source, key_id, consumed, remaining, exhausted, updated_at
+FROM quota_usage ORDER BY source, key_id`)
+	if err != nil {
+		if isMissingTable(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var out []StoredQuota
+	for rows.Next() {
+		var q StoredQuota
+		var remaining sql.NullInt64
+		var updatedAt int64
+		if err := rows.Scan(&q.Source, &q.KeyID, &q.Consumed, &remaining, &q.Exhausted, &updatedAt); err != nil {
+			return nil, err
+		}
+		q.Remaining = -1
+		if remaining.Valid {
+			q.Remaining = int(remaining.Int64)
+		}
+		q.UpdatedAt = time.Unix(updatedAt, 0)
+		out = append(out, q)
+	}
+	return out, rows.Err()
+}
+
+// WriteQuotaUsage writes the stored spend of every API key as an aligned
+// table.
+func WriteQuotaUsage(w io.Writer, quotas []StoredQuota) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "SOURCE\tKEY\tCONSUMED\tREMAINING\tEXHAUSTED\tUPDATED")
+	for _, q := range quotas {
+		remaining := "unknown"
+		if q.Remaining >= 0 {
+			remaining = fmt.Sprint(q.Remaining)
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%t\t%s\n",
+			q.Source, q.KeyID, q.Consumed, remaining, q.Exhausted, q.UpdatedAt.Format(time.DateTime))
+	}
+	return tw.Flush()
+}
+
+// configureQuota creates the run's quota tracker from --max-credits.
+func (r *Runner) configureQuota() {
+	limits := make(map[string]int)
+	for name, limit := range r.options.MaxCredits {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !isKnownSource(name) {
+			logger.Warnf("--max-credits set for unknown source %q", name)
+			continue
+		}
+		limits[name] = limit
+	}
+	r.quota = sources.NewQuotaTracker(limits)
+}
+
+// reportQuota prints the credits spent during the run and adds them to
+// the totals stored in the local DB.
+func (r *Runner) reportQuota() {
+	usage := r.quota.Usage()
+	if len(usage) == 0 {
+		return
+	}
+	logger.Info("Credit usage for this run:")
+	for _, u := range usage {
+		line := fmt.Sprintf("  %s (key %s): %d credits used", u.Source, u.KeyID, u.Consumed)
+		if u.Remaining >= 0 {
+			line += fmt.Sprintf(", %d remaining", u.Remaining)
+		}
+		if u.Exhausted {
+			line += ", quota exhausted"
+		}
+		logger.Info(line)
+	}
+	if err := r.leakerDB.SaveQuotaUsage(usage); err != nil {
+		logger.Warnf("could not save credit usage to the local DB: %s", err)
+	}
+}
@@ -0,0 +1,83 @@
+package runner
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/vflame6/leaker/runner/sources"
+)
+
+// Redaction levels accepted by --redact.
+const (
+	RedactNone    = ""
+	RedactPartial = "partial" // first and last character: h*****2
+	RedactLength  = "length"  // one asterisk per character
+	RedactHash    = "hash"    // SHA-256, or HMAC-SHA256 with --redact-key
+	RedactRemove  = "remove"  // empty
+)
+
+// configureRedaction validates --redact and its options.
+func (r *Runner) configureRedaction() error {
+	switch r.options.Redact {
+	case RedactNone, RedactPartial, RedactLength, RedactHash, RedactRemove:
+	default:
+		return fmt.Errorf("unknown redaction level %q (partial, length, hash or remove)", r.options.Redact)
+	}
+	if r.options.RedactKey != "" && r.options.Redact != RedactHash {
+		return fmt.Errorf("--redact-key needs --redact %s", RedactHash)
+	}
+	if r.options.RedactDB && r.options.Redact == RedactNone {
+		return fmt.Errorf("--redact-db needs a --redact level")
+	}
+	return nil
+}
+
+// redactPassword applies a redaction level to a password. Digests are
+// prefixed with their algorithm so they are not mistaken for leaked
+// hashes. Without a key, short passwords can be recovered from their
+// SHA-256 by brute force; an HMAC key keeps them safe while still letting
+// key holders correlate equal passwords.
+func redactPassword(password, level, key string) string {
+	if password == "" {
+		return ""
+	}
+	switch level {
+	case RedactPartial:
+		return maskPassword(password)
+	case RedactLength:
+		return strings.Repeat("*", utf8.RuneCountInString(password))
+	case RedactHash:
+		if key == "" {
+			sum := sha256.Sum256([]byte(password))
+			return "sha256:" + hex.EncodeToString(sum[:])
+		}
+		mac := hmac.New(sha256.New, []byte(key))
+		mac.Write([]byte(password))
+		return "hmac-sha256:" + hex.EncodeToString(mac.Sum(nil))
+	case RedactRemove:
+		return ""
+	}
+	return password
+}
+
+// RedactResult returns result as it may be written out under a redaction
+// level: the result itself when nothing is redacted, else a redacted copy
+// that keeps the original checksum.
+func RedactResult(result *sources.Result, level, key string) *sources.Result {
+	if level == RedactNone || result.Password == "" {
+		return result
+	}
+	result.Checksum() // cache it so the copy keeps the original one
+	redacted := *result
+	redacted.Password = redactPassword(result.Password, level, key)
+	return &redacted
+}
+
+// redact applies the runner's --redact level to result.
+func (r *Runner) redact(result *sources.Result) *sources.Result {
+	return RedactResult(result, r.options.Redact, r.options.RedactKey)
+}
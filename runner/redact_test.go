@@ -0,0 +1,170 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vflame6/leaker/runner/sources"
+)
+
+func TestRedactPassword(t *testing.T) {
+	for _, tc := range []struct {
+		level, key, password, want string
+	}{
+		{RedactNone, "", "hunter2", "hunter2"},
+		{RedactPartial, "", "hunter2", "h*****2"},
+		{RedactPartial, "", "ab", "**"},
+		{RedactLength, "", "pässwörd", "********"},
+		{RedactHash, "", "hunter2", "sha256:f52fbd32b2b3b86ff88ef6c490628285f482af15ddcb29541f94bcf526a3f6c7"},
+		// HMAC-SHA256("k", "hunter2")
+		{RedactHash, "k", "hunter2", "hmac-sha256:0cd9cde64b418f83ab6358d5fa0fb2b0264ba58b97196e7a99d4b6317f0169c5"},
+		{RedactRemove, "", "hunter2", ""},
+		{RedactLength, "", "", ""},
+	} {
+		if got := redactPassword(tc.password, tc.level, tc.key); got != tc.want {
+			t.Errorf("redactPassword(%q, %q, %q) = %q, want %q", tc.password, tc.level, tc.key, got, tc.want)
+		}
+	}
+	if redactPassword("hunter2", RedactHash, "a") == redactPassword("hunter2", RedactHash, "b") {
+		t.Error("expected different keys to give different digests")
+	}
+}
+
+func TestConfigureRedaction(t *testing.T) {
+	for _, tc := range []struct {
+		options Options
+		valid   bool
+	}{
+		{Options{}, true},
+		{Options{Redact: RedactHash, RedactKey: "k", RedactDB: true}, true},
+		{Options{Redact: "mask"}, false},
+		{Options{Redact: RedactPartial, RedactKey: "k"}, false},
+		{Options{RedactDB: true}, false},
+	} {
+		r := &Runner{options: &tc.options}
+		if err := r.configureRedaction(); (err == nil) != tc.valid {
+			t.Errorf("%+v: unexpected error %v", tc.options, err)
+		}
+	}
+}
+
+func enumerateRedacted(t *testing.T, configure func(*Options)) (*LeakerDB, string) {
+	t.Helper()
+	db := openTestLeakerDB(t)
+	r := newTestRunner([]string{})
+	r.leakerDB = db
+	r.scanSources = []sources.Source{&fakeSource{name: "fake", emits: []sources.Result{
+		{Source: "fake", Email: "alice@example.com", Password: "hunter2"},
+		{Source: "fake", Email: "alice@example.com", Hash: "5f4dcc3b5aa765d61d8327deb882cf99"},
+	}}}
+	r.options.Type = sources.TypeEmail
+	r.options.JSON = true
+	configure(r.options)
+
+	var buf bytes.Buffer
+	if err := r.EnumerateMultipleTargets(context.Background(), strings.NewReader("alice@example.com\n"), []io.Writer{&buf}); err != nil {
+		t.Fatal(err)
+	}
+	return db, buf.String()
+}
+
+func storedPasswords(t *testing.T, db *LeakerDB) []string {
+	t.Helper()
+	var passwords []string
+	for result := range db.Search(context.Background(), "alice@example.com", sources.TypeEmail) {
+		if result.Password != "" {
+			passwords = append(passwords, result.Password)
+		}
+	}
+	return passwords
+}
+
+func TestEnumerate_RedactsOutputButNotDB(t *testing.T) {
+	db, out := enumerateRedacted(t, func(o *Options) { o.Redact = RedactPartial })
+	if strings.Contains(out, "hunter2") || !strings.Contains(out, `"password":"h*****2"`) {
+		t.Errorf("expected the password redacted in output: %s", out)
+	}
+	if !strings.Contains(out, "5f4dcc3b5aa765d61d8327deb882cf99") {
+		t.Errorf("hashes must not be redacted: %s", out)
+	}
+	if got := storedPasswords(t, db); len(got) != 1 || got[0] != "hunter2" {
+		t.Errorf("expected the original password in the DB, got %v", got)
+	}
+}
+
+func TestEnumerate_RedactDB(t *testing.T) {
+	db, out := enumerateRedacted(t, func(o *Options) {
+		o.Redact = RedactRemove
+		o.RedactDB = true
+	})
+	if strings.Contains(out, "hunter2") || strings.Contains(out, `"password"`) {
+		t.Errorf("expected the password removed from output: %s", out)
+	}
+	if got := storedPasswords(t, db); len(got) != 0 {
+		t.Errorf("expected no password in the DB, got %v", got)
+	}
+
+	// the redacted row keeps the checksum of the original result
+	original := sources.Result{Source: "fake", Email: "alice@example.com", Password: "hunter2"}
+	if err := db.Insert(&original); err != nil {
+		t.Fatal(err)
+	}
+	if got := storedPasswords(t, db); len(got) != 0 {
+		t.Errorf("expected the original to dedupe against the redacted row, got %v", got)
+	}
+}
+
+func TestNotifier_SendsRedactedPasswordsAsIs(t *testing.T) {
+	ts, received := newTestReceiver(t)
+	n, err := newNotifier([]NotificationSettings{{Type: NotifyWebhook, URL: ts.URL}}, ts.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.redacted = true
+	r := &Runner{options: &Options{Redact: RedactHash}}
+	n.result("alice@example.com", r.redact(&testLeak), time.Time{})
+	n.Close()
+
+	requests := received()
+	if len(requests) != 1 {
+		t.Fatalf("expected one request, got %d", len(requests))
+	}
+	if !strings.Contains(string(requests[0].body), "sha256:f52fbd32b2b3b86ff88ef6c490628285f482af15ddcb29541f94bcf526a3f6c7") {
+		t.Errorf("expected the digest unmasked: %s", requests[0].body)
+	}
+}
+
+func TestEnumerate_ReportsAndExportsSeeOriginalPasswords(t *testing.T) {
+	r := newTestRunner([]string{})
+	r.scanSources = []sources.Source{&fakeSource{name: "fake", emits: []sources.Result{
+		{Source: "fake", Email: "alice@example.com", Password: "hunter2"},
+	}}}
+	r.options.Type = sources.TypeEmail
+	r.options.Redact = RedactRemove
+	report := NewReport()
+	report.Redact = RedactRemove
+	findings := NewFindings()
+	findings.Redact = RedactRemove
+
+	if err := r.EnumerateMultipleTargets(context.Background(), strings.NewReader("alice@example.com\n"), []io.Writer{report, findings}); err != nil {
+		t.Fatal(err)
+	}
+	if totals := report.Totals(); totals.Plaintext != 1 {
+		t.Errorf("expected the redacted password counted as plaintext, got %+v", totals)
+	}
+	if got := report.Targets[0].Credentials[0].Password; got != "" {
+		t.Errorf("expected the report password removed, got %q", got)
+	}
+	// SHA-256("hunter2")
+	const digest = "f52fbd32b2b3b86ff88ef6c490628285f482af15ddcb29541f94bcf526a3f6c7"
+	if got, hashed := findings.exportPassword(findings.Results[0].Result.Password, ExportPasswordsHash); !hashed || got != digest {
+		t.Errorf("expected the digest of the original password, got %q", got)
+	}
+	if got, _ := findings.exportPassword(findings.Results[0].Result.Password, ExportPasswordsPlain); got != "" {
+		t.Errorf("expected the exported password removed, got %q", got)
+	}
+}
@@ -0,0 +1,317 @@
+package runner
+
+import (
+	"cmp"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/vflame6/leaker/runner/sources"
+	"github.com/vflame6/leaker/utils"
+)
+
+// unknownDatabase labels results whose source did not name the breach.
+const unknownDatabase = "(unknown)"
+
+// Report groups results by target, then by breach database and source,
+// for HTML and Markdown engagement reports. It is filled either as one of
+// the runner's output writers during a run or from a JSONL file. Passwords
+// are only ever stored masked.
+type Report struct {
+	io.Writer
+	Generated time.Time
+	Targets   []*ReportTarget
+	// Verified is set when any result carries an HIBP count from -V.
+	Verified bool
+	// Redact and RedactKey mask passwords at a --redact level instead of
+	// partially.
+	Redact    string
+	RedactKey string
+
+	targets map[string]*ReportTarget
+}
+
+// ReportStats counts results and how their credentials are stored.
+type ReportStats struct {
+	Results   int
+	Plaintext int // results with a clear-text password
+	Hashed    int // results with only a password hash
+	// HIBP counts plaintext passwords found in Have I Been Pwned.
+	HIBP int
+}
+
+// PlaintextRatio is the percentage of credentials stored in clear text.
+func (s ReportStats) PlaintextRatio() string {
+	return percent(s.Plaintext, s.Plaintext+s.Hashed)
+}
+
+// HashedRatio is the percentage of credentials stored as a hash only.
+func (s ReportStats) HashedRatio() string {
+	return percent(s.Hashed, s.Plaintext+s.Hashed)
+}
+
+func (s *ReportStats) add(c ReportCredential) {
+	s.Results++
+	switch {
+	case c.plaintext:
+		s.Plaintext++
+	case c.Hash != "":
+		s.Hashed++
+	}
+	if c.HIBP > 0 {
+		s.HIBP++
+	}
+}
+
+// ReportTarget is the section of one target.
+type ReportTarget struct {
+	Target string
+	ReportStats
+	Groups      []*ReportGroup
+	Credentials []ReportCredential
+
+	groups map[[2]string]*ReportGroup
+}
+
+// ReportGroup counts the results of one breach database and source.
+type ReportGroup struct {
+	Database string
+	Source   string
+	ReportStats
+}
+
+// ReportCredential is one row of the masked credential table.
+type ReportCredential struct {
+	Identity string // email, else username, phone or name
+	Password string // masked
+	Hash     string // shortened
+	Database string
+	Source   string
+	// HIBP is the Have I Been Pwned count of the password, -1 when the
+	// result was not verified.
+	HIBP int
+
+	plaintext bool
+}
+
+// NewReport creates an empty report.
+func NewReport() *Report {
+	return &Report{Writer: io.Discard, Generated: time.Now(), targets: make(map[string]*ReportTarget)}
+}
+
+// Add records a result found for target.
+func (rp *Report) Add(result *sources.Result, target string) {
+	section, ok := rp.targets[target]
+	if !ok {
+		section = &ReportTarget{Target: target, groups: make(map[[2]string]*ReportGroup)}
+		rp.targets[target] = section
+		rp.Targets = append(rp.Targets, section)
+	}
+
+	database := cmp.Or(result.Database, unknownDatabase)
+	credential := ReportCredential{
+		Identity:  cmp.Or(result.Email, result.Username, result.Phone, result.Name),
+		Password:  redactPassword(result.Password, cmp.Or(rp.Redact, RedactPartial), rp.RedactKey),
+		Hash:      shortenHash(result.Hash),
+		Database:  database,
+		Source:    result.Source,
+		HIBP:      -1,
+		plaintext: result.Password != "",
+	}
+	if count, err := strconv.Atoi(result.Extra["hibp_count"]); err == nil {
+		credential.HIBP = count
+		rp.Verified = true
+	}
+
+	key := [2]string{database, result.Source}
+	group, ok := section.groups[key]
+	if !ok {
+		group = &ReportGroup{Database: database, Source: result.Source}
+		section.groups[key] = group
+		section.Groups = append(section.Groups, group)
+	}
+	group.add(credential)
+	section.add(credential)
+	section.Credentials = append(section.Credentials, credential)
+}
+
+// Totals sums the statistics of every target.
+func (rp *Report) Totals() ReportStats {
+	var total ReportStats
+	for _, section := range rp.Targets {
+		total.Results += section.Results
+		total.Plaintext += section.Plaintext
+		total.Hashed += section.Hashed
+		total.HIBP += section.HIBP
+	}
+	return total
+}
+
+// sortGroups orders each target's groups by result count, largest first.
+func (rp *Report) sortGroups() {
+	for _, section := range rp.Targets {
+		slices.SortStableFunc(section.Groups, func(a, b *ReportGroup) int {
+			return cmp.Or(cmp.Compare(b.Results, a.Results), cmp.Compare(a.Database, b.Database), cmp.Compare(a.Source, b.Source))
+		})
+	}
+}
+
+// WriteHTML writes the report as a self-contained HTML page.
+func (rp *Report) WriteHTML(w io.Writer) error {
+	rp.sortGroups()
+	return htmlReportTemplate.Execute(w, rp)
+}
+
+// WriteMarkdown writes the report as Markdown.
+func (rp *Report) WriteMarkdown(w io.Writer) error {
+	rp.sortGroups()
+	return markdownReportTemplate.Execute(w, rp)
+}
+
+// ReadJSONLReport builds a report from leaker JSONL output.
+func ReadJSONLReport(r io.Reader) (*Report, error) {
+	report := NewReport()
+	return report, report.ReadJSONL(r)
+}
+
+// ReadJSONL adds the results of leaker JSONL output to the report.
+func (rp *Report) ReadJSONL(r io.Reader) error {
+	return readJSONL(r, rp.Add)
+}
+
+// WriteReportFiles writes the HTML and Markdown reports to the given
+// paths; an empty path skips that variant.
+func WriteReportFiles(report *Report, htmlPath, markdownPath string, overwrite bool) error {
+	for _, out := range []struct {
+		path  string
+		write func(io.Writer) error
+	}{
+		{htmlPath, report.WriteHTML},
+		{markdownPath, report.WriteMarkdown},
+	} {
+		if out.path == "" {
+			continue
+		}
+		file, err := utils.CreateFileWithSafe(out.path, false, overwrite)
+		if err != nil {
+			return err
+		}
+		err = out.write(file)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("could not write report %s: %w", out.path, err)
+		}
+	}
+	return nil
+}
+
+// shortenHash keeps enough of a hash to recognise it in a report.
+func shortenHash(hash string) string {
+	if runes := []rune(hash); len(runes) > 16 {
+		return string(runes[:16]) + "…"
+	}
+	return hash
+}
+
+func percent(part, total int) string {
+	if total == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.0f%%", float64(part)*100/float64(total))
+}
+
+// markdownEscape keeps values from breaking Markdown table cells.
+func markdownEscape(value string) string {
+	value = strings.NewReplacer("\r", " ", "\n", " ").Replace(value)
+	var b strings.Builder
+	for _, r := range value {
+		if strings.ContainsRune("\\`*_[]<>|#", r) {
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+var reportFuncs = map[string]any{
+	"md":   markdownEscape,
+	"date": func(t time.Time) string { return t.Format(time.DateTime) },
+}
+
+var htmlReportTemplate = htmltemplate.Must(htmltemplate.New("report").Funcs(reportFuncs).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>leaker report</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Roboto, Helvetica, Arial, sans-serif; color: #1f2328; margin: 2rem auto; max-width: 70rem; padding: 0 1rem; }
+h1 { border-bottom: 2px solid #d0d7de; padding-bottom: .3rem; }
+h2 { margin-top: 2.5rem; border-bottom: 1px solid #d0d7de; padding-bottom: .2rem; }
+table { border-collapse: collapse; width: 100%; margin: .8rem 0 1.2rem; font-size: .9rem; }
+th, td { border: 1px solid #d0d7de; padding: .35rem .6rem; text-align: left; vertical-align: top; }
+th { background: #f6f8fa; }
+td.num, th.num { text-align: right; }
+code { font-family: ui-monospace, SFMono-Regular, Menlo, Consolas, monospace; font-size: .85rem; }
+.muted { color: #656d76; }
+.cards { display: flex; flex-wrap: wrap; gap: .8rem; margin: 1rem 0; }
+.card { border: 1px solid #d0d7de; border-radius: 6px; padding: .6rem 1rem; min-width: 8rem; }
+.card b { display: block; font-size: 1.4rem; }
+</style>
+</head>
+<body>
+<h1>leaker report</h1>
+<p class="muted">Generated {{date .Generated}}. Passwords are masked.</p>
+{{with .Totals}}<div class="cards">
+<div class="card"><b>{{len $.Targets}}</b>targets</div>
+<div class="card"><b>{{.Results}}</b>results</div>
+<div class="card"><b>{{.PlaintextRatio}}</b>plaintext ({{.Plaintext}})</div>
+<div class="card"><b>{{.HashedRatio}}</b>hashed ({{.Hashed}})</div>
+{{if $.Verified}}<div class="card"><b>{{.HIBP}}</b>passwords in HIBP</div>{{end}}
+</div>{{end}}
+{{if not .Targets}}<p>No leaks found.</p>{{end}}
+{{range .Targets}}
+<h2>{{.Target}}</h2>
+<p>{{.Results}} results, {{.PlaintextRatio}} plaintext, {{.HashedRatio}} hashed{{if $.Verified}}, {{.HIBP}} passwords in HIBP{{end}}.</p>
+<table>
+<tr><th>Database</th><th>Source</th><th class="num">Results</th><th class="num">Plaintext</th><th class="num">Hashed</th>{{if $.Verified}}<th class="num">In HIBP</th>{{end}}</tr>
+{{range .Groups}}<tr><td>{{.Database}}</td><td>{{.Source}}</td><td class="num">{{.Results}}</td><td class="num">{{.Plaintext}}</td><td class="num">{{.Hashed}}</td>{{if $.Verified}}<td class="num">{{.HIBP}}</td>{{end}}</tr>
+{{end}}</table>
+<table>
+<tr><th>Identity</th><th>Password</th><th>Hash</th><th>Database</th><th>Source</th>{{if $.Verified}}<th class="num">HIBP</th>{{end}}</tr>
+{{range .Credentials}}<tr><td>{{.Identity}}</td><td><code>{{.Password}}</code></td><td><code>{{.Hash}}</code></td><td>{{.Database}}</td><td>{{.Source}}</td>{{if $.Verified}}<td class="num">{{if ge .HIBP 0}}{{.HIBP}}{{end}}</td>{{end}}</tr>
+{{end}}</table>
+{{end}}
+</body>
+</html>
+`))
+
+var markdownReportTemplate = template.Must(template.New("report").Funcs(reportFuncs).Parse(`# leaker report
+
+Generated {{date .Generated}}. Passwords are masked.
+{{with .Totals}}
+| Targets | Results | Plaintext | Hashed |{{if $.Verified}} In HIBP |{{end}}
+|--:|--:|--:|--:|{{if $.Verified}}--:|{{end}}
+| {{len $.Targets}} | {{.Results}} | {{.Plaintext}} ({{.PlaintextRatio}}) | {{.Hashed}} ({{.HashedRatio}}) |{{if $.Verified}} {{.HIBP}} |{{end}}
+{{end}}{{if not .Targets}}
+No leaks found.
+{{end}}{{range .Targets}}
+## {{md .Target}}
+
+{{.Results}} results, {{.PlaintextRatio}} plaintext, {{.HashedRatio}} hashed{{if $.Verified}}, {{.HIBP}} passwords in HIBP{{end}}.
+
+| Database | Source | Results | Plaintext | Hashed |{{if $.Verified}} In HIBP |{{end}}
+|---|---|--:|--:|--:|{{if $.Verified}}--:|{{end}}
+{{range .Groups}}| {{md .Database}} | {{md .Source}} | {{.Results}} | {{.Plaintext}} | {{.Hashed}} |{{if $.Verified}} {{.HIBP}} |{{end}}
+{{end}}
+| Identity | Password | Hash | Database | Source |{{if $.Verified}} HIBP |{{end}}
+|---|---|---|---|---|{{if $.Verified}}--:|{{end}}
+{{range .Credentials}}| {{md .Identity}} | {{md .Password}} | {{md .Hash}} | {{md .Database}} | {{md .Source}} |{{if $.Verified}} {{if ge .HIBP 0}}{{.HIBP}}{{end}} |{{end}}
+{{end}}{{end}}`))
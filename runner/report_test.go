@@ -0,0 +1,139 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vflame6/leaker/runner/sources"
+)
+
+func testReport() *Report {
+	report := NewReport()
+	report.Add(&sources.Result{Source: "leakcheck", Email: "alice@example.com", Password: "hunter2", Database: "Combo",
+		Extra: map[string]string{"hibp_count": "17"}}, "example.com")
+	report.Add(&sources.Result{Source: "snusbase", Email: "bob@example.com", Hash: "5f4dcc3b5aa765d61d8327deb882cf99", Database: "Forum"}, "example.com")
+	report.Add(&sources.Result{Source: "snusbase", Email: "carol@example.com", Hash: "0d107d09f5bbe40cade3de5c71e9e9b7", Database: "Forum"}, "example.com")
+	report.Add(&sources.Result{Source: "proxynova", Username: "<b>dave</b>", Password: "pw"}, "other.com")
+	return report
+}
+
+func TestReport_GroupsAndCounts(t *testing.T) {
+	report := testReport()
+	if len(report.Targets) != 2 || report.Targets[0].Target != "example.com" {
+		t.Fatalf("unexpected targets: %+v", report.Targets)
+	}
+	section := report.Targets[0]
+	if section.Results != 3 || section.Plaintext != 1 || section.Hashed != 2 || section.HIBP != 1 {
+		t.Errorf("unexpected target stats: %+v", section.ReportStats)
+	}
+	if section.PlaintextRatio() != "33%" || section.HashedRatio() != "67%" {
+		t.Errorf("unexpected ratios: %s / %s", section.PlaintextRatio(), section.HashedRatio())
+	}
+	report.sortGroups()
+	if len(section.Groups) != 2 || section.Groups[0].Database != "Forum" || section.Groups[0].Results != 2 {
+		t.Errorf("expected the largest group first, got %+v", section.Groups[0])
+	}
+	if report.Targets[1].Groups[0].Database != unknownDatabase {
+		t.Errorf("expected the unknown database label, got %q", report.Targets[1].Groups[0].Database)
+	}
+	if !report.Verified {
+		t.Error("expected the report to be marked verified")
+	}
+	if total := report.Totals(); total.Results != 4 || total.Plaintext != 2 || total.Hashed != 2 {
+		t.Errorf("unexpected totals: %+v", total)
+	}
+}
+
+func TestReport_HTMLIsSelfContainedAndMasked(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testReport().WriteHTML(&buf); err != nil {
+		t.Fatal(err)
+	}
+	html := buf.String()
+	for _, forbidden := range []string{"hunter2", "<b>dave</b>", "<link", "<script", "src="} {
+		if strings.Contains(html, forbidden) {
+			t.Errorf("HTML report must not contain %q", forbidden)
+		}
+	}
+	for _, want := range []string{"<style>", "h*****2", "&lt;b&gt;dave&lt;/b&gt;", "In HIBP", "Combo"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("HTML report is missing %q", want)
+		}
+	}
+}
+
+func TestReport_Markdown(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testReport().WriteMarkdown(&buf); err != nil {
+		t.Fatal(err)
+	}
+	md := buf.String()
+	if strings.Contains(md, "hunter2") {
+		t.Error("Markdown report must mask passwords")
+	}
+	for _, want := range []string{"## example.com", `h\*\*\*\*\*2`, `\<b\>dave\</b\>`, "| Forum | snusbase | 2 | 0 | 2 | 0 |"} {
+		if !strings.Contains(md, want) {
+			t.Errorf("Markdown report is missing %q:\n%s", want, md)
+		}
+	}
+}
+
+func TestReport_WithoutVerifyOmitsHIBP(t *testing.T) {
+	report := NewReport()
+	report.Add(&sources.Result{Source: "seed", Email: "alice@example.com", Password: "x"}, "alice@example.com")
+	var buf bytes.Buffer
+	if err := report.WriteMarkdown(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "HIBP") {
+		t.Errorf("expected no HIBP columns without -V:\n%s", buf.String())
+	}
+}
+
+func TestReadJSONLReport(t *testing.T) {
+	input := `{"source":"leakcheck","target":"example.com","email":"alice@example.com","password":"hunter2","extra":{"hibp_count":"3"}}
+
+{"source":"snusbase","target":"example.com","email":"bob@example.com","hash":"abc"}
+`
+	report, err := ReadJSONLReport(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Targets) != 1 || report.Targets[0].Results != 2 || !report.Verified {
+		t.Errorf("unexpected report: %+v", report.Targets[0])
+	}
+	if _, err := ReadJSONLReport(strings.NewReader("{not json}\n")); err == nil {
+		t.Error("expected an error for invalid JSON")
+	}
+}
+
+func TestRunEnumeration_WritesReports(t *testing.T) {
+	dir := t.TempDir()
+	r := newTestRunner([]string{})
+	r.scanSources = []sources.Source{&fakeSource{name: "fake", emits: []sources.Result{
+		{Source: "fake", Email: "alice@example.com", Password: "hunter2"},
+	}}}
+	r.options.Type = sources.TypeEmail
+	r.options.Targets = "alice@example.com"
+	r.options.Output = io.Discard
+	r.options.ReportHTML = filepath.Join(dir, "report.html")
+	r.options.ReportMarkdown = filepath.Join(dir, "report.md")
+
+	if err := r.RunEnumeration(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{r.options.ReportHTML, r.options.ReportMarkdown} {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(data), "alice@example.com") {
+			t.Errorf("%s is missing the result", path)
+		}
+	}
+}
@@ -9,10 +9,12 @@ import (
 	"github.com/vflame6/leaker/runner/sources"
 	"github.com/vflame6/leaker/utils"
 	"io"
+	"net"
 	"os"
 	"regexp"
 	"slices"
 	"strings"
+	"text/template"
 )
 
 type Runner struct {
@@ -21,6 +23,27 @@ type Runner struct {
 	// leakerDB is the local SQLite cache handle. May be nil when writes
 	// are disabled and the DB does not exist on disk.
 	leakerDB *LeakerDB
+	// proxy is the validated --proxy URL with credentials applied, and
+	// sourceProxies the per-source overrides from the provider config.
+	// proxyPool replaces proxy when --proxy names a file of proxies.
+	proxy         string
+	sourceProxies map[string]string
+	proxyPool     *sources.ProxyPool
+	// sourceSettings holds the per-source overrides from the provider
+	// config, keyed by lower-cased source name.
+	sourceSettings map[string]SourceSettings
+	// quota tracks credit spend of paid sources for the run.
+	quota *sources.QuotaTracker
+	// stats collects the per-source statistics for the run summary.
+	stats *runStats
+	// errorSink receives every source error when --errors-json is set.
+	errorSink *errorSink
+	// outputTemplate renders results when --template is set.
+	outputTemplate *template.Template
+	// notifier sends findings to the --notify sinks; nil when disabled.
+	notifier *notifier
+	// onResult, when set, is called with every result that is written.
+	onResult func(target string, result *sources.Result)
 }
 
 // Close releases resources held by the runner (currently just the local
@@ -71,6 +94,7 @@ func NewRunner(options *Options) (*Runner, error) {
 
 	r := &Runner{
 		options: options,
+		stats:   newRunStats(),
 	}
 
 	// Open the local DB cache. In writable mode, the file is created if
@@ -94,23 +118,92 @@ func NewRunner(options *Options) (*Runner, error) {
 	}
 	r.leakerDB = db
 
-	// Inject the lookup function into any LocalDB source instance so
-	// its Run() can call LeakerDB.Search without importing the runner
-	// package (which would create a cycle).
-	for _, s := range AllSources {
-		if ldb, ok := s.(*sources.LocalDB); ok {
-			if db != nil {
-				ldb.Lookup = db.Search
-			}
-		}
-	}
-
 	if cfgErr := r.configureSources(); cfgErr != nil {
 		return r, cfgErr
 	}
+	r.configureSourceKeys()
+
+	r.configureLocalDB()
+	r.configureSourceOptions()
+	r.configureQuota()
+	if settingsErr := r.configureSourceSettings(); settingsErr != nil {
+		return r, fmt.Errorf("invalid provider config settings: %w", settingsErr)
+	}
+	if proxyErr := r.configureProxies(os.Getenv); proxyErr != nil {
+		return r, fmt.Errorf("invalid proxy configuration: %w", proxyErr)
+	}
+	if templateErr := r.configureOutputTemplate(); templateErr != nil {
+		return r, fmt.Errorf("invalid output template: %w", templateErr)
+	}
+	if redactErr := r.configureRedaction(); redactErr != nil {
+		return r, redactErr
+	}
+	if notifyErr := r.configureNotifications(); notifyErr != nil {
+		return r, fmt.Errorf("invalid notification configuration: %w", notifyErr)
+	}
 	return r, nil
 }
 
+// configureLocalDB injects the lookup function into the runner's LocalDB
+// source instance so its Run() can call LeakerDB.Search without importing
+// the runner package (which would create a cycle).
+func (r *Runner) configureLocalDB() {
+	for _, s := range r.scanSources {
+		if ldb, ok := s.(*sources.LocalDB); ok && r.leakerDB != nil {
+			ldb.Lookup = r.leakerDB.Search
+		}
+	}
+}
+
+// configureSourceKeys hands the API keys from the provider config to the
+// runner's source instances.
+func (r *Runner) configureSourceKeys() {
+	for _, s := range r.scanSources {
+		if keys := r.options.ProviderKeys[s.Name()]; len(keys) > 0 {
+			s.AddApiKeys(keys)
+		} else if s.NeedsKey() {
+			logger.Debugf("Cannot use the %s source because there is no API key/secret defined for it.", s.Name())
+		}
+	}
+}
+
+// configureSourceOptions applies run options to the selected sources that
+// support them: the --max-pages and --max-results bounds for paginating
+// sources, and the --phonebook expansion for IntelX.
+func (r *Runner) configureSourceOptions() {
+	for _, s := range r.scanSources {
+		if pl, ok := s.(sources.PageLimiter); ok {
+			pl.SetPageLimits(r.options.MaxPages, r.options.MaxResults)
+		}
+		if ix, ok := s.(*sources.IntelX); ok {
+			ix.Phonebook = r.options.Phonebook
+		}
+	}
+}
+
+// configureSourceSettings validates the per-source overrides from the
+// provider config and indexes them by source name.
+func (r *Runner) configureSourceSettings() error {
+	r.sourceSettings = make(map[string]SourceSettings)
+	for name, settings := range r.options.ProviderSettings.Sources {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !isKnownSource(name) {
+			logger.Warnf("Provider config has settings for unknown source %q", name)
+			continue
+		}
+		if err := settings.validate(); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		r.sourceSettings[name] = settings
+	}
+	return nil
+}
+
+// isKnownSource reports whether name is the name of a registered source.
+func isKnownSource(name string) bool {
+	return slices.Contains(sources.SourceNames(), name)
+}
+
 func (r *Runner) configureSources() error {
 	// lowercase all selected sources
 	for i := 0; i < len(r.options.Sources); i++ {
@@ -121,15 +214,17 @@ func (r *Runner) configureSources() error {
 	// every other token must match a real source by name.
 	var allSourcesNames []string
 	allSourcesNames = append(allSourcesNames, "all", "online")
-	for _, source := range AllSources {
-		allSourcesNames = append(allSourcesNames, source.Name())
-	}
+	allSourcesNames = append(allSourcesNames, sources.SourceNames()...)
 	for _, source := range r.options.Sources {
 		if !slices.Contains(allSourcesNames, source) {
 			return fmt.Errorf("invalid source \"%s\" specified in -s flag", source)
 		}
 	}
 
+	// Every runner gets fresh source instances so keys, options and DB
+	// handles never leak between runners.
+	all := sources.NewSources()
+
 	hasAll := slices.Contains(r.options.Sources, "all")
 	hasOnline := slices.Contains(r.options.Sources, "online")
 	hasLocal := slices.Contains(r.options.Sources, sources.LocalSourceName)
@@ -140,21 +235,21 @@ func (r *Runner) configureSources() error {
 			logger.Debug("Source token 'online' is redundant when 'all' is specified")
 		}
 		logger.Debug("Configuring leaker to use all available sources (online + local)")
-		r.scanSources = append(r.scanSources, AllSources[:]...)
+		r.scanSources = append(r.scanSources, all...)
 		return nil
 	}
 
 	// "online" + "local" == "all": every source.
 	if hasOnline && hasLocal {
 		logger.Debug("Configuring leaker to use all available sources (online + local)")
-		r.scanSources = append(r.scanSources, AllSources[:]...)
+		r.scanSources = append(r.scanSources, all...)
 		return nil
 	}
 
 	// "online" alone: every source EXCEPT local.
 	if hasOnline {
 		logger.Debug("Configuring leaker to use all online sources (excluding local)")
-		for _, source := range AllSources {
+		for _, source := range all {
 			if source.Name() != sources.LocalSourceName {
 				r.scanSources = append(r.scanSources, source)
 			}
@@ -164,7 +259,7 @@ func (r *Runner) configureSources() error {
 
 	// Explicit name list. Match by name exactly as today.
 	logger.Debugf("Configuring leaker to use specified sources: %s", strings.Join(r.options.Sources, ", "))
-	for _, source := range AllSources {
+	for _, source := range all {
 		if slices.Contains(r.options.Sources, strings.ToLower(source.Name())) {
 			r.scanSources = append(r.scanSources, source)
 		}
@@ -172,6 +267,43 @@ func (r *Runner) configureSources() error {
 	return nil
 }
 
+// encryptOutput reports whether output files are encrypted with age.
+func (r *Runner) encryptOutput() bool {
+	return len(r.options.EncryptTo) > 0 || r.options.Passphrase != ""
+}
+
+// createOutputFile creates an output file, encrypted with age when
+// requested. Existing files are refused unless --overwrite is set, and are
+// then appended to or, for a file that must only hold this run's output,
+// truncated.
+func (r *Runner) createOutputFile(path string, appendToFile bool) (io.WriteCloser, error) {
+	// an age file can't be appended to, so an encrypted output replaces it
+	encrypt := r.encryptOutput()
+	appendToFile = appendToFile && !encrypt
+
+	// by default, leaker will raise an error if the output file is already exist
+	// it is done like that to increase data safety, but this behavior can be overwritten with --overwrite
+	var plain *os.File
+	var err error
+	if r.options.Overwrite {
+		plain, err = utils.CreateFileWithSafe(path, appendToFile, true)
+	} else {
+		plain, err = utils.CreateFileWithSafe(path, appendToFile, false)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !encrypt {
+		return plain, nil
+	}
+	encrypted, err := NewAgeWriter(plain, r.options.EncryptTo, r.options.Passphrase)
+	if err != nil {
+		_ = plain.Close()
+		return nil, fmt.Errorf("could not encrypt output: %w", err)
+	}
+	return &encryptedFile{WriteCloser: encrypted, file: plain}, nil
+}
+
 func (r *Runner) RunEnumeration(ctx context.Context) error {
 	var err error
 
@@ -181,25 +313,107 @@ func (r *Runner) RunEnumeration(ctx context.Context) error {
 		return err
 	}
 
+	// fail before spending any requests if the summary, reports or exports can't be written
+	for _, path := range []string{r.options.SummaryJSON, r.options.ReportHTML, r.options.ReportMarkdown, r.options.STIX, r.options.MISP} {
+		if path != "" && !r.options.Overwrite && utils.FileExists(path) {
+			return fmt.Errorf("file already exists: %s", path)
+		}
+	}
+	if r.options.HashDir != "" && !r.options.Overwrite {
+		if path := existingHashFile(r.options.HashDir); path != "" {
+			return fmt.Errorf("file already exists: %s", path)
+		}
+	}
+
+	var directory *TargetDirectory
+	if r.options.OutputDir != "" {
+		directory = r.newTargetDirectory(r.options.OutputDir)
+		if !r.options.Overwrite && utils.FileExists(directory.IndexPath()) {
+			return fmt.Errorf("file already exists: %s", directory.IndexPath())
+		}
+	}
+
+	// open the error sink before any output is created, so a failure leaves
+	// no half-written output behind
+	r.errorSink, err = openErrorSink(r.options.ErrorsJSON, r.options.Overwrite)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = r.errorSink.Close()
+	}()
+
 	// configure output
 	outputs := []io.Writer{r.options.Output}
 
-	var file *os.File
+	var file io.WriteCloser
 	if r.options.OutputFile != "" {
-		// by default, leaker will raise an error if the output file is already exist
-		// it is done like that to increase data safety, but this behavior can be overwritten with --overwrite
-		if r.options.Overwrite {
-			file, err = utils.CreateFileWithSafe(r.options.OutputFile, true, true)
-		} else {
-			file, err = utils.CreateFileWithSafe(r.options.OutputFile, true, false)
-		}
+		file, err = r.createOutputFile(r.options.OutputFile, true)
 		if err != nil {
 			return err
 		}
 		outputs = append(outputs, file)
 	}
 
-	return r.EnumerateMultipleTargets(ctx, t, outputs)
+	outputs, err = r.formatOutputs(outputs)
+	if err != nil {
+		if file != nil {
+			_ = file.Close()
+		}
+		return fmt.Errorf("could not write output header: %w", err)
+	}
+	var report *Report
+	if r.options.ReportHTML != "" || r.options.ReportMarkdown != "" {
+		report = NewReport()
+		report.Redact, report.RedactKey = r.options.Redact, r.options.RedactKey
+		outputs = append(outputs, report)
+	}
+	if directory != nil {
+		outputs = append(outputs, directory)
+	}
+	var findings *Findings
+	if r.options.STIX != "" || r.options.MISP != "" || r.options.HashDir != "" {
+		findings = NewFindings()
+		findings.Redact, findings.RedactKey = r.options.Redact, r.options.RedactKey
+		outputs = append(outputs, findings)
+	}
+
+	err = r.EnumerateMultipleTargets(ctx, t, outputs)
+	if flushErr := flushOutputs(outputs); flushErr != nil {
+		logger.Errorf("could not write results: %s", flushErr)
+	}
+	if file != nil {
+		if closeErr := file.Close(); closeErr != nil {
+			logger.Errorf("could not write output file: %s", closeErr)
+		}
+	}
+	if directory != nil {
+		if closeErr := directory.Close(); closeErr != nil {
+			logger.Errorf("could not write output directory: %s", closeErr)
+		}
+	}
+	r.notifier.Close()
+	if report != nil {
+		if reportErr := WriteReportFiles(report, r.options.ReportHTML, r.options.ReportMarkdown, r.options.Overwrite); reportErr != nil {
+			logger.Errorf("%s", reportErr)
+		}
+	}
+	if findings != nil {
+		if exportErr := WriteExportFiles(findings, r.options.STIX, r.options.MISP, r.options.ExportPasswords, r.options.Overwrite); exportErr != nil {
+			logger.Errorf("%s", exportErr)
+		}
+		if r.options.HashDir != "" {
+			if hashErr := WriteHashFiles(findings, r.options.HashDir, r.options.Overwrite); hashErr != nil {
+				logger.Errorf("%s", hashErr)
+			}
+		}
+	}
+	r.reportProxyPool()
+	r.reportQuota()
+	if summaryErr := r.reportSummary(os.Stderr); summaryErr != nil {
+		logger.Errorf("could not write run summary: %s", summaryErr)
+	}
+	return err
 }
 
 // Target-syntax validators. The TLD quantifier is {2,} (not a fixed upper
@@ -213,7 +427,8 @@ var (
 
 // matchesTargetType reports whether line is a syntactically valid target for
 // the given scan type. Phone input is expected to be digit-normalized already.
-// Types without a syntax constraint (username, keyword) always pass.
+// Types without a syntax constraint (username, keyword, name, password)
+// always pass.
 func matchesTargetType(scanType sources.ScanType, line string) bool {
 	switch scanType {
 	case sources.TypeEmail:
@@ -222,11 +437,46 @@ func matchesTargetType(scanType sources.ScanType, line string) bool {
 		return domainRegex.MatchString(line)
 	case sources.TypePhone:
 		return phoneRegex.MatchString(line)
+	case sources.TypeIP:
+		return net.ParseIP(line) != nil
+	case sources.TypeHash:
+		return !strings.ContainsAny(line, " \t")
 	default:
 		return true
 	}
 }
 
+// isCaseSensitive reports whether targets of the scan type must keep their
+// original case. Passwords and hashes (bcrypt, base64) are case-sensitive;
+// every other target is lower-cased before enumeration.
+func isCaseSensitive(scanType sources.ScanType) bool {
+	return scanType == sources.TypePassword || scanType == sources.TypeHash
+}
+
+// normalizeTarget trims the target and lower-cases it unless the scan type
+// is case-sensitive. Phone input is reduced to its digits, so formats like
+// "+7 (995) 234-10-96" are accepted.
+func normalizeTarget(scanType sources.ScanType, target string) string {
+	target = strings.TrimSpace(target)
+	if !isCaseSensitive(scanType) {
+		target = strings.ToLower(target)
+	}
+	if scanType == sources.TypePhone {
+		target = utils.ExtractPhoneDigits(target)
+	}
+	return target
+}
+
+// ParseTarget normalizes a target of the scan type the way the CLI does
+// and checks its syntax.
+func ParseTarget(scanType sources.ScanType, raw string) (string, error) {
+	target := normalizeTarget(scanType, raw)
+	if target == "" || !matchesTargetType(scanType, target) {
+		return "", fmt.Errorf("invalid %s target %q", scanType, raw)
+	}
+	return target, nil
+}
+
 func (r *Runner) EnumerateMultipleTargets(ctx context.Context, reader io.Reader, writers []io.Writer) error {
 	if !r.options.NoFilter {
 		logger.Debugf("Results filtering is enabled, leaker will filter results by matching every result to inputted target.")
@@ -236,27 +486,113 @@ func (r *Runner) EnumerateMultipleTargets(ctx context.Context, reader io.Reader,
 
 	scanner := bufio.NewScanner(reader)
 
+	// searched tracks the targets already enumerated, so targets repeated
+	// in the input and email addresses found by --expand-emails for
+	// overlapping domain targets aren't searched twice.
+	searched := make(map[string]struct{})
+
 	var errs []error
 	for scanner.Scan() {
-		line := strings.ToLower(strings.TrimSpace(scanner.Text()))
-
-		// Normalize phone input: extract digits from formats like "+7 (995) 234-10-96"
-		if r.options.Type == sources.TypePhone {
-			line = utils.ExtractPhoneDigits(line)
-		}
+		line := normalizeTarget(r.options.Type, scanner.Text())
 
 		// check if the line is a syntactically valid target for the scan type
 		if line == "" || !matchesTargetType(r.options.Type, line) {
 			logger.Infof("Can't parse input as target, skipping: %s", line)
 			continue
 		}
+		if _, ok := searched[line]; ok {
+			logger.Infof("Target already searched, skipping: %s", line)
+			continue
+		}
+		searched[line] = struct{}{}
+
+		if err := openTarget(writers, line); err != nil {
+			logger.Errorf("skipping %s: %s", line, err)
+			r.stats.failed("", err)
+			errs = append(errs, err)
+			continue
+		}
 
 		// run enumeration for a single line
-		if err := r.EnumerateSingleTarget(ctx, line, r.options.Type, r.options.Timeout, writers); err != nil {
+		discovered, err := r.enumerateTarget(ctx, line, r.options.Type, r.options.Timeout, writers)
+		if err != nil {
 			logger.Errorf("error enumerating %s: %s", line, err)
+			r.stats.failed("", err)
 			errs = append(errs, err)
 		}
+		if err := closeTarget(writers, line); err != nil {
+			logger.Errorf("could not close the output of %s: %s", line, err)
+			r.stats.failed("", err)
+			errs = append(errs, err)
+		}
+
+		// enumerate email addresses discovered for a domain target
+		for _, email := range discovered {
+			if ctx.Err() != nil {
+				break
+			}
+			if _, ok := searched[email]; ok {
+				continue
+			}
+			searched[email] = struct{}{}
+			if err := openTarget(writers, email); err != nil {
+				logger.Errorf("skipping %s: %s", email, err)
+				r.stats.failed("", err)
+				errs = append(errs, err)
+				continue
+			}
+			if _, err := r.enumerateTarget(ctx, email, sources.TypeEmail, r.options.Timeout, writers); err != nil {
+				logger.Errorf("error enumerating %s: %s", email, err)
+				r.stats.failed("", err)
+				errs = append(errs, err)
+			}
+			if err := closeTarget(writers, email); err != nil {
+				logger.Errorf("could not close the output of %s: %s", email, err)
+				r.stats.failed("", err)
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%w: %w", ErrTargetsFailed, errors.Join(errs...))
+	}
+	return nil
+}
+
+// targetOpener is implemented by output writers that prepare for each
+// target before it is searched.
+type targetOpener interface {
+	OpenTarget(target string) error
+}
+
+// targetCloser is implemented by output writers that release a target's
+// resources once it is searched.
+type targetCloser interface {
+	CloseTarget(target string) error
+}
+
+// openTarget prepares the writers for target, so a target whose output
+// can't be written is skipped before any request is made.
+func openTarget(writers []io.Writer, target string) error {
+	for _, writer := range writers {
+		if opener, ok := writer.(targetOpener); ok {
+			if err := opener.OpenTarget(target); err != nil {
+				return err
+			}
+		}
 	}
+	return nil
+}
 
+// closeTarget releases the writers' resources for target once it is
+// searched.
+func closeTarget(writers []io.Writer, target string) error {
+	var errs []error
+	for _, writer := range writers {
+		if closer, ok := writer.(targetCloser); ok {
+			errs = append(errs, closer.CloseTarget(target))
+		}
+	}
 	return errors.Join(errs...)
 }
@@ -3,7 +3,10 @@ package runner
 import (
 	"bytes"
 	"context"
+	"errors"
 	"io"
+	"net/http"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -21,15 +24,15 @@ func newTestRunner(sourceNames []string) *Runner {
 	return &Runner{options: opts}
 }
 
-// TestConfigureSources_All verifies that "all" adds every source in AllSources,
+// TestConfigureSources_All verifies that "all" adds every registered source,
 // including the local source.
 func TestConfigureSources_All(t *testing.T) {
 	r := newTestRunner([]string{"all"})
 	if err := r.configureSources(); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if len(r.scanSources) != len(AllSources) {
-		t.Errorf("expected %d sources, got %d", len(AllSources), len(r.scanSources))
+	if len(r.scanSources) != len(sources.SourceNames()) {
+		t.Errorf("expected %d sources, got %d", len(sources.SourceNames()), len(r.scanSources))
 	}
 	if !containsSourceName(r.scanSources, sources.LocalSourceName) {
 		t.Error("expected 'all' to include the local source")
@@ -54,7 +57,7 @@ func TestConfigureSources_Online(t *testing.T) {
 	if err := r.configureSources(); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	want := len(AllSources) - 1 // all minus local
+	want := len(sources.SourceNames()) - 1 // all minus local
 	if len(r.scanSources) != want {
 		t.Errorf("expected %d online sources, got %d", want, len(r.scanSources))
 	}
@@ -85,8 +88,8 @@ func TestConfigureSources_OnlineAndLocalEqualsAll(t *testing.T) {
 	if err := r.configureSources(); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if len(r.scanSources) != len(AllSources) {
-		t.Errorf("expected %d sources, got %d", len(AllSources), len(r.scanSources))
+	if len(r.scanSources) != len(sources.SourceNames()) {
+		t.Errorf("expected %d sources, got %d", len(sources.SourceNames()), len(r.scanSources))
 	}
 	if !containsSourceName(r.scanSources, sources.LocalSourceName) {
 		t.Error("expected local to be present")
@@ -100,8 +103,8 @@ func TestConfigureSources_AllPlusOnlineRedundant(t *testing.T) {
 	if err := r.configureSources(); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if len(r.scanSources) != len(AllSources) {
-		t.Errorf("expected %d sources, got %d", len(AllSources), len(r.scanSources))
+	if len(r.scanSources) != len(sources.SourceNames()) {
+		t.Errorf("expected %d sources, got %d", len(sources.SourceNames()), len(r.scanSources))
 	}
 }
 
@@ -141,14 +144,60 @@ func TestConfigureSources_NoSharedState(t *testing.T) {
 	}
 
 	// Both should have the correct count — not doubled from leftover global state
-	if len(r1.scanSources) != len(AllSources) {
-		t.Errorf("r1: expected %d sources, got %d", len(AllSources), len(r1.scanSources))
+	if len(r1.scanSources) != len(sources.SourceNames()) {
+		t.Errorf("r1: expected %d sources, got %d", len(sources.SourceNames()), len(r1.scanSources))
 	}
-	if len(r2.scanSources) != len(AllSources) {
-		t.Errorf("r2: expected %d sources, got %d", len(AllSources), len(r2.scanSources))
+	if len(r2.scanSources) != len(sources.SourceNames()) {
+		t.Errorf("r2: expected %d sources, got %d", len(sources.SourceNames()), len(r2.scanSources))
 	}
 }
 
+// TestConfigureSources_FreshInstancesPerRunner verifies that runners build
+// their own source instances, so keys given to one never reach another.
+func TestConfigureSources_FreshInstancesPerRunner(t *testing.T) {
+	r1 := newTestRunner([]string{"leakcheck"})
+	r1.options.ProviderKeys = map[string][]string{"leakcheck": {"key-1"}}
+	r2 := newTestRunner([]string{"leakcheck"})
+	for _, r := range []*Runner{r1, r2} {
+		if err := r.configureSources(); err != nil {
+			t.Fatal(err)
+		}
+		r.configureSourceKeys()
+	}
+
+	if r1.scanSources[0] == r2.scanSources[0] {
+		t.Fatal("expected each runner to get its own source instance")
+	}
+
+	// LeakCheck skips targets without a key, so r2's instance must make no
+	// request while r1's sends its own key
+	for _, tc := range []struct {
+		runner *Runner
+		want   []string
+	}{
+		{r1, []string{"key-1"}},
+		{r2, nil},
+	} {
+		var keys []string
+		session := &sources.Session{Client: &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			keys = append(keys, req.Header.Get("X-API-Key"))
+			return nil, errors.New("no network in tests")
+		})}}
+		for range tc.runner.scanSources[0].Run(context.Background(), "a@b.com", sources.TypeEmail, session) {
+		}
+		if !slices.Equal(keys, tc.want) {
+			t.Errorf("expected requests with keys %v, got %v", tc.want, keys)
+		}
+	}
+}
+
+// roundTripFunc is an http.RoundTripper backed by a function.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
 // TestEnumerateMultipleTargets_SkipsBlankLines verifies blank lines are skipped.
 func TestEnumerateMultipleTargets_SkipsBlankLines(t *testing.T) {
 	r := newTestRunner([]string{})
@@ -237,6 +286,104 @@ func TestMatchesTargetType_Email(t *testing.T) {
 	}
 }
 
+// TestMatchesTargetType_Pivots checks syntax validation for the pivot scan
+// types: IP addresses must parse, hashes must not contain whitespace, and
+// names and passwords accept anything.
+func TestMatchesTargetType_Pivots(t *testing.T) {
+	cases := []struct {
+		scanType sources.ScanType
+		line     string
+		want     bool
+	}{
+		{sources.TypeIP, "10.0.0.1", true},
+		{sources.TypeIP, "2001:db8::1", true},
+		{sources.TypeIP, "10.0.0", false},
+		{sources.TypeHash, "5f4dcc3b5aa765d61d8327deb882cf99", true},
+		{sources.TypeHash, "not a hash", false},
+		{sources.TypeName, "john smith", true},
+		{sources.TypePassword, "Pa55 w0rd!", true},
+	}
+	for _, c := range cases {
+		if got := matchesTargetType(c.scanType, c.line); got != c.want {
+			t.Errorf("matchesTargetType(%s, %q) = %v, want %v", c.scanType, c.line, got, c.want)
+		}
+	}
+}
+
+// TestEnumerateMultipleTargets_KeepsPasswordCase verifies that password
+// targets are not lower-cased before reaching the sources.
+func TestEnumerateMultipleTargets_KeepsPasswordCase(t *testing.T) {
+	var seen []string
+	src := &targetRecorder{fakeSource: fakeSource{name: "recorder"}, targets: &seen}
+
+	r := newTestRunner([]string{})
+	r.scanSources = []sources.Source{src}
+	r.options.Type = sources.TypePassword
+	r.options.NoFilter = true
+
+	if err := r.EnumerateMultipleTargets(context.Background(), strings.NewReader("HunTer2\n"), []io.Writer{&bytes.Buffer{}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(seen) != 1 || seen[0] != "HunTer2" {
+		t.Errorf("expected password target to keep its case, got %q", seen)
+	}
+}
+
+// TestEnumerateMultipleTargets_ExpandsDiscoveredEmails verifies that
+// --expand-emails re-enumerates each distinct email address found for a
+// domain target exactly once, and ignores addresses at other domains.
+func TestEnumerateMultipleTargets_ExpandsDiscoveredEmails(t *testing.T) {
+	var seen []string
+	src := &targetRecorder{
+		fakeSource: fakeSource{name: "recorder", emits: []sources.Result{
+			{Source: "recorder", Email: "alice@example.com"},
+			{Source: "recorder", Email: "ALICE@example.com", Password: "x"},
+			{Source: "recorder", Email: "bob@mail.example.com"},
+			{Source: "recorder", Email: "carol@example.com.evil.org"},
+		}},
+		targets: &seen,
+	}
+
+	r := newTestRunner([]string{})
+	r.scanSources = []sources.Source{src}
+	r.options.Type = sources.TypeDomain
+	r.options.ExpandEmails = true
+
+	input := strings.NewReader("example.com\nmail.example.com\n")
+	if err := r.EnumerateMultipleTargets(context.Background(), input, []io.Writer{&bytes.Buffer{}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"example.com", "alice@example.com", "bob@mail.example.com", "mail.example.com"}
+	if !slices.Equal(seen, want) {
+		t.Errorf("expected targets %q, got %q", want, seen)
+	}
+}
+
+// TestConfigureSourceOptions_Phonebook verifies that --phonebook is
+// passed on to the IntelX source.
+func TestConfigureSourceOptions_Phonebook(t *testing.T) {
+	ix := &sources.IntelX{}
+	r := newTestRunner([]string{})
+	r.options.Phonebook = true
+	r.scanSources = []sources.Source{ix}
+	r.configureSourceOptions()
+
+	if !ix.Phonebook {
+		t.Error("expected IntelX phonebook lookups to be enabled")
+	}
+}
+
+// targetRecorder is a fakeSource that records every target it is run with.
+type targetRecorder struct {
+	fakeSource
+	targets *[]string
+}
+
+func (t *targetRecorder) Run(ctx context.Context, target string, st sources.ScanType, s *sources.Session) <-chan sources.Result {
+	*t.targets = append(*t.targets, target)
+	return t.fakeSource.Run(ctx, target, st, s)
+}
+
 func TestEnumerateMultipleTargets_SkipsNonDomainForDomainType(t *testing.T) {
 	r := newTestRunner([]string{})
 	r.options.Type = sources.TypeDomain
@@ -476,3 +623,32 @@ func (o *orderingSource) UsesKey() bool       { return o.inner.UsesKey() }
 func (o *orderingSource) NeedsKey() bool      { return o.inner.NeedsKey() }
 func (o *orderingSource) AddApiKeys([]string) {}
 func (o *orderingSource) RateLimit() int      { return o.inner.RateLimit() }
+
+// pagingSource is a fakeSource that records the page limits it receives.
+type pagingSource struct {
+	fakeSource
+	maxPages, maxResults int
+}
+
+func (p *pagingSource) SetPageLimits(maxPages, maxResults int) {
+	p.maxPages = maxPages
+	p.maxResults = maxResults
+}
+
+// TestConfigureSourceOptions_AppliesPageLimits verifies that --max-pages and
+// --max-results reach sources implementing PageLimiter and that other
+// sources are left alone.
+func TestConfigureSourceOptions_AppliesPageLimits(t *testing.T) {
+	paging := &pagingSource{fakeSource: fakeSource{name: "paging"}}
+	plain := &fakeSource{name: "plain"}
+
+	r := newTestRunner([]string{})
+	r.options.MaxPages = 3
+	r.options.MaxResults = 250
+	r.scanSources = []sources.Source{paging, plain}
+	r.configureSourceOptions()
+
+	if paging.maxPages != 3 || paging.maxResults != 250 {
+		t.Errorf("expected limits 3/250, got %d/%d", paging.maxPages, paging.maxResults)
+	}
+}
@@ -0,0 +1,469 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/vflame6/leaker/logger"
+	"github.com/vflame6/leaker/runner/sources"
+)
+
+// DefaultMaxJobs is the number of searches the API server runs at once
+// when ServeOptions.MaxJobs is not set.
+const DefaultMaxJobs = 4
+
+// maxRequestBody caps the size of a search request body.
+const maxRequestBody = 1 << 20
+
+// ServeOptions configures the HTTP API server.
+type ServeOptions struct {
+	Listen  string // address to listen on, e.g. 127.0.0.1:8080
+	Token   string // bearer token every request must present
+	MaxJobs int    // searches running at once; more are refused with 429
+	// JobWorkers is the number of queued jobs run at once.
+	JobWorkers int
+}
+
+// Server exposes the runner over HTTP. Every search runs through a fork
+// of the base runner, so it shares the local DB, keys, proxies and credit
+// limits but gets its own sources and statistics.
+type Server struct {
+	runner *Runner
+	token  string
+	jobs   chan struct{}
+	// queue runs submitted jobs; nil when the local DB is read-only.
+	queue *JobQueue
+}
+
+// NewServer creates an API server on top of a configured runner.
+func NewServer(r *Runner, options ServeOptions) (*Server, error) {
+	if options.Token == "" {
+		return nil, errors.New("an API token is required to serve the API")
+	}
+	if options.MaxJobs <= 0 {
+		options.MaxJobs = DefaultMaxJobs
+	}
+	server := &Server{
+		runner: r,
+		token:  options.Token,
+		jobs:   make(chan struct{}, options.MaxJobs),
+	}
+	queue, err := r.NewJobQueue(options.JobWorkers)
+	if err != nil {
+		logger.Warnf("Job endpoints are disabled: %s", err)
+	}
+	server.queue = queue
+	return server, nil
+}
+
+// Handler returns the HTTP handler of the API.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/sources", s.handleSources)
+	mux.HandleFunc("POST /api/v1/search", s.handleSearch)
+	mux.HandleFunc("GET /api/v1/cache", s.handleCache)
+	mux.HandleFunc("POST /api/v1/jobs", s.handleSubmitJob)
+	mux.HandleFunc("GET /api/v1/jobs", s.handleListJobs)
+	mux.HandleFunc("GET /api/v1/jobs/{id}", s.handleGetJob)
+	mux.HandleFunc("GET /api/v1/jobs/{id}/results", s.handleJobResults)
+	mux.HandleFunc("DELETE /api/v1/jobs/{id}", s.handleCancelJob)
+	return s.authenticate(mux)
+}
+
+// Serve runs the API server and its job workers until ctx is cancelled.
+// In-flight searches are cancelled with ctx, running jobs are queued
+// again, and the credits spent are reported on shutdown.
+func (r *Runner) Serve(ctx context.Context, options ServeOptions) error {
+	server, err := NewServer(r, options)
+	if err != nil {
+		return err
+	}
+	httpServer := &http.Server{
+		Addr:              options.Listen,
+		Handler:           server.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+
+	queueCtx, stopQueue := context.WithCancel(ctx)
+	defer stopQueue()
+	queueDone := make(chan struct{})
+	go func() {
+		defer close(queueDone)
+		if server.queue != nil {
+			server.queue.Run(queueCtx)
+		}
+	}()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- httpServer.ListenAndServe()
+	}()
+	logger.Infof("Serving the leaker API on %s", options.Listen)
+
+	select {
+	case err = <-serveErr:
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		err = httpServer.Shutdown(shutdownCtx)
+	}
+	stopQueue()
+	<-queueDone
+	r.reportQuota()
+	return err
+}
+
+// authenticate rejects requests without the bearer token.
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeAPIError(w, http.StatusUnauthorized, "missing or invalid API token")
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+// sourceStatus describes a source and whether keys are configured for it.
+type sourceStatus struct {
+	Name     string `json:"name"`
+	UsesKey  bool   `json:"uses_key"`
+	NeedsKey bool   `json:"needs_key"`
+	Keys     int    `json:"keys"`
+}
+
+func (s *Server) handleSources(w http.ResponseWriter, _ *http.Request) {
+	var statuses []sourceStatus
+	for _, source := range sources.NewSources() {
+		statuses = append(statuses, sourceStatus{
+			Name:     source.Name(),
+			UsesKey:  source.UsesKey(),
+			NeedsKey: source.NeedsKey(),
+			Keys:     len(s.runner.options.ProviderKeys[source.Name()]),
+		})
+	}
+	slices.SortFunc(statuses, func(a, b sourceStatus) int { return strings.Compare(a.Name, b.Name) })
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(statuses)
+}
+
+// SearchOptions are the per-search settings accepted by the API and stored
+// with jobs.
+type SearchOptions struct {
+	Sources         []string `json:"sources,omitempty"`
+	NoFilter        bool     `json:"no_filter,omitempty"`
+	NoDeduplication bool     `json:"no_deduplication,omitempty"`
+	Verify          bool     `json:"verify,omitempty"`
+	Metadata        bool     `json:"metadata,omitempty"`
+}
+
+// apply overrides the run options of a forked runner.
+func (o SearchOptions) apply(options *Options, scanType sources.ScanType) {
+	if len(o.Sources) > 0 {
+		options.Sources = slices.Clone(o.Sources)
+	}
+	options.Type = scanType
+	options.JSON = true
+	options.NoFilter = o.NoFilter
+	options.NoDeduplication = o.NoDeduplication
+	options.Verify = o.Verify
+	options.Metadata = o.Metadata
+}
+
+// searchRequest is the body of POST /api/v1/search.
+type searchRequest struct {
+	Target string `json:"target"`
+	Type   string `json:"type"`
+	SearchOptions
+}
+
+// handleSearch runs a search and streams the results as NDJSON, or as
+// Server-Sent Events when the client accepts text/event-stream. Both carry
+// source errors and a final search summary: as "error" and "done" events
+// over SSE, and as NDJSON lines whose "type" is "error" or "done", which
+// result lines lack.
+func (s *Server) handleSearch(w http.ResponseWriter, req *http.Request) {
+	var body searchRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, req.Body, maxRequestBody))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&body); err != nil {
+		writeAPIError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %s", err))
+		return
+	}
+	scanType, target, err := parseAPITarget(body.Type, body.Target)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	search, err := s.runner.fork(func(options *Options) {
+		body.SearchOptions.apply(options, scanType)
+	})
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	select {
+	case s.jobs <- struct{}{}:
+		defer func() { <-s.jobs }()
+	default:
+		writeAPIError(w, http.StatusTooManyRequests, "too many searches running, retry later")
+		return
+	}
+
+	sse := strings.Contains(req.Header.Get("Accept"), "text/event-stream")
+	controller := http.NewResponseController(w)
+	results := &streamWriter{w: w, controller: controller}
+	if sse {
+		results.event = "result"
+		search.errorSink = newErrorSink(&streamWriter{w: w, controller: controller, event: "error"})
+		w.Header().Set("Content-Type", "text/event-stream")
+	} else {
+		search.errorSink = newErrorSink(results)
+		search.errorSink.typed = true
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := search.enumerateTarget(req.Context(), target, scanType, s.runner.options.Timeout, []io.Writer{results}); err != nil {
+		logger.Errorf("error enumerating %s: %s", target, err)
+	}
+	if req.Context().Err() != nil {
+		return
+	}
+	summary := search.stats.snapshot(nil)
+	if sse {
+		done, _ := json.Marshal(summary)
+		_, _ = (&streamWriter{w: w, controller: controller, event: "done"}).Write(done)
+		return
+	}
+	_ = json.NewEncoder(results).Encode(typedRunSummary{Type: "done", RunSummary: summary})
+}
+
+// jobRequest is the body of POST /api/v1/jobs.
+type jobRequest struct {
+	Targets []string `json:"targets"`
+	Type    string   `json:"type"`
+	SearchOptions
+}
+
+// jobQueue returns the queue, answering 503 when jobs are disabled.
+func (s *Server) jobQueue(w http.ResponseWriter) *JobQueue {
+	if s.queue == nil {
+		writeAPIError(w, http.StatusServiceUnavailable, "jobs need a writable local DB")
+	}
+	return s.queue
+}
+
+func (s *Server) handleSubmitJob(w http.ResponseWriter, req *http.Request) {
+	queue := s.jobQueue(w)
+	if queue == nil {
+		return
+	}
+	var body jobRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, req.Body, maxRequestBody))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&body); err != nil {
+		writeAPIError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %s", err))
+		return
+	}
+	scanType, ok := sources.ParseScanType(body.Type)
+	if !ok {
+		writeAPIError(w, http.StatusBadRequest, fmt.Sprintf("unknown scan type %q", body.Type))
+		return
+	}
+	job, err := queue.Submit(scanType, body.Targets, body.SearchOptions)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeAPIJSON(w, http.StatusAccepted, job)
+}
+
+func (s *Server) handleListJobs(w http.ResponseWriter, req *http.Request) {
+	jobs, err := s.runner.leakerDB.Jobs(JobStatus(req.URL.Query().Get("status")))
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if jobs == nil {
+		jobs = []Job{}
+	}
+	writeAPIJSON(w, http.StatusOK, jobs)
+}
+
+func (s *Server) handleGetJob(w http.ResponseWriter, req *http.Request) {
+	job, err := s.runner.leakerDB.Job(req.PathValue("id"))
+	if err != nil {
+		writeJobError(w, err)
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, job)
+}
+
+// handleJobResults streams the results found so far by a job as NDJSON.
+func (s *Server) handleJobResults(w http.ResponseWriter, req *http.Request) {
+	id := req.PathValue("id")
+	if _, err := s.runner.leakerDB.Job(id); err != nil {
+		writeJobError(w, err)
+		return
+	}
+	results, err := s.runner.leakerDB.JobResults(req.Context(), id)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	for _, jr := range results {
+		if err := WriteJSONResult(w, true, s.runner.redact(&jr.Result), jr.Target); err != nil {
+			return
+		}
+	}
+}
+
+func (s *Server) handleCancelJob(w http.ResponseWriter, req *http.Request) {
+	if s.jobQueue(w) == nil {
+		return
+	}
+	id := req.PathValue("id")
+	if err := s.runner.leakerDB.CancelJob(id); err != nil {
+		writeJobError(w, err)
+		return
+	}
+	job, err := s.runner.leakerDB.Job(id)
+	if err != nil {
+		writeJobError(w, err)
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, job)
+}
+
+func writeJobError(w http.ResponseWriter, err error) {
+	if errors.Is(err, ErrJobNotFound) {
+		writeAPIError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	writeAPIError(w, http.StatusConflict, err.Error())
+}
+
+// handleCache streams the local DB matches for a target as NDJSON without
+// querying any online source.
+func (s *Server) handleCache(w http.ResponseWriter, req *http.Request) {
+	scanType, target, err := parseAPITarget(req.URL.Query().Get("type"), req.URL.Query().Get("target"))
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	if s.runner.leakerDB == nil {
+		return
+	}
+	out := &streamWriter{w: w, controller: http.NewResponseController(w)}
+	for result := range s.runner.leakerDB.Search(req.Context(), target, scanType) {
+		if result.Error != nil {
+			logger.Errorf("could not search the local DB for %s: %s", target, result.Error)
+			return
+		}
+		if err := WriteJSONResult(out, s.runner.options.Metadata, s.runner.redact(&result), target); err != nil {
+			return
+		}
+	}
+}
+
+// parseAPITarget validates the scan type and target of an API request and
+// normalizes the target the way the CLI does.
+func parseAPITarget(typeName, raw string) (sources.ScanType, string, error) {
+	scanType, ok := sources.ParseScanType(typeName)
+	if !ok {
+		return 0, "", fmt.Errorf("unknown scan type %q", typeName)
+	}
+	target, err := ParseTarget(scanType, raw)
+	if err != nil {
+		return 0, "", err
+	}
+	return scanType, target, nil
+}
+
+// fork returns a runner for a single API search. It shares the base
+// runner's local DB, proxies, source settings and credit tracker, and
+// builds its own source instances from the options as adjusted by
+// configure.
+func (r *Runner) fork(configure func(*Options)) (*Runner, error) {
+	options := *r.options
+	options.Sources = slices.Clone(options.Sources)
+	configure(&options)
+
+	fork := &Runner{
+		options:        &options,
+		leakerDB:       r.leakerDB,
+		proxy:          r.proxy,
+		sourceProxies:  r.sourceProxies,
+		proxyPool:      r.proxyPool,
+		sourceSettings: r.sourceSettings,
+		quota:          r.quota,
+		stats:          newRunStats(),
+	}
+	if err := fork.configureSources(); err != nil {
+		return nil, err
+	}
+	fork.configureSourceKeys()
+	fork.configureLocalDB()
+	fork.configureSourceOptions()
+	return fork, nil
+}
+
+// streamWriter writes each line to an HTTP response and flushes it, as a
+// Server-Sent Event when event is set.
+type streamWriter struct {
+	w          io.Writer
+	controller *http.ResponseController
+	event      string
+}
+
+func (s *streamWriter) Write(p []byte) (int, error) {
+	var err error
+	if s.event == "" {
+		_, err = s.w.Write(p)
+	} else {
+		for _, line := range bytes.Split(bytes.TrimRight(p, "\n"), []byte("\n")) {
+			if _, err = fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", s.event, line); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+	_ = s.controller.Flush()
+	return len(p), nil
+}
+
+// Close implements io.Closer so a streamWriter can back an errorSink; the
+// response itself is closed by net/http.
+func (s *streamWriter) Close() error { return nil }
+
+func writeAPIJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeAPIError(w http.ResponseWriter, status int, message string) {
+	writeAPIJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,238 @@
+package runner
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/vflame6/leaker/runner/sources"
+)
+
+const testAPIToken = "test-token"
+
+// newTestServer serves a runner whose local DB holds two leaks for
+// alice@example.com.
+func newTestServer(t *testing.T, maxJobs int) (*Server, *httptest.Server) {
+	t.Helper()
+	db, err := OpenLeakerDB(tempDBPath(t), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	for _, r := range []sources.Result{
+		{Source: "seed", Email: "alice@example.com", Password: "one"},
+		{Source: "seed", Email: "alice@example.com", Password: "two"},
+	} {
+		if err := db.Insert(&r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := newTestRunner([]string{sources.LocalSourceName})
+	r.leakerDB = db
+	r.options.ProviderKeys = map[string][]string{"leakcheck": {"k1", "k2"}}
+	server, err := NewServer(r, ServeOptions{Token: testAPIToken, MaxJobs: maxJobs})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(server.Handler())
+	t.Cleanup(ts.Close)
+	return server, ts
+}
+
+func apiRequest(t *testing.T, method, url, body string, header map[string]string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+testAPIToken)
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = resp.Body.Close() })
+	return resp
+}
+
+func TestNewServer_RequiresToken(t *testing.T) {
+	if _, err := NewServer(newTestRunner(nil), ServeOptions{}); err == nil {
+		t.Error("expected an error without a token")
+	}
+}
+
+func TestServer_RejectsMissingToken(t *testing.T) {
+	_, ts := newTestServer(t, 1)
+	resp := apiRequest(t, http.MethodGet, ts.URL+"/api/v1/sources", "", map[string]string{"Authorization": "Bearer wrong"})
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401, got %d", resp.StatusCode)
+	}
+}
+
+func TestServer_ListsSourcesWithKeyStatus(t *testing.T) {
+	_, ts := newTestServer(t, 1)
+	resp := apiRequest(t, http.MethodGet, ts.URL+"/api/v1/sources", "", nil)
+
+	var statuses []sourceStatus
+	if err := json.NewDecoder(resp.Body).Decode(&statuses); err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != len(sources.SourceNames()) {
+		t.Fatalf("expected every source, got %d", len(statuses))
+	}
+	for _, s := range statuses {
+		if s.Name == "leakcheck" && (s.Keys != 2 || !s.NeedsKey) {
+			t.Errorf("unexpected leakcheck status %+v", s)
+		}
+	}
+}
+
+func TestServer_SearchStreamsNDJSON(t *testing.T) {
+	_, ts := newTestServer(t, 1)
+	resp := apiRequest(t, http.MethodPost, ts.URL+"/api/v1/search", `{"target":"Alice@Example.com","type":"email"}`, nil)
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/x-ndjson" {
+		t.Fatalf("unexpected response %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	var lines []jsonResult
+	var done typedRunSummary
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if strings.Contains(scanner.Text(), `"type":"done"`) {
+			if err := json.Unmarshal(scanner.Bytes(), &done); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		var line jsonResult
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("invalid NDJSON line %q: %v", scanner.Text(), err)
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) != 2 || lines[0].Target != "alice@example.com" {
+		t.Errorf("unexpected results %+v", lines)
+	}
+	if done.Type != "done" || done.Results != 2 || done.Failures != 0 {
+		t.Errorf("expected a final summary line, got %+v", done)
+	}
+}
+
+func TestServer_SearchStreamsNDJSONErrors(t *testing.T) {
+	server, ts := newTestServer(t, 1)
+	// a closed DB makes the local source fail
+	_ = server.runner.leakerDB.Close()
+	resp := apiRequest(t, http.MethodPost, ts.URL+"/api/v1/search", `{"target":"alice@example.com","type":"email"}`, nil)
+
+	var types []string
+	var sourceErr typedSourceError
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var line struct {
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("invalid NDJSON line %q: %v", scanner.Text(), err)
+		}
+		types = append(types, line.Type)
+		if line.Type == "error" {
+			if err := json.Unmarshal(scanner.Bytes(), &sourceErr); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if !slices.Equal(types, []string{"error", "done"}) {
+		t.Errorf("expected an error line and a summary, got %v", types)
+	}
+	if sourceErr.Source != sources.LocalSourceName || sourceErr.Target != "alice@example.com" || sourceErr.Error == "" {
+		t.Errorf("unexpected error line %+v", sourceErr)
+	}
+}
+
+func TestServer_SearchStreamsSSE(t *testing.T) {
+	_, ts := newTestServer(t, 1)
+	resp := apiRequest(t, http.MethodPost, ts.URL+"/api/v1/search", `{"target":"alice@example.com","type":"email"}`,
+		map[string]string{"Accept": "text/event-stream"})
+
+	events := map[string]int{}
+	var done RunSummary
+	scanner := bufio.NewScanner(resp.Body)
+	var event string
+	for scanner.Scan() {
+		line := scanner.Text()
+		if name, ok := strings.CutPrefix(line, "event: "); ok {
+			event = name
+			events[event]++
+		}
+		if data, ok := strings.CutPrefix(line, "data: "); ok && event == "done" {
+			if err := json.Unmarshal([]byte(data), &done); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if events["result"] != 2 || events["done"] != 1 || done.Results != 2 {
+		t.Errorf("unexpected events %v, summary %+v", events, done)
+	}
+}
+
+func TestServer_SearchRejectsInvalidRequests(t *testing.T) {
+	_, ts := newTestServer(t, 1)
+	for _, body := range []string{
+		`{"target":"alice@example.com","type":"subdomain"}`,
+		`{"target":"not-an-email","type":"email"}`,
+		`{"target":"alice@example.com","type":"email","sources":["nope"]}`,
+		`{"target":"alice@example.com","type":"email","unknown":true}`,
+	} {
+		resp := apiRequest(t, http.MethodPost, ts.URL+"/api/v1/search", body, nil)
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", body, resp.StatusCode)
+		}
+	}
+}
+
+func TestServer_SearchConcurrencyLimit(t *testing.T) {
+	server, ts := newTestServer(t, 1)
+	server.jobs <- struct{}{}
+	defer func() { <-server.jobs }()
+
+	resp := apiRequest(t, http.MethodPost, ts.URL+"/api/v1/search", `{"target":"alice@example.com","type":"email"}`, nil)
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("expected 429 while all job slots are taken, got %d", resp.StatusCode)
+	}
+}
+
+func TestServer_QueriesCache(t *testing.T) {
+	_, ts := newTestServer(t, 1)
+	resp := apiRequest(t, http.MethodGet, ts.URL+"/api/v1/cache?type=email&target=alice@example.com", "", nil)
+
+	count := 0
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		count++
+	}
+	if resp.StatusCode != http.StatusOK || count != 2 {
+		t.Errorf("expected 2 cached results, got status %d and %d lines", resp.StatusCode, count)
+	}
+}
+
+func TestServer_CacheRedactsPasswords(t *testing.T) {
+	server, ts := newTestServer(t, 1)
+	server.runner.options.Redact = RedactLength
+	resp := apiRequest(t, http.MethodGet, ts.URL+"/api/v1/cache?type=email&target=alice@example.com", "", nil)
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(body), `"password":"one"`) || !strings.Contains(string(body), `"password":"***"`) {
+		t.Errorf("expected redacted passwords: %s", body)
+	}
+}
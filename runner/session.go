@@ -0,0 +1,214 @@
+package runner
+
+import (
+	"bufio"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/vflame6/leaker/logger"
+	"github.com/vflame6/leaker/runner/sources"
+	"github.com/vflame6/leaker/utils"
+)
+
+// Environment variables holding proxy credentials. They take precedence
+// over the provider config and keep secrets off the command line.
+const (
+	envProxyUsername = "LEAKER_PROXY_USERNAME"
+	envProxyPassword = "LEAKER_PROXY_PASSWORD"
+)
+
+// configureProxies validates --proxy and the per-source proxies from the
+// provider config, applies proxy credentials and stores the resulting
+// URLs on the runner. Any invalid proxy is a fatal configuration error so
+// traffic never silently goes out directly.
+func (r *Runner) configureProxies(getenv func(string) string) error {
+	settings := r.options.ProviderSettings
+	username := firstNonEmpty(getenv(envProxyUsername), settings.ProxyUsername)
+	password := firstNonEmpty(getenv(envProxyPassword), settings.ProxyPassword)
+
+	if r.options.Proxy != "" && utils.FileExists(r.options.Proxy) {
+		pool, err := loadProxyPool(r.options.Proxy, username, password, r.options.ProxyCooldown)
+		if err != nil {
+			return err
+		}
+		r.proxyPool = pool
+		logger.Debugf("Using a pool of %d proxies from %s, rotating per %s", len(pool.Stats()), r.options.Proxy, r.proxyRotation())
+	} else if r.options.Proxy != "" {
+		proxyURL, err := sources.ParseProxyURL(r.options.Proxy)
+		if err != nil {
+			return err
+		}
+		if proxyURL.User != nil {
+			logger.Warnf("Proxy credentials on the command line are visible to other local users; prefer %s/%s or the provider config",
+				envProxyUsername, envProxyPassword)
+		}
+		proxyURL = sources.WithProxyCredentials(proxyURL, username, password)
+		r.proxy = proxyURL.String()
+		logger.Debugf("Using proxy %s", proxyURL.Redacted())
+	}
+
+	r.sourceProxies = make(map[string]string)
+	for name, sourceSettings := range r.sourceSettings {
+		if sourceSettings.Proxy == "" {
+			continue
+		}
+		proxyURL, err := sources.ParseProxyURL(sourceSettings.Proxy)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		proxyURL = sources.WithProxyCredentials(proxyURL, username, password)
+		r.sourceProxies[name] = proxyURL.String()
+		logger.Debugf("Using proxy %s for %s", proxyURL.Redacted(), name)
+	}
+	return nil
+}
+
+// loadProxyPool reads one proxy URL per line from file. Blank lines and
+// lines starting with # are skipped.
+func loadProxyPool(file, username, password string, cooldown time.Duration) (*sources.ProxyPool, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	var proxies []*url.URL
+	scanner := bufio.NewScanner(f)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		proxyURL, err := sources.ParseProxyURL(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", file, lineNumber, err)
+		}
+		proxies = append(proxies, sources.WithProxyCredentials(proxyURL, username, password))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(proxies) == 0 {
+		return nil, fmt.Errorf("no proxies found in %s", file)
+	}
+	return sources.NewProxyPool(proxies, cooldown)
+}
+
+// proxyRotation returns the configured rotation mode, defaulting to
+// per-request rotation.
+func (r *Runner) proxyRotation() sources.ProxyRotation {
+	if r.options.ProxyRotation == string(sources.RotatePerTarget) {
+		return sources.RotatePerTarget
+	}
+	return sources.RotatePerRequest
+}
+
+// reportProxyPool logs the health of every pooled proxy at the end of a run.
+func (r *Runner) reportProxyPool() {
+	if r.proxyPool == nil {
+		return
+	}
+	stats := r.proxyPool.Stats()
+	available := 0
+	for _, s := range stats {
+		if !s.Evicted {
+			available++
+		}
+	}
+	logger.Infof("Proxy pool health: %d of %d proxies available", available, len(stats))
+	for _, s := range stats {
+		state := "ok"
+		if s.Evicted {
+			state = "evicted"
+		}
+		logger.Infof("  %s: %s, %d requests, %d failures, %d evictions", s.Proxy, state, s.Requests, s.Failures, s.Evictions)
+	}
+}
+
+// sessionSet holds the HTTP sessions used while enumerating one target:
+// a shared session plus one per source that is routed differently or has
+// its own timeout or request allowance.
+type sessionSet struct {
+	shared   *sources.Session
+	bySource map[string]*sources.Session
+}
+
+// newSessionSet creates the sessions for a single target.
+func (r *Runner) newSessionSet(timeout time.Duration) (*sessionSet, error) {
+	shared, err := r.newSession(timeout, "")
+	if err != nil {
+		return nil, err
+	}
+	set := &sessionSet{shared: shared, bySource: make(map[string]*sources.Session)}
+
+	for _, s := range r.scanSources {
+		settings := r.sourceSettings[s.Name()]
+		proxy := r.sourceProxies[s.Name()]
+		if proxy == "" && settings.Timeout == 0 && settings.MaxRequests == 0 {
+			continue
+		}
+		sourceTimeout := timeout
+		if settings.Timeout > 0 {
+			sourceTimeout = settings.Timeout
+		}
+		session, err := r.newSession(sourceTimeout, proxy)
+		if err != nil {
+			set.Close()
+			return nil, err
+		}
+		session.LimitRequests(settings.MaxRequests)
+		set.bySource[s.Name()] = session
+	}
+	return set, nil
+}
+
+// newSession creates a session through proxy, or through --proxy (single
+// proxy or pool) when proxy is empty, reporting credit spend to the run's
+// quota tracker.
+func (r *Runner) newSession(timeout time.Duration, proxy string) (*sources.Session, error) {
+	var session *sources.Session
+	if proxy == "" && r.proxyPool != nil {
+		session = sources.NewPoolSession(timeout, r.options.UserAgent, r.proxyPool, r.proxyRotation(), r.options.Insecure)
+	} else {
+		if proxy == "" {
+			proxy = r.proxy
+		}
+		var err error
+		session, err = sources.NewSession(timeout, r.options.UserAgent, proxy, r.options.Insecure)
+		if err != nil {
+			return nil, err
+		}
+	}
+	session.Quota = r.quota
+	return session, nil
+}
+
+// get returns the session a source should use.
+func (s *sessionSet) get(name string) *sources.Session {
+	if session, ok := s.bySource[name]; ok {
+		return session
+	}
+	return s.shared
+}
+
+// Close closes every session in the set.
+func (s *sessionSet) Close() {
+	s.shared.Close()
+	for _, session := range s.bySource {
+		session.Close()
+	}
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
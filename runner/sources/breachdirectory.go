@@ -7,7 +7,6 @@ import (
 	"io"
 	"net/http"
 
-	"github.com/vflame6/leaker/logger"
 	"github.com/vflame6/leaker/utils"
 )
 
@@ -34,6 +33,11 @@ func (s *BreachDirectory) Run(ctx context.Context, target string, scanType ScanT
 	go func() {
 		defer close(results)
 
+		// Auto-detection only covers account identifiers.
+		if scanType.IsPivot() {
+			return
+		}
+
 		randomApiKey := utils.PickRandom(s.apiKeys, s.Name(), s.NeedsKey())
 		if randomApiKey == "" {
 			return
@@ -51,7 +55,7 @@ func (s *BreachDirectory) Run(ctx context.Context, target string, scanType ScanT
 		req.Header.Set("x-rapidapi-host", "breachdirectory.p.rapidapi.com")
 		req.Header.Set("Accept", "application/json")
 
-		logger.Debugf("Sending a request in BreachDirectory source for %s", target)
+		session.Log().Debugf("Sending a request in BreachDirectory source for %s", target)
 		resp, err := session.Client.Do(req)
 		if err != nil {
 			results <- Result{Source: s.Name(), Error: err}
@@ -64,7 +68,7 @@ func (s *BreachDirectory) Run(ctx context.Context, target string, scanType ScanT
 			results <- Result{Source: s.Name(), Error: err}
 			return
 		}
-		logger.Debugf("Response from BreachDirectory source: status code [%d], size [%d]", resp.StatusCode, len(body))
+		session.Log().Debugf("Response from BreachDirectory source: status code [%d], size [%d]", resp.StatusCode, len(body))
 
 		if resp.StatusCode != http.StatusOK {
 			results <- Result{
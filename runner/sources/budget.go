@@ -0,0 +1,33 @@
+package sources
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrBudgetExceeded is reported when a source stops early because it used
+// up its per-target time budget or request allowance. The runner treats it
+// as a warning rather than a source failure.
+var ErrBudgetExceeded = errors.New("budget exceeded")
+
+// LimitRequests caps the number of HTTP requests sent through the session.
+// Further requests fail with an error wrapping ErrBudgetExceeded. A
+// non-positive limit disables the cap.
+func (s *Session) LimitRequests(limit int) {
+	if t, ok := s.Client.Transport.(*CustomTransport); ok {
+		t.maxRequests = int64(limit)
+	}
+}
+
+// checkRequestBudget counts a request against the session's allowance.
+func (t *CustomTransport) checkRequestBudget(req *http.Request) error {
+	if t.maxRequests <= 0 {
+		return nil
+	}
+	if t.requests.Add(1) > t.maxRequests {
+		return fmt.Errorf("%w: request limit of %d reached before %s %s",
+			ErrBudgetExceeded, t.maxRequests, req.Method, req.URL.Host)
+	}
+	return nil
+}
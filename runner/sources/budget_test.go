@@ -0,0 +1,34 @@
+package sources
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSession_LimitRequests(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	session := newTestSession(t)
+	session.LimitRequests(2)
+
+	for i := 0; i < 3; i++ {
+		req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, srv.URL, nil)
+		resp, err := session.Client.Do(req)
+		if i < 2 {
+			if err != nil {
+				t.Fatalf("request %d: unexpected error: %v", i, err)
+			}
+			session.DiscardHTTPResponse(resp)
+			continue
+		}
+		if !errors.Is(err, ErrBudgetExceeded) {
+			t.Errorf("expected ErrBudgetExceeded on request %d, got %v", i, err)
+		}
+	}
+}
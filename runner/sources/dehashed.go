@@ -7,13 +7,26 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
 
-	"github.com/vflame6/leaker/logger"
 	"github.com/vflame6/leaker/utils"
 )
 
+const dehashedDefaultBaseURL = "https://api.dehashed.com"
+
+const (
+	// dehashedPageSize is the number of entries requested per page.
+	dehashedPageSize = 1000
+	// dehashedMaxResults is the deepest DeHashed pages: it refuses
+	// requests where page*size exceeds it.
+	dehashedMaxResults = 10000
+)
+
 type DeHashed struct {
+	pageLimits
 	apiKeys []string
+	baseURL string
 }
 
 type dehashedSearchRequest struct {
@@ -64,81 +77,132 @@ func (s *DeHashed) Run(ctx context.Context, target string, scanType ScanType, se
 			query = target
 		case TypePhone:
 			query = "phone:" + target
-		}
-
-		searchReq := dehashedSearchRequest{
-			Query:  query,
-			Page:   1,
-			Size:   100,
-			DeDupe: true,
-		}
-
-		body, err := json.Marshal(searchReq)
-		if err != nil {
-			results <- Result{Source: s.Name(), Error: err}
-			return
-		}
-
-		req, err := http.NewRequestWithContext(ctx, "POST", "https://api.dehashed.com/v2/search",
-			bytes.NewReader(body))
-		if err != nil {
-			results <- Result{Source: s.Name(), Error: err}
-			return
-		}
-		req.Header.Set("Dehashed-Api-Key", randomApiKey)
-		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set("Accept", "application/json")
-
-		logger.Debugf("Sending a request in DeHashed source for %s", target)
-		resp, err := session.Client.Do(req)
-		if err != nil {
-			results <- Result{Source: s.Name(), Error: err}
-			return
-		}
-		defer session.DiscardHTTPResponse(resp)
-
-		respBody, err := io.ReadAll(resp.Body)
-		if err != nil {
-			results <- Result{Source: s.Name(), Error: err}
+		case TypeHash:
+			query = "hashed_password:" + target
+		case TypeIP:
+			query = "ip_address:" + target
+		case TypeName:
+			query = "name:" + strconv.Quote(target)
+		case TypePassword:
+			query = "password:" + strconv.Quote(target)
+		default:
 			return
 		}
-		logger.Debugf("Response from DeHashed source: status code [%d], size [%d]", resp.StatusCode, len(respBody))
 
-		if resp.StatusCode != http.StatusOK {
-			results <- Result{
-				Source: s.Name(),
-				Error:  fmt.Errorf("DeHashed returned status %d: %s", resp.StatusCode, string(respBody)),
+		fetched, total := 0, 0
+		lastPageFull, providerCapped := false, false
+		for page := 1; page <= s.pageCap() && fetched < s.resultCap(); page++ {
+			if page*dehashedPageSize > dehashedMaxResults {
+				providerCapped = true
+				break
+			}
+			if page > 1 && !waitNextPage(ctx, s.RateLimit()) {
+				return
+			}
+			if err := session.Quota.Allow(s.Name()); err != nil {
+				results <- Result{Source: s.Name(), Error: err}
+				break
 			}
-			return
-		}
-
-		var response dehashedSearchResponse
-		if err := json.Unmarshal(respBody, &response); err != nil {
-			results <- Result{Source: s.Name(), Error: err}
-			return
-		}
 
-		for _, entry := range response.Entries {
-			r := Result{
-				Source:   s.Name(),
-				Email:    entry.Email,
-				Username: entry.Username,
-				Password: entry.Password,
-				Hash:     entry.HashedPassword,
-				Name:     entry.Name,
-				Phone:    entry.Phone,
-				IP:       entry.IPAddress,
-				Database: entry.DatabaseName,
+			session.Log().Debugf("Sending a request in DeHashed source for %s (page %d)", target, page)
+			response, err := s.search(ctx, session, randomApiKey, query, page)
+			if err != nil {
+				results <- Result{Source: s.Name(), Error: err}
+				return
 			}
-			if r.HasData() {
-				results <- r
+			// Every search costs one credit; the response carries the balance left.
+			session.Quota.Consume(s.Name(), randomApiKey, 1)
+			session.Quota.SetRemaining(s.Name(), randomApiKey, response.Balance)
+			total = response.Total
+			lastPageFull = len(response.Entries) == dehashedPageSize
+
+			for _, entry := range response.Entries {
+				if fetched >= s.resultCap() {
+					break
+				}
+				fetched++
+				r := Result{
+					Source:   s.Name(),
+					Email:    entry.Email,
+					Username: entry.Username,
+					Password: entry.Password,
+					Hash:     entry.HashedPassword,
+					Name:     entry.Name,
+					Phone:    entry.Phone,
+					IP:       entry.IPAddress,
+					Database: entry.DatabaseName,
+				}
+				if r.HasData() {
+					results <- r
+				}
 			}
+
+			if !lastPageFull || (total > 0 && fetched >= total) {
+				break
+			}
+			providerCapped = page*dehashedPageSize >= dehashedMaxResults
+		}
+		if providerCapped {
+			warnProviderCap(session.Log(), "DeHashed", target, dehashedMaxResults, total, fetched)
+		} else {
+			warnTruncated(session.Log(), "DeHashed", target, total, fetched, lastPageFull)
 		}
 	}()
 
 	return results
 }
 
+// search requests a single page of results.
+func (s *DeHashed) search(ctx context.Context, session *Session, apiKey, query string, page int) (dehashedSearchResponse, error) {
+	body, err := json.Marshal(dehashedSearchRequest{
+		Query:  query,
+		Page:   page,
+		Size:   dehashedPageSize,
+		DeDupe: true,
+	})
+	if err != nil {
+		return dehashedSearchResponse{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", s.apiBaseURL()+"/v2/search",
+		bytes.NewReader(body))
+	if err != nil {
+		return dehashedSearchResponse{}, err
+	}
+	req.Header.Set("Dehashed-Api-Key", apiKey)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := session.Client.Do(req)
+	if err != nil {
+		return dehashedSearchResponse{}, err
+	}
+	defer session.DiscardHTTPResponse(resp)
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return dehashedSearchResponse{}, err
+	}
+	session.Log().Debugf("Response from DeHashed source: status code [%d], size [%d]", resp.StatusCode, len(respBody))
+
+	if resp.StatusCode != http.StatusOK {
+		return dehashedSearchResponse{}, fmt.Errorf("DeHashed returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var response dehashedSearchResponse
+	if err := json.Unmarshal(respBody, &response); err != nil {
+		return dehashedSearchResponse{}, err
+	}
+	return response, nil
+}
+
+func (s *DeHashed) apiBaseURL() string {
+	if s.baseURL != "" {
+		return strings.TrimRight(s.baseURL, "/")
+	}
+	return dehashedDefaultBaseURL
+}
+
 func (s *DeHashed) Name() string {
 	return "dehashed"
 }
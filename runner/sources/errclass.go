@@ -0,0 +1,74 @@
+package sources
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ErrorClass groups source errors by cause for run summaries and error
+// reports.
+type ErrorClass string
+
+const (
+	ErrorAuth      ErrorClass = "auth"       // rejected or missing API key
+	ErrorRateLimit ErrorClass = "rate_limit" // rate limited or out of credits
+	ErrorTimeout   ErrorClass = "timeout"    // request or connection timed out
+	ErrorParse     ErrorClass = "parse"      // unexpected response body
+	ErrorBudget    ErrorClass = "budget"     // stopped by a per-source budget
+	ErrorOther     ErrorClass = "other"
+)
+
+// statusCodeRegex extracts the HTTP status from the "returned status N"
+// errors sources build from unexpected responses.
+var statusCodeRegex = regexp.MustCompile(`status (\d{3})\b`)
+
+// ClassifyError returns the class of an error reported by a source, or an
+// empty class for a nil error.
+func ClassifyError(err error) ErrorClass {
+	if err == nil {
+		return ""
+	}
+	if errors.Is(err, ErrBudgetExceeded) {
+		return ErrorBudget
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrorTimeout
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return ErrorTimeout
+	}
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+		return ErrorParse
+	}
+
+	msg := strings.ToLower(err.Error())
+	if m := statusCodeRegex.FindStringSubmatch(msg); m != nil {
+		code, _ := strconv.Atoi(m[1])
+		switch code {
+		case 401, 403:
+			return ErrorAuth
+		case 402, 429:
+			return ErrorRateLimit
+		}
+	}
+	switch {
+	case strings.Contains(msg, "rate limit"), strings.Contains(msg, "too many requests"):
+		return ErrorRateLimit
+	case strings.Contains(msg, "unauthorized"), strings.Contains(msg, "forbidden"),
+		strings.Contains(msg, "api key"), strings.Contains(msg, "api-key"):
+		return ErrorAuth
+	case strings.Contains(msg, "timeout"), strings.Contains(msg, "timed out"):
+		return ErrorTimeout
+	case strings.Contains(msg, "parse"), strings.Contains(msg, "unmarshal"), strings.Contains(msg, "invalid character"):
+		return ErrorParse
+	}
+	return ErrorOther
+}
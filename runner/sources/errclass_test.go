@@ -0,0 +1,33 @@
+package sources
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestClassifyError(t *testing.T) {
+	var syntaxErr error = json.Unmarshal([]byte("{"), &struct{}{})
+	tests := []struct {
+		err  error
+		want ErrorClass
+	}{
+		{nil, ""},
+		{errors.New("DeHashed returned status 401: unauthorized"), ErrorAuth},
+		{errors.New("HIBP returned status 403: "), ErrorAuth},
+		{errors.New("LeakRadar returned status 429: slow down"), ErrorRateLimit},
+		{errors.New("IntelX file read rate limited (402), stopping further reads"), ErrorRateLimit},
+		{fmt.Errorf("get: %w", context.DeadlineExceeded), ErrorTimeout},
+		{syntaxErr, ErrorParse},
+		{errors.New("failed to parse LeakCheck response: <html>"), ErrorParse},
+		{fmt.Errorf("%w: request limit of 3 reached", ErrBudgetExceeded), ErrorBudget},
+		{errors.New("Snusbase returned status 500: oops"), ErrorOther},
+	}
+	for _, tt := range tests {
+		if got := ClassifyError(tt.err); got != tt.want {
+			t.Errorf("ClassifyError(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
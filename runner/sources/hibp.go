@@ -0,0 +1,292 @@
+package sources
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vflame6/leaker/utils"
+)
+
+const hibpDefaultBaseURL = "https://haveibeenpwned.com/api/v3"
+
+const (
+	// hibpRequestInterval spaces requests to the 10 requests per minute
+	// of the smallest subscription.
+	hibpRequestInterval = 6 * time.Second
+	// hibpMaxRetries caps how often a request is retried after a 429.
+	hibpMaxRetries = 3
+	// hibpMaxRetryAfter caps how long a single Retry-After is honoured.
+	hibpMaxRetryAfter = 2 * time.Minute
+)
+
+// HIBP queries the Have I Been Pwned breached-account API. Unlike the
+// Pwned Passwords range API used by the Verifier, these endpoints require
+// a subscription key and return breach metadata rather than credentials.
+// Requests are spaced to the subscription's rate limit across targets,
+// and retried after the Retry-After of a 429.
+type HIBP struct {
+	apiKeys []string
+	baseURL string
+	// interval overrides hibpRequestInterval in tests.
+	interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time // earliest time of the next request
+}
+
+type hibpBreach struct {
+	Name         string   `json:"Name"`
+	Title        string   `json:"Title"`
+	Domain       string   `json:"Domain"`
+	BreachDate   string   `json:"BreachDate"`
+	PwnCount     int      `json:"PwnCount"`
+	DataClasses  []string `json:"DataClasses"`
+	IsVerified   bool     `json:"IsVerified"`
+	IsFabricated bool     `json:"IsFabricated"`
+	IsSensitive  bool     `json:"IsSensitive"`
+	IsSpamList   bool     `json:"IsSpamList"`
+}
+
+func (s *HIBP) Run(ctx context.Context, target string, scanType ScanType, session *Session) <-chan Result {
+	results := make(chan Result)
+
+	go func() {
+		defer close(results)
+
+		apiKey := utils.PickRandom(s.apiKeys, s.Name(), s.NeedsKey())
+		if apiKey == "" {
+			return
+		}
+
+		switch scanType {
+		case TypeEmail:
+			s.searchAccount(ctx, session, apiKey, target, results)
+		case TypeDomain:
+			s.searchDomain(ctx, session, apiKey, target, results)
+		default:
+			// The breached-account API only indexes email addresses and
+			// verified domains.
+			return
+		}
+	}()
+
+	return results
+}
+
+// searchAccount lists every breach the email address appears in.
+func (s *HIBP) searchAccount(ctx context.Context, session *Session, apiKey, target string, results chan<- Result) {
+	endpoint := fmt.Sprintf("%s/breachedaccount/%s?truncateResponse=false&includeUnverified=true",
+		s.apiBaseURL(), url.PathEscape(target))
+
+	session.Log().Debugf("Sending a request in HIBP source for %s", target)
+	body, found, err := s.get(ctx, session, apiKey, endpoint)
+	if err != nil {
+		results <- Result{Source: s.Name(), Error: err}
+		return
+	}
+	if !found {
+		return
+	}
+
+	var breaches []hibpBreach
+	if err := json.Unmarshal(body, &breaches); err != nil {
+		results <- Result{Source: s.Name(), Error: err}
+		return
+	}
+
+	for _, breach := range breaches {
+		results <- s.breachToResult(target, breach)
+	}
+}
+
+// searchDomain uses the domain search endpoint, which only answers for
+// domains verified on the subscriber's HIBP dashboard. The endpoint maps
+// email aliases to breach names, so the full breach catalogue is fetched
+// afterwards to attach the same metadata as account searches.
+func (s *HIBP) searchDomain(ctx context.Context, session *Session, apiKey, target string, results chan<- Result) {
+	endpoint := fmt.Sprintf("%s/breacheddomain/%s", s.apiBaseURL(), url.PathEscape(target))
+
+	session.Log().Debugf("Sending a request in HIBP source for %s", target)
+	body, found, err := s.get(ctx, session, apiKey, endpoint)
+	if err != nil {
+		results <- Result{Source: s.Name(), Error: err}
+		return
+	}
+	if !found {
+		return
+	}
+
+	var aliases map[string][]string
+	if err := json.Unmarshal(body, &aliases); err != nil {
+		results <- Result{Source: s.Name(), Error: err}
+		return
+	}
+	if len(aliases) == 0 {
+		return
+	}
+
+	catalogue := make(map[string]hibpBreach)
+	catalogueBody, _, err := s.get(ctx, session, apiKey, s.apiBaseURL()+"/breaches")
+	if err != nil {
+		// Breach names alone are still useful; emit them without metadata.
+		session.Log().Debugf("HIBP breach catalogue error: %v", err)
+	} else {
+		var breaches []hibpBreach
+		if err := json.Unmarshal(catalogueBody, &breaches); err != nil {
+			session.Log().Debugf("HIBP breach catalogue parse error: %v", err)
+		}
+		for _, breach := range breaches {
+			catalogue[breach.Name] = breach
+		}
+	}
+
+	for alias, names := range aliases {
+		email := alias + "@" + target
+		for _, name := range names {
+			breach, ok := catalogue[name]
+			if !ok {
+				breach = hibpBreach{Name: name}
+			}
+			results <- s.breachToResult(email, breach)
+		}
+	}
+}
+
+// get performs an authenticated GET request, retrying after a 429. A 404
+// is the API's way of saying "no breaches" and is reported as found=false
+// without an error.
+func (s *HIBP) get(ctx context.Context, session *Session, apiKey, endpoint string) ([]byte, bool, error) {
+	var delay time.Duration
+	for attempt := 0; ; attempt++ {
+		if err := s.wait(ctx, delay); err != nil {
+			return nil, false, err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+		if err != nil {
+			return nil, false, err
+		}
+		req.Header.Set("hibp-api-key", apiKey)
+		req.Header.Set("Accept", "application/json")
+
+		resp, err := session.Client.Do(req)
+		if err != nil {
+			return nil, false, err
+		}
+		body, err := io.ReadAll(resp.Body)
+		session.DiscardHTTPResponse(resp)
+		if err != nil {
+			return nil, false, err
+		}
+		session.Log().Debugf("Response from HIBP source: status code [%d], size [%d]", resp.StatusCode, len(body))
+
+		switch resp.StatusCode {
+		case http.StatusOK:
+			return body, true, nil
+		case http.StatusNotFound:
+			return nil, false, nil
+		case http.StatusTooManyRequests:
+			if attempt < hibpMaxRetries {
+				delay = hibpRetryAfter(resp.Header.Get("Retry-After"), attempt)
+				session.Log().Debugf("HIBP rate limit reached, retrying in %v", delay)
+				continue
+			}
+		}
+		return nil, false, fmt.Errorf("HIBP returned status %d: %s", resp.StatusCode, string(body))
+	}
+}
+
+// wait blocks until the next request may be sent, at least delay from
+// now, and reserves the following slot.
+func (s *HIBP) wait(ctx context.Context, delay time.Duration) error {
+	interval := s.interval
+	if interval == 0 {
+		interval = hibpRequestInterval
+	}
+	s.mu.Lock()
+	now := time.Now()
+	at := now.Add(delay)
+	if s.next.After(at) {
+		at = s.next
+	}
+	s.next = at.Add(interval)
+	s.mu.Unlock()
+
+	timer := time.NewTimer(at.Sub(now))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// hibpRetryAfter returns how long to wait before retrying a 429: the
+// Retry-After seconds or date when given, else an exponential backoff.
+func hibpRetryAfter(header string, attempt int) time.Duration {
+	delay := hibpRequestInterval << attempt
+	if seconds, err := strconv.Atoi(strings.TrimSpace(header)); err == nil && seconds >= 0 {
+		delay = time.Duration(seconds) * time.Second
+	} else if at, err := http.ParseTime(header); err == nil {
+		delay = time.Until(at)
+	}
+	return min(max(delay, 0), hibpMaxRetryAfter)
+}
+
+func (s *HIBP) breachToResult(email string, breach hibpBreach) Result {
+	r := Result{
+		Source:   s.Name(),
+		Email:    email,
+		Database: breach.Name,
+	}
+	if breach.BreachDate != "" {
+		r.SetExtra("breach_date", breach.BreachDate)
+	}
+	if len(breach.DataClasses) > 0 {
+		r.SetExtra("data_classes", strings.Join(breach.DataClasses, "; "))
+	}
+	// Flags are only known when the catalogue entry was resolved.
+	if breach.BreachDate != "" {
+		r.SetExtra("verified", strconv.FormatBool(breach.IsVerified))
+		r.SetExtra("fabricated", strconv.FormatBool(breach.IsFabricated))
+		r.SetExtra("sensitive", strconv.FormatBool(breach.IsSensitive))
+		r.SetExtra("spam_list", strconv.FormatBool(breach.IsSpamList))
+	}
+	return r
+}
+
+func (s *HIBP) apiBaseURL() string {
+	if s.baseURL != "" {
+		return strings.TrimRight(s.baseURL, "/")
+	}
+	return hibpDefaultBaseURL
+}
+
+func (s *HIBP) Name() string {
+	return "hibp"
+}
+
+func (s *HIBP) UsesKey() bool {
+	return true
+}
+
+func (s *HIBP) NeedsKey() bool {
+	return true
+}
+
+func (s *HIBP) AddApiKeys(keys []string) {
+	s.apiKeys = keys
+}
+
+func (s *HIBP) RateLimit() int {
+	// Requests are paced by the source itself, see wait.
+	return 1
+}
@@ -0,0 +1,190 @@
+package sources
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newTestSession(t *testing.T) *Session {
+	t.Helper()
+	session, err := NewSession(5*time.Second, "leaker-test", "", false)
+	if err != nil {
+		t.Fatalf("NewSession: %v", err)
+	}
+	t.Cleanup(session.Close)
+	return session
+}
+
+func collectResults(ch <-chan Result) []Result {
+	var out []Result
+	for r := range ch {
+		out = append(out, r)
+	}
+	return out
+}
+
+func TestHIBP_EmailBreaches(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("hibp-api-key") != "key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/breachedaccount/user@example.com" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`[{"Name":"Adobe","BreachDate":"2013-10-04","DataClasses":["Email addresses","Passwords"],"IsVerified":true}]`))
+	}))
+	defer srv.Close()
+
+	s := &HIBP{apiKeys: []string{"key"}, baseURL: srv.URL}
+	got := collectResults(s.Run(context.Background(), "user@example.com", TypeEmail, newTestSession(t)))
+	if len(got) != 1 {
+		t.Fatalf("expected 1 result, got %d: %+v", len(got), got)
+	}
+	r := got[0]
+	if r.Error != nil {
+		t.Fatalf("unexpected error: %v", r.Error)
+	}
+	if r.Email != "user@example.com" || r.Database != "Adobe" {
+		t.Errorf("unexpected result: %+v", r)
+	}
+	if r.Extra["breach_date"] != "2013-10-04" {
+		t.Errorf("breach_date = %q", r.Extra["breach_date"])
+	}
+	if r.Extra["data_classes"] != "Email addresses; Passwords" {
+		t.Errorf("data_classes = %q", r.Extra["data_classes"])
+	}
+	if r.Extra["verified"] != "true" || r.Extra["fabricated"] != "false" {
+		t.Errorf("unexpected flags: %+v", r.Extra)
+	}
+}
+
+func TestHIBP_NotFoundIsNotAnError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	s := &HIBP{apiKeys: []string{"key"}, baseURL: srv.URL}
+	got := collectResults(s.Run(context.Background(), "clean@example.com", TypeEmail, newTestSession(t)))
+	if len(got) != 0 {
+		t.Fatalf("expected no results for 404, got %+v", got)
+	}
+}
+
+func TestHIBP_DomainSearchResolvesCatalogue(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/breacheddomain/example.com":
+			_, _ = w.Write([]byte(`{"alice":["Adobe"],"bob":["Unknown"]}`))
+		case "/breaches":
+			_, _ = w.Write([]byte(`[{"Name":"Adobe","BreachDate":"2013-10-04","IsVerified":true}]`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	s := &HIBP{apiKeys: []string{"key"}, baseURL: srv.URL, interval: time.Millisecond}
+	got := collectResults(s.Run(context.Background(), "example.com", TypeDomain, newTestSession(t)))
+	if len(got) != 2 {
+		t.Fatalf("expected 2 results, got %d: %+v", len(got), got)
+	}
+	byEmail := make(map[string]Result)
+	for _, r := range got {
+		byEmail[r.Email] = r
+	}
+	if byEmail["alice@example.com"].Extra["breach_date"] != "2013-10-04" {
+		t.Errorf("expected catalogue metadata for alice, got %+v", byEmail["alice@example.com"])
+	}
+	bob := byEmail["bob@example.com"]
+	if bob.Database != "Unknown" || len(bob.Extra) != 0 {
+		t.Errorf("expected bare breach name for bob, got %+v", bob)
+	}
+}
+
+func TestHIBP_RetriesAfterRateLimit(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write([]byte(`[{"Name":"Adobe"}]`))
+	}))
+	defer srv.Close()
+
+	s := &HIBP{apiKeys: []string{"key"}, baseURL: srv.URL, interval: time.Millisecond}
+	got := collectResults(s.Run(context.Background(), "user@example.com", TypeEmail, newTestSession(t)))
+	if len(got) != 1 || got[0].Error != nil || got[0].Database != "Adobe" {
+		t.Fatalf("expected the retried request to succeed, got %+v", got)
+	}
+	if requests != 2 {
+		t.Errorf("expected 2 requests, got %d", requests)
+	}
+}
+
+func TestHIBP_GivesUpAfterRepeatedRateLimits(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	s := &HIBP{apiKeys: []string{"key"}, baseURL: srv.URL, interval: time.Millisecond}
+	got := collectResults(s.Run(context.Background(), "user@example.com", TypeEmail, newTestSession(t)))
+	if len(got) != 1 || got[0].Error == nil {
+		t.Fatalf("expected a rate limit error, got %+v", got)
+	}
+	if requests != hibpMaxRetries+1 {
+		t.Errorf("expected %d requests, got %d", hibpMaxRetries+1, requests)
+	}
+}
+
+func TestHIBP_SpacesRequests(t *testing.T) {
+	var times []time.Time
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		times = append(times, time.Now())
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	const interval = 50 * time.Millisecond
+	s := &HIBP{apiKeys: []string{"key"}, baseURL: srv.URL, interval: interval}
+	session := newTestSession(t)
+	for _, target := range []string{"a@example.com", "b@example.com", "c@example.com"} {
+		collectResults(s.Run(context.Background(), target, TypeEmail, session))
+	}
+	for i := 1; i < len(times); i++ {
+		if gap := times[i].Sub(times[i-1]); gap < interval-5*time.Millisecond {
+			t.Errorf("request %d came %v after the previous one, want at least %v", i, gap, interval)
+		}
+	}
+}
+
+func TestHIBPRetryAfter(t *testing.T) {
+	for _, tc := range []struct {
+		header  string
+		attempt int
+		want    time.Duration
+	}{
+		{"3", 0, 3 * time.Second},
+		{"", 0, hibpRequestInterval},
+		{"", 2, 4 * hibpRequestInterval},
+		{"garbage", 1, 2 * hibpRequestInterval},
+		{"100000", 0, hibpMaxRetryAfter},
+		{"Wed, 21 Oct 2015 07:28:00 GMT", 0, 0}, // in the past
+	} {
+		if got := hibpRetryAfter(tc.header, tc.attempt); got != tc.want {
+			t.Errorf("hibpRetryAfter(%q, %d) = %v, want %v", tc.header, tc.attempt, got, tc.want)
+		}
+	}
+}
@@ -7,7 +7,6 @@ import (
 	"io"
 	"net/http"
 
-	"github.com/vflame6/leaker/logger"
 	"github.com/vflame6/leaker/utils"
 )
 
@@ -29,6 +28,11 @@ func (s *HudsonRock) Run(ctx context.Context, target string, scanType ScanType,
 	go func() {
 		defer close(results)
 
+		// Stealer logs are not indexed by hash, IP, name or password.
+		if scanType.IsPivot() {
+			return
+		}
+
 		randomApiKey := utils.PickRandom(s.apiKeys, s.Name(), s.NeedsKey())
 
 		if randomApiKey == "" {
@@ -64,7 +68,7 @@ func (s *HudsonRock) runFree(ctx context.Context, target string, scanType ScanTy
 	}
 	req.Header.Set("Accept", "application/json")
 
-	logger.Debugf("Sending a request in HudsonRock (free) source for %s", target)
+	session.Log().Debugf("Sending a request in HudsonRock (free) source for %s", target)
 	resp, err := session.Client.Do(req)
 	if err != nil {
 		results <- Result{Source: s.Name(), Error: err}
@@ -77,7 +81,7 @@ func (s *HudsonRock) runFree(ctx context.Context, target string, scanType ScanTy
 		results <- Result{Source: s.Name(), Error: err}
 		return
 	}
-	logger.Debugf("Response from HudsonRock (free) source: status code [%d], size [%d]", resp.StatusCode, len(body))
+	session.Log().Debugf("Response from HudsonRock (free) source: status code [%d], size [%d]", resp.StatusCode, len(body))
 
 	if resp.StatusCode != http.StatusOK {
 		results <- Result{
@@ -127,7 +131,7 @@ func (s *HudsonRock) runPaid(ctx context.Context, target string, scanType ScanTy
 	req.Header.Set("api-key", apiKey)
 	req.Header.Set("Accept", "application/json")
 
-	logger.Debugf("Sending a request in HudsonRock (paid) source for %s", target)
+	session.Log().Debugf("Sending a request in HudsonRock (paid) source for %s", target)
 	resp, err := session.Client.Do(req)
 	if err != nil {
 		results <- Result{Source: s.Name(), Error: err}
@@ -140,7 +144,7 @@ func (s *HudsonRock) runPaid(ctx context.Context, target string, scanType ScanTy
 		results <- Result{Source: s.Name(), Error: err}
 		return
 	}
-	logger.Debugf("Response from HudsonRock (paid) source: status code [%d], size [%d]", resp.StatusCode, len(body))
+	session.Log().Debugf("Response from HudsonRock (paid) source: status code [%d], size [%d]", resp.StatusCode, len(body))
 
 	if resp.StatusCode != http.StatusOK {
 		results <- Result{
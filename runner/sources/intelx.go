@@ -18,6 +18,11 @@ import (
 
 type IntelX struct {
 	apiKeys []intelxKey
+
+	// Phonebook enables the phonebook expansion for domain scans: email
+	// addresses IntelX has seen for the domain are emitted as results
+	// before the regular leak search runs.
+	Phonebook bool
 }
 
 // intelxKey holds a parsed HOST:API_KEY pair.
@@ -63,6 +68,32 @@ type intelxResultResponse struct {
 	Status  int                  `json:"status"` // 0=results(continue), 1=no more, 2=not found, 3=keep trying
 }
 
+// intelxPhonebookRequest is the request body for POST /phonebook/search
+type intelxPhonebookRequest struct {
+	Term       string `json:"term"`
+	MaxResults int    `json:"maxresults"`
+	Media      int    `json:"media"`
+	Target     int    `json:"target"` // 1 = domains, 2 = emails, 3 = URLs
+	Timeout    int    `json:"timeout"`
+}
+
+type intelxSelector struct {
+	Value string `json:"selectorvalue"`
+	Type  int    `json:"selectortype"` // 1 = email address
+}
+
+type intelxPhonebookResponse struct {
+	Selectors []intelxSelector `json:"selectors"`
+	Status    int              `json:"status"` // same codes as intelxResultResponse
+}
+
+const (
+	intelxPhonebookTargetEmails = 2
+	intelxSelectorTypeEmail     = 1
+	intelxPhonebookMaxResults   = 1000
+	intelxPhonebookDatabase     = "IntelX Phonebook"
+)
+
 // maxFileReadSize is the maximum number of bytes to read from a single file.
 // This prevents downloading very large files.
 const maxFileReadSize = 10 * 1024 * 1024 // 10 MB
@@ -73,6 +104,12 @@ func (s *IntelX) Run(ctx context.Context, target string, scanType ScanType, sess
 	go func() {
 		defer close(results)
 
+		// Leak files are grepped for the target as an account identifier;
+		// field pivots would match arbitrary unrelated lines.
+		if scanType.IsPivot() {
+			return
+		}
+
 		key := utils.PickRandom(s.apiKeys, s.Name(), s.NeedsKey())
 		if key.apiKey == "" {
 			return
@@ -81,6 +118,16 @@ func (s *IntelX) Run(ctx context.Context, target string, scanType ScanType, sess
 		apiURL := fmt.Sprintf("https://%s/", key.host)
 		lowerTarget := strings.ToLower(target)
 
+		if s.Phonebook && scanType == TypeDomain {
+			emails, err := s.searchPhonebook(ctx, session, apiURL, randomApiKey, lowerTarget)
+			if err != nil {
+				results <- Result{Source: s.Name(), Error: err}
+			}
+			for _, email := range emails {
+				results <- Result{Source: s.Name(), Email: email, Database: intelxPhonebookDatabase}
+			}
+		}
+
 		// Start the search, restricted to leak/paste/darknet buckets only.
 		// Without bucket filtering, IntelX returns all indexed content including
 		// web pages that merely mention the domain, producing garbage results.
@@ -107,7 +154,7 @@ func (s *IntelX) Run(ctx context.Context, target string, scanType ScanType, sess
 		req.Header.Set("Content-Type", "application/json")
 		req.Header.Set("Accept", "application/json")
 
-		logger.Debugf("Sending search request in IntelX source for %s", target)
+		session.Log().Debugf("Sending search request in IntelX source for %s", target)
 		resp, err := session.Client.Do(req)
 		if err != nil {
 			results <- Result{Source: s.Name(), Error: err}
@@ -144,7 +191,7 @@ func (s *IntelX) Run(ctx context.Context, target string, scanType ScanType, sess
 		}
 
 		searchID := searchResp.ID
-		logger.Debugf("IntelX search started with ID %s", searchID)
+		session.Log().Debugf("IntelX search started with ID %s", searchID)
 
 		// Collect all records first, then fetch file contents
 		var allRecords []intelxResultRecord
@@ -158,7 +205,7 @@ func (s *IntelX) Run(ctx context.Context, target string, scanType ScanType, sess
 			case <-time.After(time.Second / time.Duration(s.RateLimit())):
 			}
 
-			logger.Debugf("Sending a request for IntelX poll attempt %d", attempt)
+			session.Log().Debugf("Sending a request for IntelX poll attempt %d", attempt)
 
 			resultReq, err := http.NewRequestWithContext(ctx, "GET",
 				fmt.Sprintf("%sintelligent/search/result?id=%s&limit=100", apiURL, searchID), nil)
@@ -188,7 +235,7 @@ func (s *IntelX) Run(ctx context.Context, target string, scanType ScanType, sess
 				return
 			}
 
-			logger.Debugf("IntelX poll attempt %d: status=%d, records=%d", attempt, resultData.Status, len(resultData.Records))
+			session.Log().Debugf("IntelX poll attempt %d: status=%d, records=%d", attempt, resultData.Status, len(resultData.Records))
 
 			allRecords = append(allRecords, resultData.Records...)
 
@@ -205,7 +252,7 @@ func (s *IntelX) Run(ctx context.Context, target string, scanType ScanType, sess
 		// Terminate search to free resources
 		s.terminateSearch(ctx, session, apiURL, randomApiKey, searchID)
 
-		logger.Debugf("IntelX found %d records, fetching file contents", len(allRecords))
+		session.Log().Debugf("IntelX found %d records, fetching file contents", len(allRecords))
 
 		// Sort records by access level (public first) so we prefer readable files
 		sort.Slice(allRecords, func(i, j int) bool {
@@ -229,13 +276,13 @@ func (s *IntelX) Run(ctx context.Context, target string, scanType ScanType, sess
 		var filteredRecords []intelxResultRecord
 		for _, record := range uniqueRecords {
 			if isIntelxWebBucket(record.Bucket) {
-				logger.Debugf("IntelX skipping record %s from web bucket %q", record.Name, record.Bucket)
+				session.Log().Debugf("IntelX skipping record %s from web bucket %q", record.Name, record.Bucket)
 				continue
 			}
 			filteredRecords = append(filteredRecords, record)
 		}
 
-		logger.Debugf("IntelX %d records after bucket filtering (was %d)", len(filteredRecords), len(uniqueRecords))
+		session.Log().Debugf("IntelX %d records after bucket filtering (was %d)", len(filteredRecords), len(uniqueRecords))
 
 		// For each filtered record, fetch file contents and extract matching lines.
 		// Stop fetching if we hit a rate limit (402) — the API budget is exhausted.
@@ -250,21 +297,36 @@ func (s *IntelX) Run(ctx context.Context, target string, scanType ScanType, sess
 			if rateLimited {
 				break
 			}
+			if err := session.Quota.Allow(s.Name()); err != nil {
+				results <- Result{Source: s.Name(), Error: err}
+				break
+			}
 
-			lines, status := s.fetchMatchingLines(ctx, session, apiURL, randomApiKey, record, lowerTarget)
+			sample, lines, status := s.fetchMatchingLines(ctx, session, apiURL, randomApiKey, record, lowerTarget)
+			if status == http.StatusOK {
+				// File reads are what the IntelX plan credits limit.
+				session.Quota.Consume(s.Name(), randomApiKey, 1)
+			}
 			if status == http.StatusPaymentRequired {
 				rateLimited = true
+				session.Quota.MarkExhausted(s.Name(), randomApiKey)
 				results <- Result{
 					Source: s.Name(),
 					Error:  fmt.Errorf("IntelX file read rate limited (%d), stopping further reads", status),
 				}
 				continue
 			}
+			if len(lines) == 0 {
+				continue
+			}
+			parser := newIntelxParser(sample)
+			session.Log().Debugf("IntelX file %s detected as %s", record.Name, parser.format)
 			for _, line := range lines {
-				r := parseIntelxLine(line, lowerTarget)
-				r.Source = s.Name()
-				if r.HasData() {
-					results <- r
+				for _, r := range parser.parse(line, lowerTarget) {
+					r.Source = s.Name()
+					if r.HasData() {
+						results <- r
+					}
 				}
 			}
 		}
@@ -273,8 +335,8 @@ func (s *IntelX) Run(ctx context.Context, target string, scanType ScanType, sess
 	return results
 }
 
-// parseIntelxLine parses a raw leak line from IntelX files.
-// Lines come in various formats:
+// parseIntelxLine parses a raw leak line from IntelX files whose format
+// could not be detected. It guesses per line between the common formats:
 //   - email:password (combo list)
 //   - email;password
 //   - CSV: id,,email,hash,name (database dumps)
@@ -290,28 +352,7 @@ func parseIntelxLine(line, target string) Result {
 
 	// Try CSV format first (comma-separated with potential empty fields)
 	if strings.Count(line, ",") >= 2 {
-		parts := strings.Split(line, ",")
-		// Find which part contains the target email
-		emailIdx := -1
-		for i, p := range parts {
-			if strings.Contains(strings.ToLower(p), target) && strings.Contains(p, "@") {
-				emailIdx = i
-				break
-			}
-		}
-		if emailIdx >= 0 {
-			r.Email = strings.TrimSpace(parts[emailIdx])
-			// Assign remaining non-empty fields heuristically
-			for i, p := range parts {
-				if i == emailIdx {
-					continue
-				}
-				p = strings.TrimSpace(p)
-				if p == "" || p == "''" {
-					continue
-				}
-				assignIntelxField(&r, p)
-			}
+		if r, ok := assignIntelxFields(strings.Split(line, ","), target); ok {
 			return r
 		}
 	}
@@ -354,6 +395,35 @@ func parseIntelxLine(line, target string) Result {
 	return r
 }
 
+// assignIntelxFields maps the fields of a row around the field holding the
+// target's email; the other non-empty fields are assigned heuristically.
+// It reports false when no field holds such an email.
+func assignIntelxFields(parts []string, target string) (Result, bool) {
+	var r Result
+	emailIdx := -1
+	for i, p := range parts {
+		if strings.Contains(strings.ToLower(p), target) && strings.Contains(p, "@") {
+			emailIdx = i
+			break
+		}
+	}
+	if emailIdx < 0 {
+		return r, false
+	}
+	r.Email = strings.TrimSpace(parts[emailIdx])
+	for i, p := range parts {
+		if i == emailIdx {
+			continue
+		}
+		p = strings.TrimSpace(p)
+		if p == "" || p == "''" {
+			continue
+		}
+		assignIntelxField(&r, p)
+	}
+	return r, true
+}
+
 // assignIntelxField heuristically assigns a CSV field value to a Result.
 func assignIntelxField(r *Result, val string) {
 	if looksLikeHash(val) {
@@ -411,18 +481,21 @@ func looksLikeHash(s string) bool {
 	return false
 }
 
-// fetchMatchingLines reads a file from IntelX and returns lines containing the target.
+// fetchMatchingLines reads a file from IntelX and returns lines containing the target,
+// along with a sample of the file's first lines for format detection. The sample
+// also carries the file's first INSERT statement (truncated) and its CREATE TABLE
+// statements so SQL dumps with a long preamble still expose their column names.
 // For redacted files (free tier), the content will be masked and won't match the target,
 // which is the correct behavior — downstream filtering handles this naturally.
-func (s *IntelX) fetchMatchingLines(ctx context.Context, session *Session, apiURL, apiKey string, record intelxResultRecord, target string) ([]string, int) {
+func (s *IntelX) fetchMatchingLines(ctx context.Context, session *Session, apiURL, apiKey string, record intelxResultRecord, target string) ([]string, []string, int) {
 	readURL := fmt.Sprintf("%sfile/read?type=%d&limit=0", apiURL, record.Type)
 
-	logger.Debugf("Sending a request in IntelX source for file %s", record.Name)
+	session.Log().Debugf("Sending a request in IntelX source for file %s", record.Name)
 
 	req, err := http.NewRequestWithContext(ctx, "GET", readURL, nil)
 	if err != nil {
-		logger.Debugf("IntelX file read request error for %s: %v", record.Name, err)
-		return nil, 0
+		session.Log().Debugf("IntelX file read request error for %s: %v", record.Name, err)
+		return nil, nil, 0
 	}
 	// Set query params via url.Values to ensure proper encoding
 	q := req.URL.Query()
@@ -433,14 +506,14 @@ func (s *IntelX) fetchMatchingLines(ctx context.Context, session *Session, apiUR
 
 	resp, err := session.Client.Do(req)
 	if err != nil {
-		logger.Debugf("IntelX file read error for %s: %v", record.Name, err)
-		return nil, 0
+		session.Log().Debugf("IntelX file read error for %s: %v", record.Name, err)
+		return nil, nil, 0
 	}
 	defer session.DiscardHTTPResponse(resp)
 
 	if resp.StatusCode != http.StatusOK {
-		logger.Debugf("IntelX file read returned status %d for %s", resp.StatusCode, record.Name)
-		return nil, resp.StatusCode
+		session.Log().Debugf("IntelX file read returned status %d for %s", resp.StatusCode, record.Name)
+		return nil, nil, resp.StatusCode
 	}
 
 	// Read the response body with a size limit
@@ -449,19 +522,66 @@ func (s *IntelX) fetchMatchingLines(ctx context.Context, session *Session, apiUR
 	// Increase scanner buffer for potentially long lines
 	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
 
+	var sampler intelxSampler
 	var matches []string
 	for scanner.Scan() {
 		line := scanner.Text()
+		sampler.add(line)
 		if strings.Contains(strings.ToLower(line), target) {
 			matches = append(matches, line)
 		}
 	}
 
 	if len(matches) > 0 {
-		logger.Debugf("IntelX file %s: found %d matching lines", record.Name, len(matches))
+		session.Log().Debugf("IntelX file %s: found %d matching lines", record.Name, len(matches))
 	}
 
-	return matches, http.StatusOK
+	return sampler.lines, matches, http.StatusOK
+}
+
+// intelxSampler collects the sample of a file used for format detection:
+// its first non-blank lines, its first INSERT statement and the lines of
+// its CREATE TABLE statements.
+type intelxSampler struct {
+	lines      []string
+	sawInsert  bool
+	inCreate   bool
+	tableLines int
+}
+
+func (s *intelxSampler) add(line string) {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" {
+		return
+	}
+	isInsert := hasFoldPrefix(trimmed, "INSERT INTO")
+	if hasFoldPrefix(trimmed, "CREATE TABLE") {
+		s.inCreate = true
+	}
+	inCreate := s.inCreate && s.tableLines < intelxMaxTableLines
+	if len(s.lines) < intelxSampleLines || (isInsert && !s.sawInsert) || inCreate {
+		s.lines = append(s.lines, truncateIntelxSample(trimmed))
+	}
+	if s.inCreate {
+		s.tableLines++
+		s.inCreate = !strings.HasSuffix(trimmed, ";")
+	}
+	s.sawInsert = s.sawInsert || isInsert
+}
+
+// hasFoldPrefix reports whether s begins with prefix, ignoring case.
+func hasFoldPrefix(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
+
+// truncateIntelxSample bounds sampled lines; multi-row INSERT statements
+// can span megabytes on a single line.
+func truncateIntelxSample(line string) string {
+	const maxSampleLineLength = 4096
+	if len(line) > maxSampleLineLength {
+		return line[:maxSampleLineLength]
+	}
+	return line
 }
 
 // isIntelxWebBucket returns true if the bucket contains web-crawled content
@@ -473,6 +593,108 @@ func isIntelxWebBucket(bucket string) bool {
 		strings.HasPrefix(bucket, "whois.")
 }
 
+// searchPhonebook runs a phonebook search for email selectors on the domain
+// and returns the distinct addresses at the domain or its subdomains.
+func (s *IntelX) searchPhonebook(ctx context.Context, session *Session, apiURL, apiKey, domain string) ([]string, error) {
+	body, err := json.Marshal(intelxPhonebookRequest{
+		Term:       domain,
+		MaxResults: intelxPhonebookMaxResults,
+		Target:     intelxPhonebookTargetEmails,
+		Timeout:    20,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", apiURL+"phonebook/search", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("x-key", apiKey)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	session.Log().Debugf("Sending phonebook request in IntelX source for %s", domain)
+	resp, err := session.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	session.DiscardHTTPResponse(resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("IntelX phonebook search returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var searchResp intelxSearchResponse
+	if err := json.Unmarshal(respBody, &searchResp); err != nil {
+		return nil, err
+	}
+	if searchResp.Status != 0 {
+		return nil, fmt.Errorf("IntelX phonebook search failed with status %d", searchResp.Status)
+	}
+	defer s.terminateSearch(ctx, session, apiURL, apiKey, searchResp.ID)
+
+	seen := make(map[string]struct{})
+	var emails []string
+	for attempt := 0; attempt < 10; attempt++ {
+		select {
+		case <-ctx.Done():
+			return emails, nil
+		case <-time.After(time.Second / time.Duration(s.RateLimit())):
+		}
+
+		resultReq, err := http.NewRequestWithContext(ctx, "GET",
+			fmt.Sprintf("%sphonebook/search/result?id=%s&limit=%d", apiURL, searchResp.ID, intelxPhonebookMaxResults), nil)
+		if err != nil {
+			return emails, err
+		}
+		resultReq.Header.Set("x-key", apiKey)
+		resultReq.Header.Set("Accept", "application/json")
+
+		resultResp, err := session.Client.Do(resultReq)
+		if err != nil {
+			return emails, err
+		}
+		resultBody, err := io.ReadAll(resultResp.Body)
+		session.DiscardHTTPResponse(resultResp)
+		if err != nil {
+			return emails, err
+		}
+
+		var resultData intelxPhonebookResponse
+		if err := json.Unmarshal(resultBody, &resultData); err != nil {
+			return emails, err
+		}
+		session.Log().Debugf("IntelX phonebook poll attempt %d: status=%d, selectors=%d", attempt, resultData.Status, len(resultData.Selectors))
+
+		for _, selector := range resultData.Selectors {
+			if selector.Type != intelxSelectorTypeEmail {
+				continue
+			}
+			email := strings.ToLower(strings.TrimSpace(selector.Value))
+			if !utils.EmailInDomain(email, domain) {
+				continue
+			}
+			if _, ok := seen[email]; ok {
+				continue
+			}
+			seen[email] = struct{}{}
+			emails = append(emails, email)
+		}
+
+		// Status: 0=continue, 1=done, 2=not found, 3=keep trying
+		if resultData.Status == 1 || resultData.Status == 2 {
+			break
+		}
+	}
+
+	session.Log().Debugf("IntelX phonebook found %d email addresses for %s", len(emails), domain)
+	return emails, nil
+}
+
 func (s *IntelX) terminateSearch(ctx context.Context, session *Session, apiURL, apiKey, searchID string) {
 	terminateCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -504,13 +726,21 @@ func (s *IntelX) NeedsKey() bool {
 	return true
 }
 
+// CheckApiKey reports whether key has the HOST:API_KEY format.
+func (s *IntelX) CheckApiKey(key string) error {
+	if !strings.Contains(key, ":") {
+		return fmt.Errorf("IntelX: invalid key format %q — expected HOST:API_KEY (e.g. 2.intelx.io:your-uuid-key)", key)
+	}
+	return nil
+}
+
 func (s *IntelX) AddApiKeys(keys []string) {
 	for _, key := range keys {
-		idx := strings.Index(key, ":")
-		if idx < 0 {
-			logger.Warnf("IntelX: invalid key format %q — expected HOST:API_KEY (e.g. 2.intelx.io:your-uuid-key)", key)
+		if err := s.CheckApiKey(key); err != nil {
+			logger.Warn(err)
 			continue
 		}
+		idx := strings.Index(key, ":")
 		s.apiKeys = append(s.apiKeys, intelxKey{
 			host:   key[:idx],
 			apiKey: key[idx+1:],
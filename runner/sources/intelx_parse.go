@@ -0,0 +1,657 @@
+package sources
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// intelxSampleLines is the number of leading non-blank lines of a file used
+// to detect its format.
+const intelxSampleLines = 25
+
+// intelxMaxTableLines bounds the CREATE TABLE lines sampled from a file.
+const intelxMaxTableLines = 500
+
+// intelxFormat is the layout of a leak file, detected once per file.
+type intelxFormat int
+
+const (
+	intelxFormatUnknown   intelxFormat = iota
+	intelxFormatSQL                    // INSERT INTO ... VALUES (...) dumps
+	intelxFormatCSV                    // delimited rows with a header line
+	intelxFormatJSONLines              // one JSON object per line
+	intelxFormatStealer                // url:user:pass stealer logs
+	intelxFormatCombo                  // email:pass combo lists
+)
+
+func (f intelxFormat) String() string {
+	switch f {
+	case intelxFormatSQL:
+		return "sql"
+	case intelxFormatCSV:
+		return "csv"
+	case intelxFormatJSONLines:
+		return "jsonl"
+	case intelxFormatStealer:
+		return "stealer"
+	case intelxFormatCombo:
+		return "combo"
+	default:
+		return "unknown"
+	}
+}
+
+var intelxComboLine = regexp.MustCompile(`^[^\s:;@]+@[^\s:;@]+\.[^\s:;@]+[:;]\S`)
+
+// intelxParser parses the matching lines of a single file using the format
+// detected from the file's first lines.
+type intelxParser struct {
+	format  intelxFormat
+	columns []string // CSV header or SQL INSERT column list
+	comma   rune     // CSV delimiter
+	// tables are the column names of the SQL CREATE TABLE statements,
+	// by lower-case table name.
+	tables map[string][]string
+}
+
+// newIntelxParser detects the file format from a sample of its first
+// non-blank lines. For SQL dumps the sample should also carry the first
+// INSERT statement and the CREATE TABLE statements, so rows of INSERTs
+// without a column list, as mysqldump writes them, still get their
+// column names.
+func newIntelxParser(sample []string) *intelxParser {
+	p := &intelxParser{}
+	if len(sample) == 0 {
+		return p
+	}
+
+	for _, line := range sample {
+		upper := strings.ToUpper(strings.TrimSpace(line))
+		if strings.HasPrefix(upper, "INSERT INTO") || strings.HasPrefix(upper, "CREATE TABLE") {
+			p.format = intelxFormatSQL
+			break
+		}
+	}
+	if p.format == intelxFormatSQL {
+		p.tables = sqlCreateTables(sample)
+		for _, line := range sample {
+			if columns := p.sqlColumns(line); len(columns) > 0 {
+				p.columns = columns
+				break
+			}
+		}
+		if p.columns == nil && len(p.tables) == 1 {
+			for _, columns := range p.tables {
+				p.columns = columns
+			}
+		}
+		return p
+	}
+
+	switch {
+	case mostLines(sample, isJSONObjectLine):
+		p.format = intelxFormatJSONLines
+	case p.detectCSVHeader(sample[0]):
+		p.format = intelxFormatCSV
+	case mostLines(sample, isStealerLine):
+		p.format = intelxFormatStealer
+	case mostLines(sample, intelxComboLine.MatchString):
+		p.format = intelxFormatCombo
+	}
+	return p
+}
+
+// parse converts a matching line into results. Lines the detected format
+// cannot handle fall back to the generic parseIntelxLine heuristics.
+func (p *intelxParser) parse(line, target string) []Result {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return nil
+	}
+
+	switch p.format {
+	case intelxFormatSQL:
+		columns := p.columns
+		if insert := p.sqlColumns(line); len(insert) > 0 {
+			columns = insert
+		}
+		var out []Result
+		for _, values := range sqlTuples(line) {
+			if !strings.Contains(strings.ToLower(strings.Join(values, " ")), target) {
+				continue
+			}
+			if len(columns) == 0 {
+				out = append(out, unnamedRecordToResult(values, target))
+				continue
+			}
+			out = append(out, recordToResult(columns, values))
+		}
+		return out
+	case intelxFormatCSV:
+		if values, ok := p.csvFields(line); ok {
+			return []Result{recordToResult(p.columns, values)}
+		}
+	case intelxFormatJSONLines:
+		if r, ok := parseJSONLine(line); ok {
+			return []Result{r}
+		}
+	case intelxFormatStealer:
+		if r, ok := parseStealerLine(line); ok {
+			return []Result{r}
+		}
+	case intelxFormatCombo:
+		if r, ok := parseComboLine(line); ok {
+			return []Result{r}
+		}
+	}
+	return []Result{parseIntelxLine(line, target)}
+}
+
+// mostLines reports whether at least half of the lines satisfy match.
+func mostLines(lines []string, match func(string) bool) bool {
+	n := 0
+	for _, line := range lines {
+		if match(strings.TrimSpace(line)) {
+			n++
+		}
+	}
+	return n > 0 && n*2 >= len(lines)
+}
+
+// detectCSVHeader picks the most frequent delimiter in the first line and
+// accepts it as a header when at least one column is a known leak field
+// and no column looks like data.
+func (p *intelxParser) detectCSVHeader(first string) bool {
+	best, bestCount := ',', 0
+	for _, c := range []rune{',', ';', '\t', '|'} {
+		if n := strings.Count(first, string(c)); n > bestCount {
+			best, bestCount = c, n
+		}
+	}
+	if bestCount == 0 {
+		return false
+	}
+	p.comma = best
+
+	columns, ok := p.csvFields(first)
+	if !ok {
+		return false
+	}
+	known := 0
+	for _, column := range columns {
+		if strings.Contains(column, "@") {
+			return false
+		}
+		if intelxColumnField(column) != "" {
+			known++
+		}
+	}
+	if known == 0 {
+		return false
+	}
+	p.columns = columns
+	return true
+}
+
+func (p *intelxParser) csvFields(line string) ([]string, bool) {
+	reader := csv.NewReader(strings.NewReader(line))
+	reader.Comma = p.comma
+	reader.LazyQuotes = true
+	reader.FieldsPerRecord = -1
+	fields, err := reader.Read()
+	if err != nil || len(fields) < 2 {
+		return nil, false
+	}
+	return fields, true
+}
+
+// intelxColumnField maps a column or JSON key name to the Result field it
+// holds, or "" for columns that are not extracted.
+func intelxColumnField(column string) string {
+	key := strings.ToLower(strings.TrimSpace(column))
+	key = strings.NewReplacer("_", "", "-", "", " ", "", "`", "", "\"", "", "'", "").Replace(key)
+	switch key {
+	case "email", "mail", "emailaddress", "useremail", "emailaddr":
+		return "email"
+	case "username", "user", "login", "nickname", "nick", "uname", "account", "userlogin", "screenname":
+		return "username"
+	case "password", "pass", "passwd", "pwd", "plaintext", "plainpassword", "clearpassword", "userpassword":
+		return "password"
+	case "hash", "passwordhash", "hashedpassword", "passhash", "pwdhash", "md5", "sha1", "sha256", "bcrypt", "crypt":
+		return "hash"
+	case "salt", "passwordsalt":
+		return "salt"
+	case "ip", "ipaddress", "lastip", "regip", "registrationip", "loginip", "userip", "ipaddr":
+		return "ip"
+	case "phone", "phonenumber", "mobile", "tel", "telephone", "cellphone", "msisdn":
+		return "phone"
+	case "name", "fullname", "displayname", "realname":
+		return "name"
+	case "firstname", "fname", "givenname":
+		return "first_name"
+	case "lastname", "lname", "surname", "familyname":
+		return "last_name"
+	case "url", "site", "website", "link":
+		return "url"
+	}
+	return ""
+}
+
+// unnamedRecordToResult maps a row whose column names are unknown with the
+// heuristics of parseIntelxLine: the target's email anchors the row and
+// the other values are assigned by shape. Rows without such an email keep
+// only values that are unambiguous on their own (emails, hashes, IPs).
+func unnamedRecordToResult(values []string, target string) Result {
+	if r, ok := assignIntelxFields(values, target); ok {
+		return r
+	}
+	return recordToResult(nil, values)
+}
+
+// recordToResult maps a row to a Result using its column names. Values in
+// unknown columns are dropped; without column names only values that are
+// unambiguous on their own (emails, hashes, IPs) are kept.
+func recordToResult(columns, values []string) Result {
+	var r Result
+	var firstName, lastName string
+	set := func(field *string, v string) {
+		if *field == "" {
+			*field = v
+		}
+	}
+
+	for i, v := range values {
+		v = strings.TrimSpace(v)
+		if v == "" || v == "''" {
+			continue
+		}
+		if len(columns) == 0 {
+			assignIntelxValue(&r, v)
+			continue
+		}
+		if i >= len(columns) {
+			break
+		}
+		switch intelxColumnField(columns[i]) {
+		case "email":
+			set(&r.Email, v)
+		case "username":
+			if strings.Contains(v, "@") {
+				set(&r.Email, v)
+			} else {
+				set(&r.Username, v)
+			}
+		case "password":
+			if looksLikeHash(v) {
+				set(&r.Hash, v)
+			} else {
+				set(&r.Password, v)
+			}
+		case "hash":
+			set(&r.Hash, v)
+		case "salt":
+			set(&r.Salt, v)
+		case "ip":
+			set(&r.IP, v)
+		case "phone":
+			set(&r.Phone, v)
+		case "name":
+			set(&r.Name, v)
+		case "first_name":
+			set(&firstName, v)
+		case "last_name":
+			set(&lastName, v)
+		case "url":
+			set(&r.URL, v)
+		}
+	}
+	if r.Name == "" {
+		r.Name = strings.TrimSpace(firstName + " " + lastName)
+	}
+	return r
+}
+
+// assignIntelxValue assigns a value from an unnamed column only when its
+// shape identifies the field.
+func assignIntelxValue(r *Result, v string) {
+	switch {
+	case r.Email == "" && strings.Contains(v, "@") && !strings.ContainsAny(v, " \t"):
+		r.Email = v
+	case r.Hash == "" && looksLikeHash(v):
+		r.Hash = v
+	case r.IP == "" && net.ParseIP(v) != nil:
+		r.IP = v
+	}
+}
+
+// sqlColumns returns the column names of the rows of an INSERT statement:
+// its explicit column list, or the columns of its table's CREATE TABLE.
+func (p *intelxParser) sqlColumns(line string) []string {
+	if columns := sqlInsertColumns(line); len(columns) > 0 {
+		return columns
+	}
+	trimmed := strings.TrimSpace(line)
+	if !hasFoldPrefix(trimmed, "INSERT INTO") {
+		return nil
+	}
+	return p.tables[sqlTableName(trimmed[len("INSERT INTO"):])]
+}
+
+// sqlTableName returns the lower-case table name at the start of s,
+// without quotes or a schema prefix.
+func sqlTableName(s string) string {
+	s = strings.TrimSpace(s)
+	end := strings.IndexAny(s, " \t(")
+	if end < 0 {
+		end = len(s)
+	}
+	name := s[:end]
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	return strings.ToLower(strings.Trim(name, "`\"[]"))
+}
+
+// sqlCreateTables returns the column names of every CREATE TABLE statement
+// in the sample, by lower-case table name. Statements may span lines.
+func sqlCreateTables(sample []string) map[string][]string {
+	text := strings.Join(sample, "\n")
+	upper := strings.ToUpper(text)
+	tables := make(map[string][]string)
+	for offset := 0; ; {
+		i := strings.Index(upper[offset:], "CREATE TABLE")
+		if i < 0 {
+			return tables
+		}
+		start := offset + i + len("CREATE TABLE")
+		offset = start
+		head := strings.TrimSpace(text[start:])
+		if strings.HasPrefix(strings.ToUpper(head), "IF NOT EXISTS") {
+			head = head[len("IF NOT EXISTS"):]
+		}
+		name := sqlTableName(head)
+		open := strings.Index(text[start:], "(")
+		if name == "" || open < 0 {
+			continue
+		}
+		body, ok := sqlParenthesized(text[start+open:])
+		if !ok {
+			continue
+		}
+		var columns []string
+		for _, definition := range splitSQLDefinitions(body) {
+			fields := strings.Fields(definition)
+			if len(fields) == 0 {
+				continue
+			}
+			switch strings.ToUpper(fields[0]) {
+			case "PRIMARY", "KEY", "UNIQUE", "INDEX", "CONSTRAINT", "FOREIGN", "FULLTEXT", "SPATIAL", "CHECK":
+				continue
+			}
+			columns = append(columns, strings.Trim(fields[0], "`\"[]"))
+		}
+		if len(columns) > 0 {
+			tables[name] = columns
+		}
+	}
+}
+
+// sqlParenthesized returns the text inside the parenthesis s starts with,
+// up to its matching closing parenthesis.
+func sqlParenthesized(s string) (string, bool) {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return s[1:i], true
+			}
+		}
+	}
+	return "", false
+}
+
+// splitSQLDefinitions splits a CREATE TABLE body at the commas between its
+// column and key definitions, keeping those inside types like
+// decimal(10,2) or quoted defaults.
+func splitSQLDefinitions(body string) []string {
+	var definitions []string
+	depth, start := 0, 0
+	var quote byte
+	for i := 0; i < len(body); i++ {
+		c := body[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			definitions = append(definitions, body[start:i])
+			start = i + 1
+		}
+	}
+	return append(definitions, body[start:])
+}
+
+// sqlInsertColumns returns the explicit column list of an INSERT statement,
+// or nil when the statement has none.
+func sqlInsertColumns(line string) []string {
+	upper := strings.ToUpper(line)
+	if !strings.HasPrefix(strings.TrimSpace(upper), "INSERT INTO") {
+		return nil
+	}
+	values := strings.Index(upper, "VALUES")
+	if values < 0 {
+		return nil
+	}
+	head := line[:values]
+	open, closing := strings.Index(head, "("), strings.LastIndex(head, ")")
+	if open < 0 || closing < open {
+		return nil
+	}
+	var columns []string
+	for _, column := range strings.Split(head[open+1:closing], ",") {
+		columns = append(columns, strings.Trim(column, " \t`\"[]"))
+	}
+	return columns
+}
+
+// sqlTuples extracts the value tuples from an INSERT statement, or from a
+// continuation line of a multi-line INSERT. Quoted values are unescaped and
+// unquoted NULLs become empty strings.
+func sqlTuples(line string) [][]string {
+	if i := strings.Index(strings.ToUpper(line), "VALUES"); i >= 0 {
+		line = line[i+len("VALUES"):]
+	}
+
+	var tuples [][]string
+	var tuple []string
+	var cur strings.Builder
+	depth := 0
+	inQuote, quoted := false, false
+	var quote byte
+
+	flush := func() {
+		v := cur.String()
+		if !quoted {
+			v = strings.TrimSpace(v)
+			if strings.EqualFold(v, "NULL") {
+				v = ""
+			}
+		}
+		tuple = append(tuple, v)
+		cur.Reset()
+		quoted = false
+	}
+
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		if inQuote {
+			switch {
+			case c == '\\' && i+1 < len(line):
+				i++
+				cur.WriteByte(sqlUnescape(line[i]))
+			case c == quote && i+1 < len(line) && line[i+1] == quote:
+				i++
+				cur.WriteByte(c)
+			case c == quote:
+				inQuote = false
+			default:
+				cur.WriteByte(c)
+			}
+			continue
+		}
+
+		switch {
+		case depth == 0:
+			if c == '(' {
+				depth = 1
+				tuple = nil
+				cur.Reset()
+				quoted = false
+			}
+		case depth == 1 && (c == '\'' || c == '"') && !quoted:
+			inQuote, quoted, quote = true, true, c
+			cur.Reset()
+		case depth == 1 && c == ',':
+			flush()
+		case depth == 1 && c == ')':
+			flush()
+			tuples = append(tuples, tuple)
+			depth = 0
+		case quoted:
+			// ignore anything between a closing quote and the next separator
+		default:
+			if c == '(' {
+				depth++
+			} else if c == ')' {
+				depth--
+			}
+			cur.WriteByte(c)
+		}
+	}
+	return tuples
+}
+
+func sqlUnescape(c byte) byte {
+	switch c {
+	case 'n':
+		return '\n'
+	case 't':
+		return '\t'
+	case 'r':
+		return '\r'
+	case '0':
+		return 0
+	}
+	return c
+}
+
+func isJSONObjectLine(line string) bool {
+	return strings.HasPrefix(line, "{") && json.Valid([]byte(line))
+}
+
+// parseJSONLine maps the top-level string and number values of a JSON
+// object by key name. Nested values are ignored.
+func parseJSONLine(line string) (Result, bool) {
+	decoder := json.NewDecoder(strings.NewReader(line))
+	decoder.UseNumber()
+	var object map[string]interface{}
+	if err := decoder.Decode(&object); err != nil {
+		return Result{}, false
+	}
+
+	keys := make([]string, 0, len(object))
+	for k := range object {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	values := make([]string, len(keys))
+	for i, k := range keys {
+		switch v := object[k].(type) {
+		case string:
+			values[i] = v
+		case json.Number:
+			values[i] = v.String()
+		case bool:
+			values[i] = strconv.FormatBool(v)
+		}
+	}
+	return recordToResult(keys, values), true
+}
+
+func isStealerLine(line string) bool {
+	i := strings.Index(line, "://")
+	return i > 0 && !strings.ContainsAny(line[:i], " \t:") && strings.Count(line[i+3:], ":")+strings.Count(line[i+3:], "|") >= 2
+}
+
+// parseStealerLine splits a url:user:pass line. The URL may contain colons
+// (scheme, port), so user and password are taken from the right. Lines
+// using "|" as the separator are accepted as well.
+func parseStealerLine(line string) (Result, bool) {
+	scheme := strings.Index(line, "://")
+	if scheme <= 0 {
+		return Result{}, false
+	}
+	sep := ":"
+	if strings.Count(line[scheme+3:], "|") >= 2 {
+		sep = "|"
+	}
+
+	i := strings.LastIndex(line, sep)
+	if i <= scheme+3 {
+		return Result{}, false
+	}
+	password := line[i+1:]
+	head := line[:i]
+	j := strings.LastIndex(head, sep)
+	if j <= scheme+3 {
+		return Result{}, false
+	}
+
+	r := Result{URL: strings.TrimSpace(head[:j]), Password: password}
+	user := strings.TrimSpace(head[j+1:])
+	if strings.Contains(user, "@") {
+		r.Email = user
+	} else {
+		r.Username = user
+	}
+	return r, true
+}
+
+// parseComboLine splits an email:pass or email;pass line at the first
+// separator, keeping any further separators as part of the password.
+func parseComboLine(line string) (Result, bool) {
+	i := strings.IndexAny(line, ":;")
+	if i <= 0 || !strings.Contains(line[:i], "@") {
+		return Result{}, false
+	}
+	return Result{Email: line[:i], Password: line[i+1:]}, true
+}
@@ -0,0 +1,186 @@
+package sources
+
+import "testing"
+
+func TestNewIntelxParser_DetectsFormat(t *testing.T) {
+	tests := []struct {
+		name   string
+		sample []string
+		want   intelxFormat
+	}{
+		{"sql", []string{"-- MySQL dump", "CREATE TABLE `users` (", "INSERT INTO `users` VALUES (1,'a@b.com')"}, intelxFormatSQL},
+		{"csv", []string{"id,email,password,name", "1,a@b.com,secret,Alice"}, intelxFormatCSV},
+		{"jsonl", []string{`{"email":"a@b.com","password":"x"}`, `{"email":"c@d.com"}`}, intelxFormatJSONLines},
+		{"stealer", []string{"https://site.com/login:alice:pw", "android://x@com.app/:bob:pw2"}, intelxFormatStealer},
+		{"combo", []string{"a@b.com:pw", "c@d.com;pw2", "garbage"}, intelxFormatCombo},
+		{"unknown", []string{"hello world", "nothing to see"}, intelxFormatUnknown},
+		{"empty", nil, intelxFormatUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newIntelxParser(tt.sample).format; got != tt.want {
+				t.Errorf("format = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIntelxParser_SQLInsert(t *testing.T) {
+	p := newIntelxParser([]string{
+		"CREATE TABLE `users` (",
+		"INSERT INTO `users` (`id`, `email`, `password_hash`, `first_name`, `last_name`, `reg_ip`) VALUES (1,'x@other.com','0',NULL,NULL,NULL)",
+	})
+	line := "INSERT INTO `users` (`id`, `email`, `password_hash`, `first_name`, `last_name`, `reg_ip`) VALUES " +
+		"(41,'bob@other.com','5f4dcc3b5aa765d61d8327deb882cf99','Bob','X','10.0.0.1')," +
+		"(42,'alice@example.com','$2a$10$abcdefghijklmnopqrstuv','Alice','O\\'Neil','192.168.1.2');"
+
+	got := p.parse(line, "alice@example.com")
+	if len(got) != 1 {
+		t.Fatalf("expected 1 result, got %d: %+v", len(got), got)
+	}
+	r := got[0]
+	if r.Email != "alice@example.com" || r.Hash != "$2a$10$abcdefghijklmnopqrstuv" ||
+		r.Name != "Alice O'Neil" || r.IP != "192.168.1.2" || r.Password != "" {
+		t.Errorf("unexpected result: %+v", r)
+	}
+}
+
+func TestIntelxParser_SQLContinuationWithoutColumns(t *testing.T) {
+	p := newIntelxParser([]string{"INSERT INTO users VALUES"})
+	got := p.parse("(7,'alice@example.com','Alice Smith','5f4dcc3b5aa765d61d8327deb882cf99'),", "alice@example.com")
+	if len(got) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(got))
+	}
+	r := got[0]
+	if r.Email != "alice@example.com" || r.Hash != "5f4dcc3b5aa765d61d8327deb882cf99" {
+		t.Errorf("unexpected result: %+v", r)
+	}
+	// without column names the text column is kept as parseIntelxLine would
+	if r.Name != "Alice Smith" {
+		t.Errorf("expected unnamed text column as the name, got Name %q", r.Name)
+	}
+}
+
+func TestIntelxParser_SQLCreateTableColumns(t *testing.T) {
+	// mysqldump writes INSERTs without a column list by default
+	p := newIntelxParser([]string{
+		"-- MySQL dump 10.13",
+		"CREATE TABLE IF NOT EXISTS `shop`.`users` (",
+		"  `id` int(11) NOT NULL AUTO_INCREMENT,",
+		"  `login` varchar(64) NOT NULL DEFAULT 'a,b',",
+		"  `email` varchar(255) NOT NULL,",
+		"  `password` varchar(64) DEFAULT NULL,",
+		"  `balance` decimal(10,2) DEFAULT NULL,",
+		"  `full_name` varchar(128) DEFAULT NULL,",
+		"  PRIMARY KEY (`id`),",
+		"  UNIQUE KEY `email` (`email`)",
+		") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;",
+		"CREATE TABLE `orders` (`id` int, `note` text, `ip` varchar(45));",
+		"INSERT INTO `users` VALUES (1,'bob','bob@other.com','x',0.00,'Bob')",
+	})
+	line := "INSERT INTO `users` VALUES (2,'alice','alice@example.com','hunter2',1.50,'Alice Smith');"
+	got := p.parse(line, "alice@example.com")
+	if len(got) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(got))
+	}
+	r := got[0]
+	if r.Username != "alice" || r.Email != "alice@example.com" || r.Password != "hunter2" || r.Name != "Alice Smith" {
+		t.Errorf("unexpected result: %+v", r)
+	}
+
+	// rows of another table are mapped by that table's columns
+	got = p.parse("INSERT INTO `orders` VALUES (5,'for alice@example.com','10.0.0.9');", "alice@example.com")
+	if len(got) != 1 || got[0].IP != "10.0.0.9" || got[0].Password != "" || got[0].Username != "" {
+		t.Errorf("unexpected orders result: %+v", got)
+	}
+}
+
+func TestIntelxSampler_CapturesCreateTable(t *testing.T) {
+	var s intelxSampler
+	for i := 0; i < intelxSampleLines; i++ {
+		s.add("-- preamble")
+	}
+	for _, line := range []string{
+		"CREATE TABLE `users` (",
+		"  `email` varchar(255),",
+		"  `password` varchar(64)",
+		");",
+		"SET NAMES utf8;",
+		"INSERT INTO `users` VALUES ('alice@example.com','hunter2');",
+		"INSERT INTO `users` VALUES ('bob@example.com','pw');",
+	} {
+		s.add(line)
+	}
+	if want := intelxSampleLines + 5; len(s.lines) != want {
+		t.Fatalf("expected %d sampled lines, got %d: %q", want, len(s.lines), s.lines)
+	}
+	p := newIntelxParser(s.lines)
+	got := p.parse("INSERT INTO `users` VALUES ('alice@example.com','hunter2');", "alice@example.com")
+	if len(got) != 1 || got[0].Password != "hunter2" {
+		t.Errorf("unexpected result: %+v", got)
+	}
+}
+
+func TestIntelxParser_CSVHeader(t *testing.T) {
+	p := newIntelxParser([]string{"user_id;login;Email;Password;Phone"})
+	got := p.parse(`1001;alice;alice@example.com;"p;ss";+15550100`, "alice@example.com")
+	if len(got) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(got))
+	}
+	r := got[0]
+	if r.Username != "alice" || r.Email != "alice@example.com" || r.Password != "p;ss" || r.Phone != "+15550100" || r.Name != "" {
+		t.Errorf("unexpected result: %+v", r)
+	}
+}
+
+func TestIntelxParser_JSONLines(t *testing.T) {
+	p := newIntelxParser([]string{`{"email":"a@b.com"}`})
+	got := p.parse(`{"id":12,"username":"alice@example.com","password":"hunter2","phone":15550100,"meta":{"x":1}}`, "alice@example.com")
+	if len(got) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(got))
+	}
+	r := got[0]
+	if r.Email != "alice@example.com" || r.Password != "hunter2" || r.Phone != "15550100" || r.Username != "" {
+		t.Errorf("unexpected result: %+v", r)
+	}
+}
+
+func TestIntelxParser_Stealer(t *testing.T) {
+	p := newIntelxParser([]string{"https://a.com:a:b"})
+	tests := []struct {
+		line string
+		want Result
+	}{
+		{"https://accounts.example.com:8443/login:alice@example.com:pw", Result{URL: "https://accounts.example.com:8443/login", Email: "alice@example.com", Password: "pw"}},
+		{"https://example.com/signin|alice|p:w", Result{URL: "https://example.com/signin", Username: "alice", Password: "p:w"}},
+	}
+	for _, tt := range tests {
+		got := p.parse(tt.line, "example.com")
+		if len(got) != 1 {
+			t.Fatalf("%s: expected 1 result, got %d", tt.line, len(got))
+		}
+		r := got[0]
+		if r.URL != tt.want.URL || r.Email != tt.want.Email || r.Username != tt.want.Username || r.Password != tt.want.Password {
+			t.Errorf("%s: got %+v, want %+v", tt.line, r, tt.want)
+		}
+	}
+}
+
+func TestIntelxParser_ComboKeepsSeparatorsInPassword(t *testing.T) {
+	p := newIntelxParser([]string{"a@b.com:pw"})
+	got := p.parse("alice@example.com:pa:ss:word", "alice@example.com")
+	if len(got) != 1 || got[0].Email != "alice@example.com" || got[0].Password != "pa:ss:word" {
+		t.Errorf("unexpected result: %+v", got)
+	}
+	if len(got[0].Extra) != 0 {
+		t.Errorf("expected no extra fields, got %+v", got[0].Extra)
+	}
+}
+
+func TestIntelxParser_UnknownFallsBack(t *testing.T) {
+	p := newIntelxParser([]string{"some header text"})
+	got := p.parse("alice@example.com:secret", "alice@example.com")
+	if len(got) != 1 || got[0].Email != "alice@example.com" || got[0].Password != "secret" {
+		t.Errorf("unexpected result: %+v", got)
+	}
+}
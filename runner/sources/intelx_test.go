@@ -0,0 +1,87 @@
+package sources
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync/atomic"
+	"testing"
+)
+
+func TestIntelX_SearchPhonebook(t *testing.T) {
+	var polls, terminated atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-key") != "key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/phonebook/search":
+			var req intelxPhonebookRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Errorf("could not decode phonebook request: %v", err)
+			}
+			if req.Term != "example.com" || req.Target != intelxPhonebookTargetEmails {
+				t.Errorf("unexpected phonebook request %+v", req)
+			}
+			_, _ = w.Write([]byte(`{"id":"search-1","status":0}`))
+		case "/phonebook/search/result":
+			if id := r.URL.Query().Get("id"); id != "search-1" {
+				t.Errorf("unexpected search id %q", id)
+			}
+			// the first poll has more results to come, the second is the last
+			if polls.Add(1) == 1 {
+				_, _ = w.Write([]byte(`{"status":0,"selectors":[
+					{"selectorvalue":"alice@example.com","selectortype":1},
+					{"selectorvalue":" Bob@Mail.Example.com ","selectortype":1},
+					{"selectorvalue":"mallory@example.org","selectortype":1},
+					{"selectorvalue":"eve@notexample.com","selectortype":1},
+					{"selectorvalue":"example.com","selectortype":2}
+				]}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"status":1,"selectors":[
+				{"selectorvalue":"ALICE@example.com","selectortype":1},
+				{"selectorvalue":"carol@example.com","selectortype":1}
+			]}`))
+		case "/intelligent/search/terminate":
+			terminated.Add(1)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	s := &IntelX{}
+	emails, err := s.searchPhonebook(context.Background(), newTestSession(t), srv.URL+"/", "key", "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"alice@example.com", "bob@mail.example.com", "carol@example.com"}
+	if !slices.Equal(emails, want) {
+		t.Errorf("expected %v, got %v", want, emails)
+	}
+	if n := polls.Load(); n != 2 {
+		t.Errorf("expected 2 result polls, got %d", n)
+	}
+	if n := terminated.Load(); n != 1 {
+		t.Errorf("expected the search to be terminated once, got %d", n)
+	}
+}
+
+func TestIntelX_SearchPhonebookFailedSearch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/phonebook/search" {
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+		_, _ = w.Write([]byte(`{"id":"","status":1}`))
+	}))
+	defer srv.Close()
+
+	s := &IntelX{}
+	if _, err := s.searchPhonebook(context.Background(), newTestSession(t), srv.URL+"/", "key", "example.com"); err == nil {
+		t.Error("expected an error for an invalid phonebook search")
+	}
+}
@@ -4,10 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"github.com/vflame6/leaker/logger"
 	"github.com/vflame6/leaker/utils"
 	"io"
 	"net/http"
+	neturl "net/url"
+	"strings"
 )
 
 type LeakCheck struct {
@@ -29,20 +30,35 @@ func (s *LeakCheck) Run(ctx context.Context, target string, scanType ScanType, s
 			return
 		}
 
-		var url string
+		var queryType string
 		var response map[string]interface{}
 
 		switch scanType {
 		case TypeEmail:
-			url = fmt.Sprintf("https://leakcheck.io/api/v2/query/%s?type=email", target)
+			queryType = "email"
 		case TypeUsername:
-			url = fmt.Sprintf("https://leakcheck.io/api/v2/query/%s?type=username", target)
+			queryType = "username"
 		case TypeDomain:
-			url = fmt.Sprintf("https://leakcheck.io/api/v2/query/%s?type=domain", target)
+			queryType = "domain"
 		case TypeKeyword:
-			url = fmt.Sprintf("https://leakcheck.io/api/v2/query/%s?type=keyword", target)
+			queryType = "keyword"
 		case TypePhone:
-			url = fmt.Sprintf("https://leakcheck.io/api/v2/query/%s?type=phone", target)
+			queryType = "phone"
+		case TypeHash:
+			// "hash" is a truncated SHA-256 of the email; "phash" searches
+			// by password hash.
+			queryType = "phash"
+		case TypePassword:
+			queryType = "password"
+		default:
+			return
+		}
+		// Passwords and hashes may contain path separators or '?'.
+		url := fmt.Sprintf("https://leakcheck.io/api/v2/query/%s?type=%s", neturl.PathEscape(target), queryType)
+
+		if err := session.Quota.Allow(s.Name()); err != nil {
+			results <- Result{Source: s.Name(), Error: err}
+			return
 		}
 
 		// prepare request with custom headers
@@ -58,7 +74,7 @@ func (s *LeakCheck) Run(ctx context.Context, target string, scanType ScanType, s
 		req.Header.Add("Accept", "application/json")
 
 		// perform the request
-		logger.Debugf("Sending a request in LeakCheck source for %s", target)
+		session.Log().Debugf("Sending a request in LeakCheck source for %s", target)
 		resp, err := session.Client.Do(req)
 		if err != nil {
 			results <- Result{
@@ -77,7 +93,7 @@ func (s *LeakCheck) Run(ctx context.Context, target string, scanType ScanType, s
 			}
 			return
 		}
-		logger.Debugf("Response from LeakCheck source: status code [%d], size [%d]", resp.StatusCode, len(body))
+		session.Log().Debugf("Response from LeakCheck source: status code [%d], size [%d]", resp.StatusCode, len(body))
 
 		err = json.Unmarshal(body, &response)
 		if err != nil {
@@ -88,6 +104,16 @@ func (s *LeakCheck) Run(ctx context.Context, target string, scanType ScanType, s
 			return
 		}
 
+		// Every successful query costs one credit; "quota" is the balance left.
+		if quota, ok := response["quota"].(float64); ok {
+			session.Quota.SetRemaining(s.Name(), randomApiKey, int(quota))
+		}
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusPaymentRequired {
+			if errMsg, _ := response["error"].(string); strings.Contains(strings.ToLower(errMsg), "limit") {
+				session.Quota.MarkExhausted(s.Name(), randomApiKey)
+			}
+		}
+
 		success, ok := response["success"].(bool)
 		if !ok || !success {
 			results <- Result{
@@ -104,6 +130,7 @@ func (s *LeakCheck) Run(ctx context.Context, target string, scanType ScanType, s
 			}
 			return
 		}
+		session.Quota.Consume(s.Name(), randomApiKey, 1)
 		foundInt := int(found)
 		if foundInt > 0 {
 			jsonResults, ok := response["result"].([]interface{})
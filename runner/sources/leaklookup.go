@@ -9,7 +9,6 @@ import (
 	"net/url"
 	"strings"
 
-	"github.com/vflame6/leaker/logger"
 	"github.com/vflame6/leaker/utils"
 )
 
@@ -45,6 +44,8 @@ func (s *LeakLookup) Run(ctx context.Context, target string, scanType ScanType,
 			searchType = "password"
 		case TypePhone:
 			searchType = "phone"
+		default:
+			return
 		}
 
 		form := url.Values{}
@@ -61,7 +62,7 @@ func (s *LeakLookup) Run(ctx context.Context, target string, scanType ScanType,
 		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 		req.Header.Set("Accept", "application/json")
 
-		logger.Debugf("Sending a request in LeakLookup source for %s", target)
+		session.Log().Debugf("Sending a request in LeakLookup source for %s", target)
 		resp, err := session.Client.Do(req)
 		if err != nil {
 			results <- Result{Source: s.Name(), Error: err}
@@ -74,7 +75,7 @@ func (s *LeakLookup) Run(ctx context.Context, target string, scanType ScanType,
 			results <- Result{Source: s.Name(), Error: err}
 			return
 		}
-		logger.Debugf("Response from LeakLookup source: status code [%d], size [%d]", resp.StatusCode, len(body))
+		session.Log().Debugf("Response from LeakLookup source: status code [%d], size [%d]", resp.StatusCode, len(body))
 
 		if resp.StatusCode != http.StatusOK {
 			results <- Result{
@@ -10,9 +10,7 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
-	"time"
 
-	"github.com/vflame6/leaker/logger"
 	"github.com/vflame6/leaker/utils"
 )
 
@@ -21,11 +19,12 @@ const leakRadarDefaultBaseURL = "https://api.leakradar.io"
 const (
 	leakRadarEmailPageSize  = 100
 	leakRadarDomainPageSize = 1000
-	// Bounds auto-unlock point spend and runtime while still covering large targets.
-	leakRadarMaxPages = 10
 )
 
 type LeakRadar struct {
+	// pageLimits bounds auto-unlock point spend and runtime while still
+	// covering large targets.
+	pageLimits
 	apiKeys []string
 	baseURL string
 }
@@ -87,9 +86,10 @@ func (s *LeakRadar) Run(ctx context.Context, target string, scanType ScanType, s
 		default:
 			return
 		}
+		// A credit limit stops paging early; the leaks fetched so far are
+		// still emitted.
 		if err != nil {
 			results <- Result{Source: s.Name(), Error: err}
-			return
 		}
 
 		for _, leak := range leaks {
@@ -112,7 +112,7 @@ func (s *LeakRadar) searchEmail(ctx context.Context, session *Session, apiKey, t
 		return nil, err
 	}
 
-	return s.searchPages(ctx, session, leakRadarEmailPageSize, func(page int) (*http.Request, error) {
+	return s.searchPages(ctx, session, apiKey, target, leakRadarEmailPageSize, func(page int) (*http.Request, error) {
 		endpoint, err := url.Parse(s.apiBaseURL() + "/search/email")
 		if err != nil {
 			return nil, err
@@ -131,13 +131,13 @@ func (s *LeakRadar) searchEmail(ctx context.Context, session *Session, apiKey, t
 		req.Header.Set("Content-Type", "application/json")
 		req.Header.Set("Accept", "application/json")
 
-		logger.Debugf("Sending a request in LeakRadar source for %s", target)
+		session.Log().Debugf("Sending a request in LeakRadar source for %s", target)
 		return req, nil
 	})
 }
 
 func (s *LeakRadar) searchDomain(ctx context.Context, session *Session, apiKey, target string) ([]leakRadarLeak, error) {
-	return s.searchPages(ctx, session, leakRadarDomainPageSize, func(page int) (*http.Request, error) {
+	return s.searchPages(ctx, session, apiKey, target, leakRadarDomainPageSize, func(page int) (*http.Request, error) {
 		endpoint, err := url.Parse(s.apiBaseURL() + "/search/domain/" + url.PathEscape(target) + "/all")
 		if err != nil {
 			return nil, err
@@ -155,7 +155,7 @@ func (s *LeakRadar) searchDomain(ctx context.Context, session *Session, apiKey,
 		req.Header.Set("Authorization", "Bearer "+apiKey)
 		req.Header.Set("Accept", "application/json")
 
-		logger.Debugf("Sending a request in LeakRadar source for %s", target)
+		session.Log().Debugf("Sending a request in LeakRadar source for %s", target)
 		return req, nil
 	})
 }
@@ -163,16 +163,24 @@ func (s *LeakRadar) searchDomain(ctx context.Context, session *Session, apiKey,
 func (s *LeakRadar) searchPages(
 	ctx context.Context,
 	session *Session,
+	apiKey string,
+	target string,
 	defaultPageSize int,
 	newRequest func(page int) (*http.Request, error),
 ) ([]leakRadarLeak, error) {
 	var leaks []leakRadarLeak
+	total := 0
+	moreAvailable := false
 
-	for page := 1; page <= leakRadarMaxPages; page++ {
+	for page := 1; page <= s.pageCap(); page++ {
 		if err := ctx.Err(); err != nil {
 			return nil, err
 		}
 
+		if err := session.Quota.Allow(s.Name()); err != nil {
+			return leaks, err
+		}
+
 		req, err := newRequest(page)
 		if err != nil {
 			return nil, err
@@ -182,7 +190,16 @@ func (s *LeakRadar) searchPages(
 		if err != nil {
 			return nil, err
 		}
+		if response.AutoUnlockPointsConsumed != nil {
+			session.Quota.Consume(s.Name(), apiKey, *response.AutoUnlockPointsConsumed)
+		}
+		total = response.Total
 		leaks = append(leaks, response.Items...)
+		if len(leaks) >= s.resultCap() {
+			leaks = leaks[:s.resultCap()]
+			moreAvailable = true
+			break
+		}
 
 		pageSize := response.PageSize
 		if pageSize <= 0 {
@@ -193,20 +210,20 @@ func (s *LeakRadar) searchPages(
 			currentPage = page
 		}
 		if len(response.Items) == 0 || currentPage*pageSize >= response.Total {
+			moreAvailable = false
 			break
 		}
+		moreAvailable = true
 
-		if page == leakRadarMaxPages {
-			logger.Debugf("LeakRadar reached page cap [%d] with more results available", leakRadarMaxPages)
+		if page == s.pageCap() {
 			break
 		}
 
-		select {
-		case <-ctx.Done():
+		if !waitNextPage(ctx, s.RateLimit()) {
 			return nil, ctx.Err()
-		case <-time.After(time.Second / time.Duration(s.RateLimit())):
 		}
 	}
+	warnTruncated(session.Log(), "LeakRadar", target, total, len(leaks), moreAvailable)
 
 	return leaks, nil
 }
@@ -222,7 +239,7 @@ func (s *LeakRadar) doSearch(session *Session, req *http.Request) (leakRadarSear
 	if err != nil {
 		return leakRadarSearchResponse{}, err
 	}
-	logger.Debugf("Response from LeakRadar source: status code [%d], size [%d]", resp.StatusCode, len(body))
+	session.Log().Debugf("Response from LeakRadar source: status code [%d], size [%d]", resp.StatusCode, len(body))
 
 	if resp.StatusCode != http.StatusOK {
 		return leakRadarSearchResponse{}, fmt.Errorf("LeakRadar returned status %d: %s", resp.StatusCode, string(body))
@@ -7,7 +7,6 @@ import (
 	"io"
 	"net/http"
 
-	"github.com/vflame6/leaker/logger"
 	"github.com/vflame6/leaker/utils"
 )
 
@@ -39,6 +38,8 @@ func (s *LeakSight) Run(ctx context.Context, target string, scanType ScanType, s
 			endpoint = "password"
 		case TypePhone:
 			endpoint = "number"
+		default:
+			return
 		}
 
 		url := fmt.Sprintf("https://api.leaksight.com/osint/%s?token=%s&text=%s",
@@ -51,7 +52,7 @@ func (s *LeakSight) Run(ctx context.Context, target string, scanType ScanType, s
 		}
 		req.Header.Set("Accept", "application/json")
 
-		logger.Debugf("Sending a request in LeakSight source for %s", target)
+		session.Log().Debugf("Sending a request in LeakSight source for %s", target)
 		resp, err := session.Client.Do(req)
 		if err != nil {
 			results <- Result{Source: s.Name(), Error: err}
@@ -64,7 +65,7 @@ func (s *LeakSight) Run(ctx context.Context, target string, scanType ScanType, s
 			results <- Result{Source: s.Name(), Error: err}
 			return
 		}
-		logger.Debugf("Response from LeakSight source: status code [%d], size [%d]", resp.StatusCode, len(body))
+		session.Log().Debugf("Response from LeakSight source: status code [%d], size [%d]", resp.StatusCode, len(body))
 
 		if resp.StatusCode != http.StatusOK {
 			results <- Result{
@@ -13,7 +13,7 @@ const LocalSourceName = "local"
 type LocalDBLookup func(ctx context.Context, target string, scanType ScanType) <-chan Result
 
 // LocalDB is a Source that reads from the local SQLite cache instead of
-// hitting an online API. It is added to AllSources so --list-sources
+// hitting an online API. It is part of the source registry so --list-sources
 // discovers it, and is pulled out of the parallel fan-out by the runner
 // so local results arrive first and populate the dedup map before any
 // online source produces a result.
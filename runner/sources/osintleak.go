@@ -6,13 +6,20 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 
-	"github.com/vflame6/leaker/logger"
 	"github.com/vflame6/leaker/utils"
 )
 
+const osintleakDefaultBaseURL = "https://osintleak.com"
+
+// osintleakPageSize is the number of records requested per page.
+const osintleakPageSize = 100
+
 type OSINTLeak struct {
+	pageLimits
 	apiKeys []string
+	baseURL string
 }
 
 // osintleakIgnoredFields are internal/metadata fields that should not appear in results.
@@ -77,124 +84,158 @@ func (s *OSINTLeak) Run(ctx context.Context, target string, scanType ScanType, s
 			searchType = "username"
 		case TypePhone:
 			searchType = "phone"
-		}
-
-		url := fmt.Sprintf(
-			"https://osintleak.com/api/v1/search_api/?api_key=%s&query=%s&type=%s&stealerlogs=true&dbleaks=true&dbleaks2=true&page=1&page_size=100",
-			randomApiKey, target, searchType,
-		)
-
-		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-		if err != nil {
-			results <- Result{Source: s.Name(), Error: err}
+		default:
 			return
 		}
-		req.Header.Set("Accept", "application/json")
 
-		logger.Debugf("Sending a request in OSINTLeak source for %s", target)
-		resp, err := session.Client.Do(req)
-		if err != nil {
-			results <- Result{Source: s.Name(), Error: err}
-			return
-		}
-		defer session.DiscardHTTPResponse(resp)
+		fetched, total := 0, 0
+		lastPageFull := false
+		for page := 1; page <= s.pageCap() && fetched < s.resultCap(); page++ {
+			if page > 1 && !waitNextPage(ctx, s.RateLimit()) {
+				return
+			}
 
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			results <- Result{Source: s.Name(), Error: err}
-			return
-		}
-		logger.Debugf("Response from OSINTLeak source: status code [%d], size [%d]", resp.StatusCode, len(body))
+			session.Log().Debugf("Sending a request in OSINTLeak source for %s (page %d)", target, page)
+			data, pageTotal, err := s.search(ctx, session, randomApiKey, target, searchType, page)
+			if err != nil {
+				results <- Result{Source: s.Name(), Error: err}
+				return
+			}
+			total = pageTotal
+			lastPageFull = len(data) == osintleakPageSize
 
-		if resp.StatusCode != http.StatusOK {
-			results <- Result{
-				Source: s.Name(),
-				Error:  fmt.Errorf("OSINTLeak returned status %d: %s", resp.StatusCode, string(body)),
+			for _, item := range data {
+				if fetched >= s.resultCap() {
+					break
+				}
+				fetched++
+				if r, ok := s.entryToResult(item); ok {
+					results <- r
+				}
 			}
-			return
-		}
 
-		var response map[string]interface{}
-		if err := json.Unmarshal(body, &response); err != nil {
-			results <- Result{Source: s.Name(), Error: err}
-			return
+			if !lastPageFull || (total > 0 && fetched >= total) {
+				break
+			}
 		}
+		warnTruncated(session.Log(), "OSINTLeak", target, total, fetched, lastPageFull)
+	}()
 
-		// Parse results array
-		data, ok := response["data"].([]interface{})
-		if !ok {
-			// Try "results" key as fallback
-			data, ok = response["results"].([]interface{})
-			if !ok {
-				return
-			}
+	return results
+}
+
+// search requests a single page of results and returns the raw entries
+// together with the total reported by the provider (zero if absent).
+func (s *OSINTLeak) search(ctx context.Context, session *Session, apiKey, target, searchType string, page int) ([]interface{}, int, error) {
+	url := fmt.Sprintf(
+		"%s/api/v1/search_api/?api_key=%s&query=%s&type=%s&stealerlogs=true&dbleaks=true&dbleaks2=true&page=%d&page_size=%d",
+		s.apiBaseURL(), apiKey, target, searchType, page, osintleakPageSize,
+	)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := session.Client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer session.DiscardHTTPResponse(resp)
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+	session.Log().Debugf("Response from OSINTLeak source: status code [%d], size [%d]", resp.StatusCode, len(body))
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("OSINTLeak returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var response map[string]interface{}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, 0, err
+	}
+
+	total := 0
+	for _, key := range []string{"total", "count"} {
+		if n, ok := response[key].(float64); ok {
+			total = int(n)
+			break
 		}
+	}
 
-		for _, item := range data {
-			entry, ok := item.(map[string]interface{})
-			if !ok {
-				continue
-			}
+	// Parse results array
+	data, ok := response["data"].([]interface{})
+	if !ok {
+		// Try "results" key as fallback
+		data, _ = response["results"].([]interface{})
+	}
+	return data, total, nil
+}
 
-			r := Result{Source: s.Name()}
-
-			// Primary fields — extract with null/None handling
-			r.Email = osintleakString(entry, "email")
-			r.Username = osintleakString(entry, "username")
-			r.Password = osintleakString(entry, "password")
-			r.Phone = osintleakString(entry, "phone")
-			r.Name = osintleakString(entry, "name")
-			r.IP = osintleakString(entry, "ip")
-			r.URL = osintleakString(entry, "url")
-			r.Hash = osintleakString(entry, "pass_hash")
-			r.Salt = osintleakString(entry, "pass_salt")
-
-			// ip_address is used in d2 dataset entries
-			if r.IP == "" {
-				r.IP = osintleakString(entry, "ip_address")
-			}
+// entryToResult maps a single OSINTLeak record to a Result.
+func (s *OSINTLeak) entryToResult(item interface{}) (Result, bool) {
+	entry, ok := item.(map[string]interface{})
+	if !ok {
+		return Result{}, false
+	}
 
-			// Map log_name → Database (can be null or missing)
-			r.Database = osintleakString(entry, "log_name")
+	r := Result{Source: s.Name()}
 
-			// Handle first_name/last_name if present
-			if fn := osintleakString(entry, "first_name"); fn != "" {
-				if r.Name != "" {
-					r.Name = fn + " " + r.Name
-				} else {
-					r.Name = fn
-				}
-			}
-			if ln := osintleakString(entry, "last_name"); ln != "" {
-				if r.Name != "" {
-					r.Name += " " + ln
-				} else {
-					r.Name = ln
-				}
-			}
+	// Primary fields — extract with null/None handling
+	r.Email = osintleakString(entry, "email")
+	r.Username = osintleakString(entry, "username")
+	r.Password = osintleakString(entry, "password")
+	r.Phone = osintleakString(entry, "phone")
+	r.Name = osintleakString(entry, "name")
+	r.IP = osintleakString(entry, "ip")
+	r.URL = osintleakString(entry, "url")
+	r.Hash = osintleakString(entry, "pass_hash")
+	r.Salt = osintleakString(entry, "pass_salt")
 
-			// Extra fields — anything not in the ignored/primary set
-			for key, val := range entry {
-				if _, ignored := osintleakIgnoredFields[key]; ignored {
-					continue
-				}
-				if key == "first_name" || key == "last_name" {
-					continue
-				}
-				strVal := osintleakStringVal(val)
-				if strVal == "" {
-					continue
-				}
-				r.SetExtra(key, strVal)
-			}
+	// ip_address is used in d2 dataset entries
+	if r.IP == "" {
+		r.IP = osintleakString(entry, "ip_address")
+	}
 
-			if r.HasData() {
-				results <- r
-			}
+	// Map log_name → Database (can be null or missing)
+	r.Database = osintleakString(entry, "log_name")
+
+	// Handle first_name/last_name if present
+	if fn := osintleakString(entry, "first_name"); fn != "" {
+		if r.Name != "" {
+			r.Name = fn + " " + r.Name
+		} else {
+			r.Name = fn
 		}
-	}()
+	}
+	if ln := osintleakString(entry, "last_name"); ln != "" {
+		if r.Name != "" {
+			r.Name += " " + ln
+		} else {
+			r.Name = ln
+		}
+	}
 
-	return results
+	// Extra fields — anything not in the ignored/primary set
+	for key, val := range entry {
+		if _, ignored := osintleakIgnoredFields[key]; ignored {
+			continue
+		}
+		if key == "first_name" || key == "last_name" {
+			continue
+		}
+		strVal := osintleakStringVal(val)
+		if strVal == "" {
+			continue
+		}
+		r.SetExtra(key, strVal)
+	}
+
+	return r, r.HasData()
 }
 
 // osintleakString extracts a string value from a map entry, treating null and "None" as empty.
@@ -225,6 +266,13 @@ func osintleakStringVal(val interface{}) string {
 	return s
 }
 
+func (s *OSINTLeak) apiBaseURL() string {
+	if s.baseURL != "" {
+		return strings.TrimRight(s.baseURL, "/")
+	}
+	return osintleakDefaultBaseURL
+}
+
 func (s *OSINTLeak) Name() string {
 	return "osintleak"
 }
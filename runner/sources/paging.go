@@ -0,0 +1,90 @@
+package sources
+
+import (
+	"context"
+	"time"
+
+	"github.com/vflame6/leaker/logger"
+)
+
+// Default pagination bounds, used when the runner does not configure
+// explicit limits. They keep credit spend and runtime in check while
+// still covering large domain targets.
+const (
+	DefaultMaxPages   = 10
+	DefaultMaxResults = 10000
+)
+
+// PageLimiter is implemented by sources that paginate through provider
+// results. The runner calls SetPageLimits once after source selection.
+type PageLimiter interface {
+	SetPageLimits(maxPages, maxResults int)
+}
+
+// pageLimits is embedded by paginating sources to satisfy PageLimiter.
+// Non-positive values fall back to the package defaults.
+type pageLimits struct {
+	maxPages   int
+	maxResults int
+}
+
+func (p *pageLimits) SetPageLimits(maxPages, maxResults int) {
+	p.maxPages = maxPages
+	p.maxResults = maxResults
+}
+
+// pageCap returns the maximum number of pages to request per target.
+func (p *pageLimits) pageCap() int {
+	if p.maxPages > 0 {
+		return p.maxPages
+	}
+	return DefaultMaxPages
+}
+
+// resultCap returns the maximum number of records to fetch per target.
+func (p *pageLimits) resultCap() int {
+	if p.maxResults > 0 {
+		return p.maxResults
+	}
+	return DefaultMaxResults
+}
+
+// warnTruncated logs a warning to log when a provider reports more results than
+// were fetched. A reported total of zero or less means the provider does
+// not expose one; in that case moreAvailable decides whether to warn.
+func warnTruncated(log *logger.Logger, provider, target string, reported, fetched int, moreAvailable bool) {
+	switch {
+	case reported > fetched:
+		log.Warnf("%s reported %d results for %s but only %d were fetched; raise --max-pages or --max-results to fetch more",
+			provider, reported, target, fetched)
+	case reported <= 0 && moreAvailable:
+		log.Warnf("%s stopped at %d results for %s with more pages available; raise --max-pages or --max-results to fetch more",
+			provider, fetched, target)
+	}
+}
+
+// warnProviderCap logs a warning to log when paging stopped at a provider's own
+// limit of results per search, which no flag can raise.
+func warnProviderCap(log *logger.Logger, provider, target string, limit, reported, fetched int) {
+	if reported > fetched {
+		log.Warnf("%s returns at most %d results per search; only %d of the %d results for %s were fetched",
+			provider, limit, fetched, reported, target)
+		return
+	}
+	log.Warnf("%s returns at most %d results per search; stopped at %d results for %s",
+		provider, limit, fetched, target)
+}
+
+// waitNextPage sleeps for one rate-limit interval between page requests.
+// It returns false if the context was cancelled while waiting.
+func waitNextPage(ctx context.Context, rateLimit int) bool {
+	if rateLimit <= 0 {
+		rateLimit = 1
+	}
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(time.Second / time.Duration(rateLimit)):
+		return true
+	}
+}
@@ -0,0 +1,345 @@
+package sources
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/vflame6/leaker/logger"
+)
+
+// pagingSource is a paginating source under test.
+type pagingSource interface {
+	Source
+	PageLimiter
+}
+
+// pagingCase describes how one paginating source asks for a page and how
+// its provider answers.
+type pagingCase struct {
+	name     string
+	pageSize int
+	source   func(baseURL string) pagingSource
+	// page returns the 1-based page a request asks for.
+	page func(t *testing.T, r *http.Request) int
+	// respond writes the records first to first+n-1 of total.
+	respond func(w http.ResponseWriter, first, n, total int)
+}
+
+// testRecords returns n records with distinct logins, starting at first.
+func testRecords(first, n int) []map[string]any {
+	records := make([]map[string]any, 0, n)
+	for i := first; i < first+n; i++ {
+		records = append(records, map[string]any{
+			"email":    fmt.Sprintf("user%d@example.com", i),
+			"password": fmt.Sprintf("secret%d", i),
+		})
+	}
+	return records
+}
+
+func decodeTestBody(t *testing.T, r *http.Request, v any) {
+	t.Helper()
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		t.Errorf("could not decode request body: %v", err)
+	}
+}
+
+func queryPage(t *testing.T, r *http.Request) int {
+	t.Helper()
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil {
+		t.Errorf("invalid page parameter %q", r.URL.Query().Get("page"))
+	}
+	return page
+}
+
+var pagingCases = []pagingCase{
+	{
+		name:     "DeHashed",
+		pageSize: dehashedPageSize,
+		source: func(baseURL string) pagingSource {
+			return &DeHashed{apiKeys: []string{"key"}, baseURL: baseURL}
+		},
+		page: func(t *testing.T, r *http.Request) int {
+			var req dehashedSearchRequest
+			decodeTestBody(t, r, &req)
+			if req.Size != dehashedPageSize {
+				t.Errorf("unexpected page size %d", req.Size)
+			}
+			return req.Page
+		},
+		respond: func(w http.ResponseWriter, first, n, total int) {
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"balance": 100,
+				"total":   total,
+				"entries": testRecords(first, n),
+			})
+		},
+	},
+	{
+		name:     "WeLeakInfo",
+		pageSize: weLeakInfoPageSize,
+		source: func(baseURL string) pagingSource {
+			return &WeLeakInfo{apiKeys: []string{"pub:priv"}, baseURL: baseURL}
+		},
+		page: func(t *testing.T, r *http.Request) int {
+			var req weLeakInfoRequest
+			decodeTestBody(t, r, &req)
+			offset, err := strconv.Atoi(req.Offset)
+			if err != nil || offset%weLeakInfoPageSize != 0 {
+				t.Errorf("unexpected offset %q", req.Offset)
+			}
+			return offset/weLeakInfoPageSize + 1
+		},
+		respond: func(w http.ResponseWriter, first, n, total int) {
+			// WeLeakInfo sends the total as a numeric string
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"Total": strconv.Itoa(total),
+				"Data":  testRecords(first, n),
+			})
+		},
+	},
+	{
+		name:     "WhiteIntel",
+		pageSize: whiteIntelPageSize,
+		source: func(baseURL string) pagingSource {
+			return &WhiteIntel{apiKeys: []string{"key"}, baseURL: baseURL}
+		},
+		page: func(t *testing.T, r *http.Request) int {
+			var req whiteIntelRequest
+			decodeTestBody(t, r, &req)
+			return req.Page
+		},
+		respond: func(w http.ResponseWriter, first, n, total int) {
+			records := testRecords(first, n)
+			for _, record := range records {
+				record["username"] = record["email"]
+				delete(record, "email")
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"success": true,
+				"total":   total,
+				"results": records,
+			})
+		},
+	},
+	{
+		name:     "OSINTLeak",
+		pageSize: osintleakPageSize,
+		source: func(baseURL string) pagingSource {
+			return &OSINTLeak{apiKeys: []string{"key"}, baseURL: baseURL}
+		},
+		page: queryPage,
+		respond: func(w http.ResponseWriter, first, n, total int) {
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"total": total,
+				"data":  testRecords(first, n),
+			})
+		},
+	},
+	{
+		name:     "LeakRadar",
+		pageSize: leakRadarEmailPageSize,
+		source: func(baseURL string) pagingSource {
+			return &LeakRadar{apiKeys: []string{"key"}, baseURL: baseURL}
+		},
+		page: queryPage,
+		respond: func(w http.ResponseWriter, first, n, total int) {
+			items := make([]map[string]any, 0, n)
+			for i := first; i < first+n; i++ {
+				items = append(items, map[string]any{
+					"id":       strconv.Itoa(i),
+					"username": fmt.Sprintf("user%d@example.com", i),
+					"password": fmt.Sprintf("secret%d", i),
+					"unlocked": true,
+				})
+			}
+			page := first/leakRadarEmailPageSize + 1
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"items":     items,
+				"total":     total,
+				"page":      page,
+				"page_size": leakRadarEmailPageSize,
+			})
+		},
+	},
+}
+
+// servePages serves total records in pages of pc.pageSize and counts the
+// requests made.
+func servePages(t *testing.T, pc pagingCase, total int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		page := pc.page(t, r)
+		if page < 1 {
+			t.Errorf("unexpected page %d", page)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		first := (page - 1) * pc.pageSize
+		n := max(min(pc.pageSize, total-first), 0)
+		pc.respond(w, first, n, total)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &requests
+}
+
+// captureLogs collects log output for the rest of the test.
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var logs bytes.Buffer
+	logger.SetOutput(&logs)
+	logger.SetNoColor(true)
+	t.Cleanup(func() {
+		logger.SetOutput(os.Stderr)
+		logger.SetNoColor(false)
+	})
+	return &logs
+}
+
+// checkPagedResults checks that got holds exactly the first want records,
+// in order and without errors.
+func checkPagedResults(t *testing.T, got []Result, want int) {
+	t.Helper()
+	if len(got) != want {
+		t.Fatalf("expected %d results, got %d", want, len(got))
+	}
+	for i, r := range got {
+		if r.Error != nil {
+			t.Fatalf("unexpected error: %v", r.Error)
+		}
+		// WhiteIntel reports the login as a username
+		if email := fmt.Sprintf("user%d@example.com", i); r.Email != email && r.Username != email {
+			t.Fatalf("result %d: expected %s, got %+v", i, email, r)
+		}
+	}
+}
+
+func TestPaging_FetchesAllPages(t *testing.T) {
+	for _, pc := range pagingCases {
+		t.Run(pc.name, func(t *testing.T) {
+			logs := captureLogs(t)
+			// two full pages and a short last page
+			total := 2*pc.pageSize + 5
+			srv, requests := servePages(t, pc, total)
+			s := pc.source(srv.URL)
+
+			got := collectResults(s.Run(context.Background(), "user@example.com", TypeEmail, newTestSession(t)))
+			checkPagedResults(t, got, total)
+			if n := requests.Load(); n != 3 {
+				t.Errorf("expected 3 page requests, got %d", n)
+			}
+			if strings.Contains(logs.String(), "[WARN]") {
+				t.Errorf("unexpected warning for a complete fetch: %s", logs.String())
+			}
+		})
+	}
+}
+
+func TestPaging_StopsAtReportedTotal(t *testing.T) {
+	for _, pc := range pagingCases {
+		t.Run(pc.name, func(t *testing.T) {
+			// the last page is full, but the reported total says it is the last
+			total := 2 * pc.pageSize
+			srv, requests := servePages(t, pc, total)
+			s := pc.source(srv.URL)
+
+			got := collectResults(s.Run(context.Background(), "user@example.com", TypeEmail, newTestSession(t)))
+			checkPagedResults(t, got, total)
+			if n := requests.Load(); n != 2 {
+				t.Errorf("expected 2 page requests, got %d", n)
+			}
+		})
+	}
+}
+
+func TestPaging_MaxPages(t *testing.T) {
+	for _, pc := range pagingCases {
+		t.Run(pc.name, func(t *testing.T) {
+			logs := captureLogs(t)
+			srv, requests := servePages(t, pc, 3*pc.pageSize)
+			s := pc.source(srv.URL)
+			s.SetPageLimits(1, 0)
+
+			got := collectResults(s.Run(context.Background(), "user@example.com", TypeEmail, newTestSession(t)))
+			checkPagedResults(t, got, pc.pageSize)
+			if n := requests.Load(); n != 1 {
+				t.Errorf("expected 1 page request, got %d", n)
+			}
+			if !strings.Contains(logs.String(), "raise --max-pages or --max-results") {
+				t.Errorf("expected a truncation warning, got %q", logs.String())
+			}
+		})
+	}
+}
+
+func TestPaging_MaxResults(t *testing.T) {
+	for _, pc := range pagingCases {
+		t.Run(pc.name, func(t *testing.T) {
+			logs := captureLogs(t)
+			srv, requests := servePages(t, pc, 3*pc.pageSize)
+			s := pc.source(srv.URL)
+			maxResults := pc.pageSize + 10
+			s.SetPageLimits(0, maxResults)
+
+			got := collectResults(s.Run(context.Background(), "user@example.com", TypeEmail, newTestSession(t)))
+			checkPagedResults(t, got, maxResults)
+			if n := requests.Load(); n != 2 {
+				t.Errorf("expected 2 page requests, got %d", n)
+			}
+			if !strings.Contains(logs.String(), "raise --max-pages or --max-results") {
+				t.Errorf("expected a truncation warning, got %q", logs.String())
+			}
+		})
+	}
+}
+
+func TestDeHashed_ProviderCap(t *testing.T) {
+	pc := pagingCases[0]
+	logs := captureLogs(t)
+	srv, requests := servePages(t, pc, 2*dehashedMaxResults)
+	s := pc.source(srv.URL)
+	s.SetPageLimits(20, 2*dehashedMaxResults)
+
+	got := collectResults(s.Run(context.Background(), "user@example.com", TypeEmail, newTestSession(t)))
+	checkPagedResults(t, got, dehashedMaxResults)
+	if n := requests.Load(); n != dehashedMaxResults/dehashedPageSize {
+		t.Errorf("expected %d page requests, got %d", dehashedMaxResults/dehashedPageSize, n)
+	}
+	if !strings.Contains(logs.String(), "returns at most 10000 results per search") {
+		t.Errorf("expected a provider cap warning, got %q", logs.String())
+	}
+	if strings.Contains(logs.String(), "--max-pages") {
+		t.Errorf("expected no advice to raise --max-pages, got %q", logs.String())
+	}
+}
+
+func TestPaging_SessionLogger(t *testing.T) {
+	global := captureLogs(t)
+	pc := pagingCases[0]
+	srv, _ := servePages(t, pc, 3*pc.pageSize)
+	s := pc.source(srv.URL)
+	s.SetPageLimits(1, 0)
+
+	var logs bytes.Buffer
+	session := newTestSession(t)
+	session.Logger = logger.New(logger.LevelWarning, &logs)
+	collectResults(s.Run(context.Background(), "user@example.com", TypeEmail, session))
+	if !strings.Contains(logs.String(), "raise --max-pages or --max-results") {
+		t.Errorf("expected the truncation warning in the session logger, got %q", logs.String())
+	}
+	if global.Len() != 0 {
+		t.Errorf("expected nothing in the package logger, got %q", global.String())
+	}
+}
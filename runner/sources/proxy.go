@@ -0,0 +1,67 @@
+package sources
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// supportedProxySchemes lists the proxy URL schemes net/http can dial.
+// socks5h resolves hostnames on the proxy side, socks5 resolves locally.
+var supportedProxySchemes = []string{"http", "https", "socks5", "socks5h"}
+
+// ParseProxyURL strictly validates a proxy URL. It requires a supported
+// scheme and an explicit host and port, and rejects paths, queries and
+// fragments so that typos fail loudly instead of silently bypassing the
+// proxy.
+func ParseProxyURL(raw string) (*url.URL, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, fmt.Errorf("empty proxy URL")
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+
+	scheme := strings.ToLower(u.Scheme)
+	supported := false
+	for _, s := range supportedProxySchemes {
+		if scheme == s {
+			supported = true
+			break
+		}
+	}
+	if !supported {
+		return nil, fmt.Errorf("invalid proxy URL %q: scheme must be one of %s",
+			u.Redacted(), strings.Join(supportedProxySchemes, ", "))
+	}
+	u.Scheme = scheme
+
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid proxy URL %q: missing host", u.Redacted())
+	}
+	port, err := strconv.Atoi(u.Port())
+	if err != nil || port < 1 || port > 65535 {
+		return nil, fmt.Errorf("invalid proxy URL %q: missing or invalid port", u.Redacted())
+	}
+	if (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" || u.Opaque != "" {
+		return nil, fmt.Errorf("invalid proxy URL %q: unexpected path or query", u.Redacted())
+	}
+	u.Path = ""
+
+	return u, nil
+}
+
+// WithProxyCredentials returns a copy of u carrying the given credentials,
+// unless u already has its own or username is empty.
+func WithProxyCredentials(u *url.URL, username, password string) *url.URL {
+	if u == nil || u.User != nil || username == "" {
+		return u
+	}
+	withCreds := *u
+	withCreds.User = url.UserPassword(username, password)
+	return &withCreds
+}
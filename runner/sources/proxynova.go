@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"github.com/vflame6/leaker/logger"
 	"io"
 	"net/http"
 	"strings"
@@ -26,8 +25,14 @@ func (s *ProxyNova) Run(ctx context.Context, target string, scanType ScanType, s
 	go func() {
 		defer close(results)
 
+		// COMB only holds email:password pairs searched as free text,
+		// so field pivots are left to sources that index those fields.
+		if scanType.IsPivot() {
+			return
+		}
+
 		// Fetch the first page to learn the total count
-		// scanType is ignored because ProxyNova does not support scan types
+		// scanType is otherwise ignored because ProxyNova does not support scan types
 		firstPage, err := s.fetchPage(ctx, target, 0, session)
 		if err != nil {
 			results <- Result{Source: s.Name(), Error: err}
@@ -84,7 +89,7 @@ func (s *ProxyNova) Run(ctx context.Context, target string, scanType ScanType, s
 // fetchPage retrieves one page of results from ProxyNova starting at offset start.
 func (s *ProxyNova) fetchPage(ctx context.Context, target string, start int, session *Session) (*ProxyNovaResponse, error) {
 	url := fmt.Sprintf("https://api.proxynova.com/comb?query=%s&start=%d&limit=100", target, start)
-	logger.Debugf("Sending a request in ProxyNova source for %s (start=%d)", target, start)
+	session.Log().Debugf("Sending a request in ProxyNova source for %s (start=%d)", target, start)
 
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
@@ -100,7 +105,7 @@ func (s *ProxyNova) fetchPage(ctx context.Context, target string, start int, ses
 	if err != nil {
 		return nil, err
 	}
-	logger.Debugf("Response from ProxyNova source: status code [%d], size [%d]", resp.StatusCode, len(body))
+	session.Log().Debugf("Response from ProxyNova source: status code [%d], size [%d]", resp.StatusCode, len(body))
 
 	var response ProxyNovaResponse
 	if err := json.Unmarshal(body, &response); err != nil {
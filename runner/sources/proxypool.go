@@ -0,0 +1,178 @@
+package sources
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// ProxyRotation selects how often a pooled session switches proxies.
+type ProxyRotation string
+
+const (
+	// RotatePerRequest sends every HTTP request through the next proxy.
+	RotatePerRequest ProxyRotation = "request"
+	// RotatePerTarget keeps one proxy for all requests of a target and only
+	// switches when that proxy gets evicted.
+	RotatePerTarget ProxyRotation = "target"
+)
+
+// DefaultProxyCooldown is how long a failing proxy stays evicted.
+const DefaultProxyCooldown = time.Minute
+
+// ProxyPool rotates requests through a list of proxies. Proxies that time
+// out or fail to connect are evicted for a cooldown period; when every
+// proxy is evicted the one whose cooldown ends first is used rather than
+// stalling the run or falling back to a direct connection.
+type ProxyPool struct {
+	mu       sync.Mutex
+	proxies  []*pooledProxy
+	next     int
+	cooldown time.Duration
+	now      func() time.Time
+}
+
+type pooledProxy struct {
+	url          *url.URL
+	requests     int
+	failures     int
+	evictions    int
+	evictedUntil time.Time
+}
+
+// ProxyStats is a health snapshot of one pooled proxy.
+type ProxyStats struct {
+	Proxy     string // redacted proxy URL
+	Requests  int
+	Failures  int
+	Evictions int
+	Evicted   bool
+}
+
+// NewProxyPool creates a pool over the given proxies. A non-positive
+// cooldown falls back to DefaultProxyCooldown.
+func NewProxyPool(proxies []*url.URL, cooldown time.Duration) (*ProxyPool, error) {
+	if len(proxies) == 0 {
+		return nil, fmt.Errorf("proxy pool is empty")
+	}
+	if cooldown <= 0 {
+		cooldown = DefaultProxyCooldown
+	}
+	pool := &ProxyPool{cooldown: cooldown, now: time.Now}
+	for _, u := range proxies {
+		pool.proxies = append(pool.proxies, &pooledProxy{url: u})
+	}
+	return pool, nil
+}
+
+// Next returns the next available proxy in round-robin order.
+func (p *ProxyPool) Next() *url.URL {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	for i := 0; i < len(p.proxies); i++ {
+		candidate := p.proxies[(p.next+i)%len(p.proxies)]
+		if !now.Before(candidate.evictedUntil) {
+			p.next = (p.next + i + 1) % len(p.proxies)
+			return candidate.url
+		}
+	}
+
+	// Every proxy is evicted: use the one that recovers first.
+	soonest := p.proxies[0]
+	for _, candidate := range p.proxies[1:] {
+		if candidate.evictedUntil.Before(soonest.evictedUntil) {
+			soonest = candidate
+		}
+	}
+	return soonest.url
+}
+
+// Evicted reports whether the proxy is currently evicted.
+func (p *ProxyPool) Evicted(u *url.URL) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if proxy := p.find(u); proxy != nil {
+		return p.now().Before(proxy.evictedUntil)
+	}
+	return false
+}
+
+// Report records the outcome of a request sent through the proxy. Timeouts
+// and connection errors evict the proxy for the cooldown period.
+func (p *ProxyPool) Report(u *url.URL, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	proxy := p.find(u)
+	if proxy == nil {
+		return
+	}
+	proxy.requests++
+	if !isProxyFailure(err) {
+		return
+	}
+	proxy.failures++
+	now := p.now()
+	if !now.Before(proxy.evictedUntil) {
+		proxy.evictions++
+	}
+	proxy.evictedUntil = now.Add(p.cooldown)
+}
+
+// Stats returns a health snapshot of every proxy in pool order.
+func (p *ProxyPool) Stats() []ProxyStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	stats := make([]ProxyStats, 0, len(p.proxies))
+	for _, proxy := range p.proxies {
+		stats = append(stats, ProxyStats{
+			Proxy:     proxy.url.Redacted(),
+			Requests:  proxy.requests,
+			Failures:  proxy.failures,
+			Evictions: proxy.evictions,
+			Evicted:   now.Before(proxy.evictedUntil),
+		})
+	}
+	return stats
+}
+
+func (p *ProxyPool) find(u *url.URL) *pooledProxy {
+	for _, proxy := range p.proxies {
+		if proxy.url == u {
+			return proxy
+		}
+	}
+	return nil
+}
+
+// isProxyFailure reports whether a transport error points at the proxy
+// rather than the upstream API: timeouts, refused or reset connections and
+// failed proxy handshakes. Cancellation by the caller is not a failure.
+func isProxyFailure(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// proxyContextKey carries the proxy chosen for a request from
+// CustomTransport.RoundTrip to the http.Transport Proxy hook.
+type proxyContextKey struct{}
+
+func proxyFromContext(ctx context.Context) *url.URL {
+	u, _ := ctx.Value(proxyContextKey{}).(*url.URL)
+	return u
+}
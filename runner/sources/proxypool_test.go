@@ -0,0 +1,175 @@
+package sources
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func mustProxyURLs(t *testing.T, raw ...string) []*url.URL {
+	t.Helper()
+	var out []*url.URL
+	for _, r := range raw {
+		u, err := ParseProxyURL(r)
+		if err != nil {
+			t.Fatalf("ParseProxyURL(%q): %v", r, err)
+		}
+		out = append(out, u)
+	}
+	return out
+}
+
+func TestProxyPool_RoundRobinAndEviction(t *testing.T) {
+	proxies := mustProxyURLs(t, "http://a.example:1", "http://b.example:2", "http://c.example:3")
+	pool, err := NewProxyPool(proxies, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1000, 0)
+	pool.now = func() time.Time { return now }
+
+	for i, want := range []*url.URL{proxies[0], proxies[1], proxies[2], proxies[0]} {
+		if got := pool.Next(); got != want {
+			t.Fatalf("Next #%d = %s, want %s", i, got, want)
+		}
+	}
+
+	pool.Report(proxies[1], &net.OpError{Op: "dial", Err: errors.New("connection refused")})
+	if !pool.Evicted(proxies[1]) {
+		t.Fatal("expected proxy b to be evicted after a connection error")
+	}
+	for i := 0; i < 4; i++ {
+		if got := pool.Next(); got == proxies[1] {
+			t.Fatal("evicted proxy was handed out")
+		}
+	}
+
+	now = now.Add(time.Minute)
+	if pool.Evicted(proxies[1]) {
+		t.Error("expected proxy b to return after the cooldown")
+	}
+
+	stats := pool.Stats()
+	if stats[1].Requests != 1 || stats[1].Failures != 1 || stats[1].Evictions != 1 || stats[1].Evicted {
+		t.Errorf("unexpected stats for b: %+v", stats[1])
+	}
+}
+
+func TestProxyPool_AllEvictedUsesSoonestRecovery(t *testing.T) {
+	proxies := mustProxyURLs(t, "http://a.example:1", "http://b.example:2")
+	pool, _ := NewProxyPool(proxies, time.Minute)
+	now := time.Unix(1000, 0)
+	pool.now = func() time.Time { return now }
+
+	pool.Report(proxies[1], context.DeadlineExceeded)
+	now = now.Add(time.Second)
+	pool.Report(proxies[0], context.DeadlineExceeded)
+
+	if got := pool.Next(); got != proxies[1] {
+		t.Errorf("expected the proxy evicted first, got %s", got)
+	}
+}
+
+func TestProxyPool_IgnoresNonProxyErrors(t *testing.T) {
+	proxies := mustProxyURLs(t, "http://a.example:1")
+	pool, _ := NewProxyPool(proxies, time.Minute)
+
+	pool.Report(proxies[0], nil)
+	pool.Report(proxies[0], context.Canceled)
+	pool.Report(proxies[0], errors.New("tls: bad certificate"))
+	if pool.Evicted(proxies[0]) {
+		t.Error("expected the proxy to stay in the pool")
+	}
+	if stats := pool.Stats(); stats[0].Requests != 3 || stats[0].Failures != 0 {
+		t.Errorf("unexpected stats: %+v", stats[0])
+	}
+}
+
+func TestNewProxyPool_Empty(t *testing.T) {
+	if _, err := NewProxyPool(nil, time.Minute); err == nil {
+		t.Error("expected an error for an empty pool")
+	}
+}
+
+func TestPoolSession_SkipsDeadProxy(t *testing.T) {
+	// A listener that is closed immediately gives a refused connection.
+	dead, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadURL := "http://" + dead.Addr().String()
+	_ = dead.Close()
+
+	hits := 0
+	live := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer live.Close()
+
+	proxies := mustProxyURLs(t, deadURL, live.URL)
+	pool, _ := NewProxyPool(proxies, time.Minute)
+	session := NewPoolSession(5*time.Second, "leaker-test", pool, RotatePerRequest, false)
+	defer session.Close()
+
+	var failures int
+	for i := 0; i < 4; i++ {
+		req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "http://leak.example/", nil)
+		resp, err := session.Client.Do(req)
+		if err != nil {
+			failures++
+			continue
+		}
+		session.DiscardHTTPResponse(resp)
+	}
+
+	if failures != 1 || hits != 3 {
+		t.Errorf("expected one failure on the dead proxy then the live one, got %d failures and %d hits", failures, hits)
+	}
+	if !pool.Evicted(proxies[0]) {
+		t.Error("expected the dead proxy to be evicted")
+	}
+}
+
+func TestPoolSession_PerTargetKeepsProxy(t *testing.T) {
+	var seen []string
+	handler := func(name string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			seen = append(seen, name)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}
+	a := httptest.NewServer(handler("a"))
+	defer a.Close()
+	b := httptest.NewServer(handler("b"))
+	defer b.Close()
+
+	pool, _ := NewProxyPool(mustProxyURLs(t, a.URL, b.URL), time.Minute)
+	for target := 0; target < 2; target++ {
+		session := NewPoolSession(5*time.Second, "leaker-test", pool, RotatePerTarget, false)
+		for i := 0; i < 2; i++ {
+			req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, "http://leak.example/", nil)
+			resp, err := session.Client.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			session.DiscardHTTPResponse(resp)
+		}
+		session.Close()
+	}
+
+	want := []string{"a", "a", "b", "b"}
+	if len(seen) != len(want) {
+		t.Fatalf("expected %v, got %v", want, seen)
+	}
+	for i := range want {
+		if seen[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, seen)
+		}
+	}
+}
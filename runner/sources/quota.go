@@ -0,0 +1,144 @@
+package sources
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// QuotaTracker records the credits paid sources consume and the balances
+// they report, per source and API key. Sources feed it through
+// Session.Quota; a nil tracker ignores every call so sources never need
+// to check for one.
+type QuotaTracker struct {
+	mu     sync.Mutex
+	usage  map[quotaKey]*QuotaUsage
+	limits map[string]int
+	spent  map[string]int
+}
+
+type quotaKey struct {
+	source string
+	keyID  string
+}
+
+// QuotaUsage is the spend of one API key during a run.
+type QuotaUsage struct {
+	Source    string
+	KeyID     string // short fingerprint of the API key, never the key itself
+	Consumed  int    // credits consumed during this run
+	Remaining int    // last balance reported by the provider, -1 if unknown
+	Exhausted bool   // the provider refused a request for lack of credits
+}
+
+// NewQuotaTracker creates a tracker. limits caps the credits each source
+// may consume during the run; sources without an entry are unlimited.
+func NewQuotaTracker(limits map[string]int) *QuotaTracker {
+	return &QuotaTracker{
+		usage:  make(map[quotaKey]*QuotaUsage),
+		limits: limits,
+		spent:  make(map[string]int),
+	}
+}
+
+// QuotaKeyID returns a short, stable fingerprint of an API key that is
+// safe to print and persist.
+func QuotaKeyID(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(sum[:4])
+}
+
+// Allow returns an error wrapping ErrBudgetExceeded once the source has
+// consumed its --max-credits allowance. Sources call it before every
+// request that costs credits.
+func (q *QuotaTracker) Allow(source string) error {
+	if q == nil {
+		return nil
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	limit, ok := q.limits[source]
+	if ok && q.spent[source] >= limit {
+		return fmt.Errorf("%w: credit limit of %d reached", ErrBudgetExceeded, limit)
+	}
+	return nil
+}
+
+// Consume records credits spent with apiKey.
+func (q *QuotaTracker) Consume(source, apiKey string, credits int) {
+	if q == nil || credits <= 0 {
+		return
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.entry(source, apiKey).Consumed += credits
+	q.spent[source] += credits
+}
+
+// SetRemaining records the balance the provider reported for apiKey.
+func (q *QuotaTracker) SetRemaining(source, apiKey string, remaining int) {
+	if q == nil {
+		return
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.entry(source, apiKey).Remaining = remaining
+}
+
+// MarkExhausted records that the provider refused apiKey for lack of
+// credits.
+func (q *QuotaTracker) MarkExhausted(source, apiKey string) {
+	if q == nil {
+		return
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	e := q.entry(source, apiKey)
+	e.Exhausted = true
+	e.Remaining = 0
+}
+
+// Consumed returns the credits the source consumed during the run, across
+// all of its keys.
+func (q *QuotaTracker) Consumed(source string) int {
+	if q == nil {
+		return 0
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.spent[source]
+}
+
+// Usage returns a snapshot of every tracked key, sorted by source and key.
+func (q *QuotaTracker) Usage() []QuotaUsage {
+	if q == nil {
+		return nil
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	usage := make([]QuotaUsage, 0, len(q.usage))
+	for _, u := range q.usage {
+		usage = append(usage, *u)
+	}
+	sort.Slice(usage, func(i, j int) bool {
+		if usage[i].Source != usage[j].Source {
+			return usage[i].Source < usage[j].Source
+		}
+		return usage[i].KeyID < usage[j].KeyID
+	})
+	return usage
+}
+
+func (q *QuotaTracker) entry(source, apiKey string) *QuotaUsage {
+	k := quotaKey{source: source, keyID: QuotaKeyID(apiKey)}
+	e, ok := q.usage[k]
+	if !ok {
+		e = &QuotaUsage{Source: source, KeyID: k.keyID, Remaining: -1}
+		q.usage[k] = e
+	}
+	return e
+}
@@ -0,0 +1,56 @@
+package sources
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestQuotaTracker_AllowStopsAtLimit(t *testing.T) {
+	q := NewQuotaTracker(map[string]int{"dehashed": 2})
+	if err := q.Allow("dehashed"); err != nil {
+		t.Fatalf("unexpected error before spending: %v", err)
+	}
+	q.Consume("dehashed", "key-1", 1)
+	q.Consume("dehashed", "key-2", 1)
+	if err := q.Allow("dehashed"); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("expected the credit limit to be reached, got %v", err)
+	}
+	if err := q.Allow("leakcheck"); err != nil {
+		t.Errorf("expected sources without a limit to be allowed, got %v", err)
+	}
+	if got := q.Consumed("dehashed"); got != 2 {
+		t.Errorf("expected 2 credits consumed, got %d", got)
+	}
+}
+
+func TestQuotaTracker_Usage(t *testing.T) {
+	q := NewQuotaTracker(nil)
+	q.Consume("leakradar", "secret-key", 5)
+	q.SetRemaining("leakradar", "secret-key", 95)
+	q.MarkExhausted("intelx", "other-key")
+
+	usage := q.Usage()
+	if len(usage) != 2 {
+		t.Fatalf("expected 2 entries, got %+v", usage)
+	}
+	if usage[0].Source != "intelx" || !usage[0].Exhausted || usage[0].Remaining != 0 {
+		t.Errorf("unexpected intelx usage: %+v", usage[0])
+	}
+	if usage[1].Consumed != 5 || usage[1].Remaining != 95 {
+		t.Errorf("unexpected leakradar usage: %+v", usage[1])
+	}
+	if strings.Contains(usage[1].KeyID, "secret") || usage[1].KeyID != QuotaKeyID("secret-key") {
+		t.Errorf("expected a key fingerprint, got %q", usage[1].KeyID)
+	}
+}
+
+func TestQuotaTracker_NilIsNoop(t *testing.T) {
+	var q *QuotaTracker
+	q.Consume("dehashed", "key", 1)
+	q.SetRemaining("dehashed", "key", 1)
+	q.MarkExhausted("dehashed", "key")
+	if err := q.Allow("dehashed"); err != nil || q.Consumed("dehashed") != 0 || q.Usage() != nil {
+		t.Error("expected a nil tracker to ignore every call")
+	}
+}
@@ -0,0 +1,57 @@
+package sources
+
+import "slices"
+
+// Factory creates a fresh, unconfigured instance of a source.
+type Factory func() Source
+
+// registry holds the factory of every available source. LocalDB is
+// included so --list-sources discovers it, but the runner excludes it from
+// the default `-s online` resolution.
+var registry = []Factory{
+	func() Source { return &BreachDirectory{} },
+	func() Source { return &DeHashed{} },
+	func() Source { return &HIBP{} },
+	func() Source { return &HudsonRock{} },
+	func() Source { return &IntelX{} },
+	func() Source { return &LeakCheck{} },
+	func() Source { return &LeakRadar{} },
+	func() Source { return &LeakLookup{} },
+	func() Source { return &LeakSight{} },
+	func() Source { return &LocalDB{} },
+	func() Source { return &OSINTLeak{} },
+	func() Source { return &ProxyNova{} },
+	func() Source { return &Snusbase{} },
+	func() Source { return &WeLeakInfo{} },
+	func() Source { return &WhiteIntel{} },
+}
+
+// NewSources returns a fresh instance of every available source, so keys
+// and other configuration never leak between runners or clients.
+func NewSources() []Source {
+	all := make([]Source, 0, len(registry))
+	for _, factory := range registry {
+		all = append(all, factory())
+	}
+	return all
+}
+
+// NewSource returns a fresh instance of the named source.
+func NewSource(name string) (Source, bool) {
+	for _, factory := range registry {
+		if s := factory(); s.Name() == name {
+			return s, true
+		}
+	}
+	return nil, false
+}
+
+// SourceNames returns the sorted names of every available source.
+func SourceNames() []string {
+	names := make([]string, 0, len(registry))
+	for _, factory := range registry {
+		names = append(names, factory().Name())
+	}
+	slices.Sort(names)
+	return names
+}
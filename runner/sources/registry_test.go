@@ -0,0 +1,32 @@
+package sources
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNewSource_ReturnsFreshInstances(t *testing.T) {
+	first, ok := NewSource("leakcheck")
+	if !ok {
+		t.Fatal("expected leakcheck to be registered")
+	}
+	second, _ := NewSource("leakcheck")
+	first.AddApiKeys([]string{"key"})
+
+	if len(second.(*LeakCheck).apiKeys) != 0 {
+		t.Error("expected keys added to one instance not to reach another")
+	}
+	if _, ok := NewSource("nope"); ok {
+		t.Error("expected an unknown source to be rejected")
+	}
+}
+
+func TestSourceNames_MatchNewSources(t *testing.T) {
+	names := SourceNames()
+	if !slices.IsSorted(names) || !slices.Contains(names, LocalSourceName) {
+		t.Errorf("unexpected names %v", names)
+	}
+	if len(NewSources()) != len(names) {
+		t.Errorf("expected %d sources, got %d", len(names), len(NewSources()))
+	}
+}
@@ -1,9 +1,8 @@
 package sources
 
 import (
+	"context"
 	"crypto/tls"
-	"fmt"
-	"github.com/vflame6/leaker/logger"
 	"io"
 	"net"
 	"net/http"
@@ -12,29 +11,21 @@ import (
 )
 
 // NewSession creates a new session object for an email.
+// proxy may be an http, https, socks5 or socks5h URL, optionally with
+// credentials; an invalid proxy is an error rather than a direct connection.
 // Set insecure=true only when the caller explicitly opts in via --insecure;
 // by default TLS certificates are verified.
 func NewSession(timeout time.Duration, userAgent, proxy string, insecure bool) (*Session, error) {
-	tr := &http.Transport{
-		MaxIdleConns:        100,
-		MaxIdleConnsPerHost: 100,
-		TLSClientConfig: &tls.Config{
-			InsecureSkipVerify: insecure, //nolint:gosec // controlled by --insecure flag
-		},
-		Dial: (&net.Dialer{
-			Timeout: timeout,
-		}).Dial,
-	}
+	tr := newHTTPTransport(timeout, insecure)
 
 	// Add proxy
 	// will raise an error if could not validate to ensure user's privacy
 	if proxy != "" {
-		proxyURL, _ := url.Parse(proxy)
-		if proxyURL == nil {
-			return nil, fmt.Errorf("invalid proxy provided: %s", proxy)
-		} else {
-			tr.Proxy = http.ProxyURL(proxyURL)
+		proxyURL, err := ParseProxyURL(proxy)
+		if err != nil {
+			return nil, err
 		}
+		tr.Proxy = http.ProxyURL(proxyURL)
 	}
 
 	customTransport := &CustomTransport{
@@ -42,14 +33,47 @@ func NewSession(timeout time.Duration, userAgent, proxy string, insecure bool) (
 		UserAgent: userAgent,
 	}
 
-	client := &http.Client{
-		Transport: customTransport,
-		Timeout:   timeout,
+	return newSession(customTransport, timeout), nil
+}
+
+// NewPoolSession creates a session that routes every request through a
+// proxy taken from pool, switching proxies per request or per session
+// (one session is used per target) depending on rotation.
+func NewPoolSession(timeout time.Duration, userAgent string, pool *ProxyPool, rotation ProxyRotation, insecure bool) *Session {
+	tr := newHTTPTransport(timeout, insecure)
+	tr.Proxy = func(req *http.Request) (*url.URL, error) {
+		return proxyFromContext(req.Context()), nil
 	}
 
-	session := &Session{Client: client}
+	customTransport := &CustomTransport{
+		Transport: tr,
+		UserAgent: userAgent,
+		pool:      pool,
+		rotation:  rotation,
+	}
+
+	return newSession(customTransport, timeout)
+}
+
+func newHTTPTransport(timeout time.Duration, insecure bool) *http.Transport {
+	return &http.Transport{
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 100,
+		TLSClientConfig: &tls.Config{
+			InsecureSkipVerify: insecure, //nolint:gosec // controlled by --insecure flag
+		},
+		Dial: (&net.Dialer{
+			Timeout: timeout,
+		}).Dial,
+	}
+}
 
-	return session, nil
+func newSession(transport *CustomTransport, timeout time.Duration) *Session {
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+	}
+	return &Session{Client: client}
 }
 
 // DiscardHTTPResponse discards the response content by demand
@@ -57,11 +81,11 @@ func (s *Session) DiscardHTTPResponse(response *http.Response) {
 	if response != nil {
 		_, err := io.Copy(io.Discard, response.Body)
 		if err != nil {
-			logger.Errorf("Could not discard response body: %s\n", err)
+			s.Log().Errorf("Could not discard response body: %s\n", err)
 			return
 		}
 		if closeErr := response.Body.Close(); closeErr != nil {
-			logger.Errorf("Could not close response body: %s\n", closeErr)
+			s.Log().Errorf("Could not close response body: %s\n", closeErr)
 		}
 	}
 }
@@ -74,12 +98,41 @@ func (s *Session) Close() {
 // RoundTrip implements the http.RoundTripper interface.
 // custom one is needed to specify user agent string.
 func (t *CustomTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.checkRequestBudget(req); err != nil {
+		if req.Body != nil {
+			_ = req.Body.Close()
+		}
+		return nil, err
+	}
+
 	// Set the User-Agent header on the request.
 	req.Header.Set("User-Agent", t.UserAgent)
 	// set other headers
 	req.Header.Set("Accept", "*/*")
 	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
 	req.Header.Set("Connection", "close")
+
+	if t.pool != nil {
+		proxyURL := t.nextProxy()
+		req = req.WithContext(context.WithValue(req.Context(), proxyContextKey{}, proxyURL))
+		resp, err := t.Transport.RoundTrip(req)
+		t.pool.Report(proxyURL, err)
+		return resp, err
+	}
+
 	// Use the underlying transport to perform the actual request.
 	return t.Transport.RoundTrip(req)
 }
+
+// nextProxy picks the pooled proxy for a request.
+func (t *CustomTransport) nextProxy() *url.URL {
+	if t.rotation != RotatePerTarget {
+		return t.pool.Next()
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.pinned == nil || t.pool.Evicted(t.pinned) {
+		t.pinned = t.pool.Next()
+	}
+	return t.pinned
+}
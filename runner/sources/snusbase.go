@@ -8,7 +8,6 @@ import (
 	"io"
 	"net/http"
 
-	"github.com/vflame6/leaker/logger"
 	"github.com/vflame6/leaker/utils"
 )
 
@@ -100,10 +99,30 @@ func (s *Snusbase) Run(ctx context.Context, target string, scanType ScanType, se
 			searchTypes = []string{"password"}
 		case TypePhone:
 			searchTypes = []string{"email", "username"}
+		case TypeHash:
+			searchTypes = []string{"hash"}
+		case TypeIP:
+			searchTypes = []string{"lastip"}
+		case TypeName:
+			searchTypes = []string{"name"}
+		case TypePassword:
+			searchTypes = []string{"password"}
+		default:
+			return
+		}
+
+		// Combo-lookup indexes user:pass pairs only, so it is skipped for
+		// pivots on fields combolists do not carry.
+		comboTypes := []string{"username"}
+		switch scanType {
+		case TypePassword:
+			comboTypes = []string{"password"}
+		case TypeHash, TypeIP, TypeName:
+			comboTypes = nil
 		}
 
 		// --- Step 1: Main search ---
-		logger.Debugf("Snusbase: searching for %s", target)
+		session.Log().Debugf("Snusbase: searching for %s", target)
 		searchBody, err := s.snusbasePost(ctx, session, apiKey,
 			"https://api.snusbase.com/data/search",
 			snusbaseSearchRequest{
@@ -162,19 +181,22 @@ func (s *Snusbase) Run(ctx context.Context, target string, scanType ScanType, se
 		}
 
 		// --- Step 2: Combo-lookup (reveals plaintext passwords from combolists) ---
-		// Combo-lookup always uses "username" type — emails are stored as
+		// Combo-lookup uses "username" type — emails are stored as
 		// the username field in combolists (user:pass format).
-		logger.Debugf("Snusbase: combo-lookup for %s", target)
-		comboBody, err := s.snusbasePost(ctx, session, apiKey,
-			"https://api.snusbase.com/tools/combo-lookup",
-			snusbaseSearchRequest{
-				Terms:   []string{target},
-				Types:   []string{"username"},
-				GroupBy: "db",
-			})
+		var comboBody []byte
+		if len(comboTypes) > 0 {
+			session.Log().Debugf("Snusbase: combo-lookup for %s", target)
+			comboBody, err = s.snusbasePost(ctx, session, apiKey,
+				"https://api.snusbase.com/tools/combo-lookup",
+				snusbaseSearchRequest{
+					Terms:   []string{target},
+					Types:   comboTypes,
+					GroupBy: "db",
+				})
+		}
 		if err != nil {
-			logger.Debugf("Snusbase combo-lookup error: %v", err)
-		} else {
+			session.Log().Debugf("Snusbase combo-lookup error: %v", err)
+		} else if comboBody != nil {
 			var comboResp snusbaseSearchResponse
 			if err := json.Unmarshal(comboBody, &comboResp); err == nil {
 				for dbName, records := range comboResp.Results {
@@ -206,7 +228,7 @@ func (s *Snusbase) Run(ctx context.Context, target string, scanType ScanType, se
 			for h := range hashSet {
 				hashes = append(hashes, h)
 			}
-			logger.Debugf("Snusbase: hash-lookup for %d hash(es)", len(hashes))
+			session.Log().Debugf("Snusbase: hash-lookup for %d hash(es)", len(hashes))
 			hashBody, err := s.snusbasePost(ctx, session, apiKey,
 				"https://api.snusbase.com/tools/hash-lookup",
 				map[string]interface{}{
@@ -215,7 +237,7 @@ func (s *Snusbase) Run(ctx context.Context, target string, scanType ScanType, se
 					"group_by": false,
 				})
 			if err != nil {
-				logger.Debugf("Snusbase hash-lookup error: %v", err)
+				session.Log().Debugf("Snusbase hash-lookup error: %v", err)
 			} else {
 				var hashResp snusbaseHashLookupResponse
 				if err := json.Unmarshal(hashBody, &hashResp); err == nil {
@@ -246,14 +268,14 @@ func (s *Snusbase) Run(ctx context.Context, target string, scanType ScanType, se
 			for ip := range ipSet {
 				ips = append(ips, ip)
 			}
-			logger.Debugf("Snusbase: ip-whois for %d IP(s)", len(ips))
+			session.Log().Debugf("Snusbase: ip-whois for %d IP(s)", len(ips))
 			whoisBody, err := s.snusbasePost(ctx, session, apiKey,
 				"https://api.snusbase.com/tools/ip-whois",
 				map[string]interface{}{
 					"terms": ips,
 				})
 			if err != nil {
-				logger.Debugf("Snusbase ip-whois error: %v", err)
+				session.Log().Debugf("Snusbase ip-whois error: %v", err)
 			} else {
 				var whoisResp snusbaseIPWhoisResponse
 				if err := json.Unmarshal(whoisBody, &whoisResp); err == nil {
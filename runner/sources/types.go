@@ -5,8 +5,13 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"net/http"
+	"net/url"
 	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/vflame6/leaker/logger"
 )
 
 type Source interface {
@@ -27,6 +32,13 @@ type Source interface {
 	RateLimit() int
 }
 
+// KeyChecker is implemented by sources whose API keys have a format that
+// can be checked before they are added. AddApiKeys skips invalid keys
+// with a warning.
+type KeyChecker interface {
+	CheckApiKey(key string) error
+}
+
 // Result represents a single leak result from a source.
 type Result struct {
 	Source   string
@@ -222,13 +234,36 @@ func (r *Result) Contains(target string) bool {
 }
 
 // CustomTransport wraps http.Transport and adds a default User-Agent header.
+// With a proxy pool it also picks the proxy for each request and reports
+// the outcome back to the pool.
 type CustomTransport struct {
 	Transport http.RoundTripper
 	UserAgent string
+
+	pool     *ProxyPool
+	rotation ProxyRotation
+	mu       sync.Mutex
+	pinned   *url.URL // proxy used for every request with RotatePerTarget
+
+	maxRequests int64 // request allowance set by LimitRequests, 0 = unlimited
+	requests    atomic.Int64
 }
 
 type Session struct {
 	Client *http.Client
+	// Quota receives credit spend from paid sources. May be nil.
+	Quota *QuotaTracker
+	// Logger receives the sources' log messages. Nil uses the package
+	// logger.
+	Logger *logger.Logger
+}
+
+// Log returns the logger of the session.
+func (s *Session) Log() *logger.Logger {
+	if s.Logger == nil {
+		return logger.DefaultLogger
+	}
+	return s.Logger
 }
 
 // ScanType is the type of scan performed by the source
@@ -241,4 +276,54 @@ const (
 	TypeDomain
 	TypeKeyword
 	TypePhone
+	TypeHash
+	TypeIP
+	TypeName
+	TypePassword
 )
+
+// String returns the CLI command name of the scan type.
+func (t ScanType) String() string {
+	switch t {
+	case TypeEmail:
+		return "email"
+	case TypeUsername:
+		return "username"
+	case TypeDomain:
+		return "domain"
+	case TypeKeyword:
+		return "keyword"
+	case TypePhone:
+		return "phone"
+	case TypeHash:
+		return "hash"
+	case TypeIP:
+		return "ip"
+	case TypeName:
+		return "name"
+	case TypePassword:
+		return "password"
+	}
+	return "unknown"
+}
+
+// ParseScanType returns the scan type named by its CLI command name.
+func ParseScanType(name string) (ScanType, bool) {
+	for t := TypeEmail; t <= TypePassword; t++ {
+		if t.String() == name {
+			return t, true
+		}
+	}
+	return 0, false
+}
+
+// IsPivot reports whether the scan type searches by a single leaked field
+// (hash, IP address, real name or password). Only sources that index the
+// matching field support these; every other source skips them.
+func (t ScanType) IsPivot() bool {
+	switch t {
+	case TypeHash, TypeIP, TypeName, TypePassword:
+		return true
+	}
+	return false
+}
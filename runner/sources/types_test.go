@@ -178,3 +178,15 @@ func TestResult_Checksum_Cached(t *testing.T) {
 		t.Fatalf("Checksum should be cached after first call: %q vs %q", first, second)
 	}
 }
+
+func TestParseScanType_RoundTrips(t *testing.T) {
+	for st := TypeEmail; st <= TypePassword; st++ {
+		got, ok := ParseScanType(st.String())
+		if !ok || got != st {
+			t.Errorf("ParseScanType(%q) = %v, %v", st.String(), got, ok)
+		}
+	}
+	if _, ok := ParseScanType("subdomain"); ok {
+		t.Error("expected an unknown scan type to be rejected")
+	}
+}
@@ -7,25 +7,35 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
 
-	"github.com/vflame6/leaker/logger"
 	"github.com/vflame6/leaker/utils"
 )
 
+const weLeakInfoDefaultBaseURL = "https://api.weleakinfo.io"
+
+// weLeakInfoPageSize is the number of records requested per page.
+const weLeakInfoPageSize = 1000
+
 type WeLeakInfo struct {
+	pageLimits
 	apiKeys []string
+	baseURL string
 }
 
 type weLeakInfoRequest struct {
 	Query    string `json:"query"`
 	Type     string `json:"type"`
 	Limit    string `json:"limit"`
+	Offset   string `json:"offset"`
 	Wildcard string `json:"wildcard"`
 }
 
 type weLeakInfoResponse struct {
-	Data []map[string]interface{} `json:"Data"`
+	// Total is sent as either a number or a numeric string.
+	Total json.Number              `json:"Total"`
+	Data  []map[string]interface{} `json:"Data"`
 }
 
 func (s *WeLeakInfo) Run(ctx context.Context, target string, scanType ScanType, session *Session) <-chan Result {
@@ -47,7 +57,7 @@ func (s *WeLeakInfo) Run(ctx context.Context, target string, scanType ScanType,
 
 		searchReq := weLeakInfoRequest{
 			Query:    target,
-			Limit:    "1000",
+			Limit:    strconv.Itoa(weLeakInfoPageSize),
 			Wildcard: "false",
 		}
 
@@ -62,88 +72,120 @@ func (s *WeLeakInfo) Run(ctx context.Context, target string, scanType ScanType,
 			searchReq.Wildcard = "true"
 		case TypeKeyword:
 			searchReq.Type = "password"
-		default:
-			// Phone and any other types — fall back to username search
+		case TypePhone:
+			// Phone numbers are not indexed separately — fall back to username search
 			searchReq.Type = "username"
-		}
-
-		body, err := json.Marshal(searchReq)
-		if err != nil {
-			results <- Result{Source: s.Name(), Error: err}
-			return
-		}
-
-		req, err := http.NewRequestWithContext(ctx, "POST", "https://api.weleakinfo.io/v3/search",
-			bytes.NewReader(body))
-		if err != nil {
-			results <- Result{Source: s.Name(), Error: err}
-			return
-		}
-		req.Header.Set("Authorization", "Bearer "+bearerToken)
-		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set("Accept", "application/json")
-
-		logger.Debugf("Sending a request in WeLeakInfo source for %s", target)
-		resp, err := session.Client.Do(req)
-		if err != nil {
-			results <- Result{Source: s.Name(), Error: err}
-			return
-		}
-		defer session.DiscardHTTPResponse(resp)
-
-		respBody, err := io.ReadAll(resp.Body)
-		if err != nil {
-			results <- Result{Source: s.Name(), Error: err}
+		default:
 			return
 		}
-		logger.Debugf("Response from WeLeakInfo source: status code [%d], size [%d]", resp.StatusCode, len(respBody))
 
-		if resp.StatusCode != http.StatusOK {
-			results <- Result{
-				Source: s.Name(),
-				Error:  fmt.Errorf("WeLeakInfo returned status %d: %s", resp.StatusCode, string(respBody)),
+		fetched, total := 0, 0
+		lastPageFull := false
+		for page := 1; page <= s.pageCap() && fetched < s.resultCap(); page++ {
+			if page > 1 && !waitNextPage(ctx, s.RateLimit()) {
+				return
 			}
-			return
-		}
+			searchReq.Offset = strconv.Itoa(fetched)
 
-		var response weLeakInfoResponse
-		if err := json.Unmarshal(respBody, &response); err != nil {
-			results <- Result{Source: s.Name(), Error: err}
-			return
-		}
-
-		for _, record := range response.Data {
-			r := Result{Source: s.Name()}
-			if val, ok := record["email"].(string); ok && val != "" {
-				r.Email = val
-			}
-			if val, ok := record["username"].(string); ok && val != "" {
-				r.Username = val
-			}
-			if val, ok := record["password"].(string); ok && val != "" {
-				r.Password = val
+			session.Log().Debugf("Sending a request in WeLeakInfo source for %s (page %d)", target, page)
+			response, err := s.search(ctx, session, bearerToken, searchReq)
+			if err != nil {
+				results <- Result{Source: s.Name(), Error: err}
+				return
 			}
-			if val, ok := record["hash"].(string); ok && val != "" {
-				r.Hash = val
+			if n, err := response.Total.Int64(); err == nil {
+				total = int(n)
 			}
-			if val, ok := record["name"].(string); ok && val != "" {
-				r.Name = val
+			lastPageFull = len(response.Data) == weLeakInfoPageSize
+
+			for _, record := range response.Data {
+				if fetched >= s.resultCap() {
+					break
+				}
+				fetched++
+				r := Result{Source: s.Name()}
+				if val, ok := record["email"].(string); ok && val != "" {
+					r.Email = val
+				}
+				if val, ok := record["username"].(string); ok && val != "" {
+					r.Username = val
+				}
+				if val, ok := record["password"].(string); ok && val != "" {
+					r.Password = val
+				}
+				if val, ok := record["hash"].(string); ok && val != "" {
+					r.Hash = val
+				}
+				if val, ok := record["name"].(string); ok && val != "" {
+					r.Name = val
+				}
+				if val, ok := record["ip"].(string); ok && val != "" {
+					r.IP = val
+				}
+				if val, ok := record["phone"].(string); ok && val != "" {
+					r.Phone = val
+				}
+				if r.HasData() {
+					results <- r
+				}
 			}
-			if val, ok := record["ip"].(string); ok && val != "" {
-				r.IP = val
-			}
-			if val, ok := record["phone"].(string); ok && val != "" {
-				r.Phone = val
-			}
-			if r.HasData() {
-				results <- r
+
+			if !lastPageFull || (total > 0 && fetched >= total) {
+				break
 			}
 		}
+		warnTruncated(session.Log(), "WeLeakInfo", target, total, fetched, lastPageFull)
 	}()
 
 	return results
 }
 
+// search requests a single page of results.
+func (s *WeLeakInfo) search(ctx context.Context, session *Session, bearerToken string, searchReq weLeakInfoRequest) (weLeakInfoResponse, error) {
+	body, err := json.Marshal(searchReq)
+	if err != nil {
+		return weLeakInfoResponse{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", s.apiBaseURL()+"/v3/search",
+		bytes.NewReader(body))
+	if err != nil {
+		return weLeakInfoResponse{}, err
+	}
+	req.Header.Set("Authorization", "Bearer "+bearerToken)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := session.Client.Do(req)
+	if err != nil {
+		return weLeakInfoResponse{}, err
+	}
+	defer session.DiscardHTTPResponse(resp)
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return weLeakInfoResponse{}, err
+	}
+	session.Log().Debugf("Response from WeLeakInfo source: status code [%d], size [%d]", resp.StatusCode, len(respBody))
+
+	if resp.StatusCode != http.StatusOK {
+		return weLeakInfoResponse{}, fmt.Errorf("WeLeakInfo returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var response weLeakInfoResponse
+	if err := json.Unmarshal(respBody, &response); err != nil {
+		return weLeakInfoResponse{}, err
+	}
+	return response, nil
+}
+
+func (s *WeLeakInfo) apiBaseURL() string {
+	if s.baseURL != "" {
+		return strings.TrimRight(s.baseURL, "/")
+	}
+	return weLeakInfoDefaultBaseURL
+}
+
 func (s *WeLeakInfo) Name() string {
 	return "weleakinfo"
 }
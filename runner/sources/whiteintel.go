@@ -7,13 +7,20 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 
-	"github.com/vflame6/leaker/logger"
 	"github.com/vflame6/leaker/utils"
 )
 
+const whiteIntelDefaultBaseURL = "https://api.whiteintel.io"
+
+// whiteIntelPageSize is the number of records requested per page.
+const whiteIntelPageSize = 500
+
 type WhiteIntel struct {
+	pageLimits
 	apiKeys []string
+	baseURL string
 }
 
 type whiteIntelRequest struct {
@@ -38,6 +45,7 @@ type whiteIntelResult struct {
 
 type whiteIntelResponse struct {
 	Success bool               `json:"success"`
+	Total   int                `json:"total"`
 	Results []whiteIntelResult `json:"results"`
 }
 
@@ -56,8 +64,7 @@ func (s *WhiteIntel) Run(ctx context.Context, target string, scanType ScanType,
 			APIKey: randomApiKey,
 			Query:  target,
 			Type:   "all",
-			Limit:  500,
-			Page:   1,
+			Limit:  whiteIntelPageSize,
 		}
 
 		switch scanType {
@@ -68,93 +75,119 @@ func (s *WhiteIntel) Run(ctx context.Context, target string, scanType ScanType,
 		case TypeUsername:
 			searchReq.Query = target
 			searchReq.Username = target
-		default:
-			// Keyword, phone — fall back to username search
+		case TypeKeyword, TypePhone:
+			// Fall back to username search
 			searchReq.Query = target
 			searchReq.Username = target
-		}
-
-		body, err := json.Marshal(searchReq)
-		if err != nil {
-			results <- Result{Source: s.Name(), Error: err}
-			return
-		}
-
-		req, err := http.NewRequestWithContext(ctx, "POST", "https://api.whiteintel.io/get_consumer_leaks.php",
-			bytes.NewReader(body))
-		if err != nil {
-			results <- Result{Source: s.Name(), Error: err}
-			return
-		}
-		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set("Accept", "application/json")
-
-		logger.Debugf("Sending a request in WhiteIntel source for %s", target)
-		resp, err := session.Client.Do(req)
-		if err != nil {
-			results <- Result{Source: s.Name(), Error: err}
-			return
-		}
-		defer session.DiscardHTTPResponse(resp)
-
-		respBody, err := io.ReadAll(resp.Body)
-		if err != nil {
-			results <- Result{Source: s.Name(), Error: err}
-			return
-		}
-		logger.Debugf("Response from WhiteIntel source: status code [%d], size [%d]", resp.StatusCode, len(respBody))
-
-		if resp.StatusCode != http.StatusOK {
-			results <- Result{
-				Source: s.Name(),
-				Error:  fmt.Errorf("WhiteIntel returned status %d: %s", resp.StatusCode, string(respBody)),
-			}
-			return
-		}
-
-		var response whiteIntelResponse
-		if err := json.Unmarshal(respBody, &response); err != nil {
-			results <- Result{Source: s.Name(), Error: err}
+		default:
 			return
 		}
 
-		if !response.Success {
-			results <- Result{
-				Source: s.Name(),
-				Error:  fmt.Errorf("WhiteIntel request failed: %s", string(respBody)),
+		fetched, total := 0, 0
+		lastPageFull := false
+		for page := 1; page <= s.pageCap() && fetched < s.resultCap(); page++ {
+			if page > 1 && !waitNextPage(ctx, s.RateLimit()) {
+				return
 			}
-			return
-		}
+			searchReq.Page = page
 
-		for _, record := range response.Results {
-			r := Result{
-				Source:   s.Name(),
-				Username: record.Username,
-				Password: record.Password,
-				IP:       record.IP,
-				URL:      record.URL,
+			session.Log().Debugf("Sending a request in WhiteIntel source for %s (page %d)", target, page)
+			response, err := s.search(ctx, session, searchReq)
+			if err != nil {
+				results <- Result{Source: s.Name(), Error: err}
+				return
 			}
-			if record.DataType != "" {
-				r.SetExtra("data_type", record.DataType)
+			total = response.Total
+			lastPageFull = len(response.Results) == whiteIntelPageSize
+
+			for _, record := range response.Results {
+				if fetched >= s.resultCap() {
+					break
+				}
+				fetched++
+				r := Result{
+					Source:   s.Name(),
+					Username: record.Username,
+					Password: record.Password,
+					IP:       record.IP,
+					URL:      record.URL,
+				}
+				if record.DataType != "" {
+					r.SetExtra("data_type", record.DataType)
+				}
+				if record.Hostname != "" {
+					r.SetExtra("hostname", record.Hostname)
+				}
+				if record.LogDate != "" {
+					r.SetExtra("log_date", record.LogDate)
+				}
+				if record.MalwarePath != "" {
+					r.SetExtra("malware_path", record.MalwarePath)
+				}
+				if r.HasData() {
+					results <- r
+				}
 			}
-			if record.Hostname != "" {
-				r.SetExtra("hostname", record.Hostname)
-			}
-			if record.LogDate != "" {
-				r.SetExtra("log_date", record.LogDate)
-			}
-			if record.MalwarePath != "" {
-				r.SetExtra("malware_path", record.MalwarePath)
-			}
-			if r.HasData() {
-				results <- r
+
+			if !lastPageFull || (total > 0 && fetched >= total) {
+				break
 			}
 		}
+		warnTruncated(session.Log(), "WhiteIntel", target, total, fetched, lastPageFull)
 	}()
 
 	return results
 }
 
+// search requests a single page of results.
+func (s *WhiteIntel) search(ctx context.Context, session *Session, searchReq whiteIntelRequest) (whiteIntelResponse, error) {
+	body, err := json.Marshal(searchReq)
+	if err != nil {
+		return whiteIntelResponse{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", s.apiBaseURL()+"/get_consumer_leaks.php",
+		bytes.NewReader(body))
+	if err != nil {
+		return whiteIntelResponse{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := session.Client.Do(req)
+	if err != nil {
+		return whiteIntelResponse{}, err
+	}
+	defer session.DiscardHTTPResponse(resp)
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return whiteIntelResponse{}, err
+	}
+	session.Log().Debugf("Response from WhiteIntel source: status code [%d], size [%d]", resp.StatusCode, len(respBody))
+
+	if resp.StatusCode != http.StatusOK {
+		return whiteIntelResponse{}, fmt.Errorf("WhiteIntel returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var response whiteIntelResponse
+	if err := json.Unmarshal(respBody, &response); err != nil {
+		return whiteIntelResponse{}, err
+	}
+
+	if !response.Success {
+		return whiteIntelResponse{}, fmt.Errorf("WhiteIntel request failed: %s", string(respBody))
+	}
+	return response, nil
+}
+
+func (s *WhiteIntel) apiBaseURL() string {
+	if s.baseURL != "" {
+		return strings.TrimRight(s.baseURL, "/")
+	}
+	return whiteIntelDefaultBaseURL
+}
+
 func (s *WhiteIntel) Name() string {
 	return "whiteintel"
 }
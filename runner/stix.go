@@ -0,0 +1,180 @@
+package runner
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vflame6/leaker/runner/sources"
+)
+
+// stixTimeLayout is the STIX 2.1 timestamp format: UTC, millisecond
+// precision.
+const stixTimeLayout = "2006-01-02T15:04:05.000Z"
+
+// stixNamespace is the UUIDv5 namespace STIX 2.1 defines for the
+// deterministic IDs of cyber-observable objects.
+var stixNamespace = uuid.MustParse("00abedb4-aa42-466c-9c01-fed23315a9b7")
+
+// STIXBundle is a STIX 2.1 bundle.
+type STIXBundle struct {
+	Type    string        `json:"type"`
+	ID      string        `json:"id"`
+	Objects []*STIXObject `json:"objects"`
+}
+
+// STIXObject is one of the STIX 2.1 objects leaker exports. Only the
+// properties of its type are set; x_leaker_* are custom properties.
+type STIXObject struct {
+	Type         string `json:"type"`
+	SpecVersion  string `json:"spec_version"`
+	ID           string `json:"id"`
+	CreatedByRef string `json:"created_by_ref,omitempty"`
+	Created      string `json:"created,omitempty"`
+	Modified     string `json:"modified,omitempty"`
+
+	// identity
+	Name          string `json:"name,omitempty"`
+	IdentityClass string `json:"identity_class,omitempty"`
+
+	// email-addr
+	Value        string `json:"value,omitempty"`
+	BelongsToRef string `json:"belongs_to_ref,omitempty"`
+
+	// user-account
+	UserID         string `json:"user_id,omitempty"`
+	DisplayName    string `json:"display_name,omitempty"`
+	Credential     string `json:"credential,omitempty"`
+	PasswordSHA256 string `json:"x_leaker_password_sha256,omitempty"`
+	PasswordHash   string `json:"x_leaker_password_hash,omitempty"`
+	PasswordSalt   string `json:"x_leaker_password_salt,omitempty"`
+
+	// observed-data, with the breach the observation comes from
+	FirstObserved  string   `json:"first_observed,omitempty"`
+	LastObserved   string   `json:"last_observed,omitempty"`
+	NumberObserved int      `json:"number_observed,omitempty"`
+	ObjectRefs     []string `json:"object_refs,omitempty"`
+	Labels         []string `json:"labels,omitempty"`
+	Target         string   `json:"x_leaker_target,omitempty"`
+	Source         string   `json:"x_leaker_source,omitempty"`
+	Database       string   `json:"x_leaker_database,omitempty"`
+	URL            string   `json:"x_leaker_url,omitempty"`
+}
+
+// NewSTIXBundle converts findings to a STIX 2.1 bundle: a leaker identity,
+// then for every result an email-addr and a user-account observable and
+// an observed-data object naming the source and breach database. Email
+// addresses share one object across results. User accounts with a leaked
+// credential get a random ID, so each credential stays its own observable;
+// those without one share the deterministic ID of their user_id.
+func NewSTIXBundle(findings *Findings, passwords string) *STIXBundle {
+	now := stixTime(findings.Generated)
+	identity := &STIXObject{
+		Type:          "identity",
+		SpecVersion:   "2.1",
+		ID:            "identity--" + uuid.NewString(),
+		Created:       now,
+		Modified:      now,
+		Name:          "leaker",
+		IdentityClass: "system",
+	}
+	bundle := &STIXBundle{Type: "bundle", ID: "bundle--" + uuid.NewString(), Objects: []*STIXObject{identity}}
+
+	seen := make(map[string]bool)
+	addObservable := func(object *STIXObject) {
+		if !seen[object.ID] {
+			seen[object.ID] = true
+			bundle.Objects = append(bundle.Objects, object)
+		}
+	}
+	for _, finding := range findings.Results {
+		refs := stixObservables(findings, &finding.Result, passwords, addObservable)
+		if len(refs) == 0 {
+			continue
+		}
+		bundle.Objects = append(bundle.Objects, &STIXObject{
+			Type:           "observed-data",
+			SpecVersion:    "2.1",
+			ID:             "observed-data--" + uuid.NewString(),
+			CreatedByRef:   identity.ID,
+			Created:        now,
+			Modified:       now,
+			FirstObserved:  now,
+			LastObserved:   now,
+			NumberObserved: 1,
+			ObjectRefs:     refs,
+			Labels:         []string{"credential-leak"},
+			Target:         finding.Target,
+			Source:         finding.Result.Source,
+			Database:       finding.Result.Database,
+			URL:            finding.Result.URL,
+		})
+	}
+	return bundle
+}
+
+// stixObservables adds the observables of result and returns their IDs.
+func stixObservables(findings *Findings, result *sources.Result, passwords string, add func(*STIXObject)) []string {
+	var refs []string
+	var account *STIXObject
+	if userID := cmp.Or(result.Username, result.Email, result.Phone); userID != "" {
+		account = &STIXObject{
+			Type:         "user-account",
+			SpecVersion:  "2.1",
+			UserID:       userID,
+			DisplayName:  result.Name,
+			PasswordHash: result.Hash,
+			PasswordSalt: result.Salt,
+		}
+		if password, hashed := findings.exportPassword(result.Password, passwords); hashed {
+			account.PasswordSHA256 = password
+		} else {
+			account.Credential = password
+		}
+		// STIX 2.1 derives user-account IDs from account_type, user_id and
+		// account_login only, so a deterministic ID would merge every
+		// credential of the account into one object
+		if account.Credential != "" || account.PasswordSHA256 != "" || account.PasswordHash != "" || account.PasswordSalt != "" {
+			account.ID = account.Type + "--" + uuid.NewString()
+		} else {
+			account.ID = stixObservableID(account.Type, map[string]string{"user_id": account.UserID})
+		}
+		add(account)
+		refs = append(refs, account.ID)
+	}
+	if result.Email != "" {
+		email := &STIXObject{Type: "email-addr", SpecVersion: "2.1", Value: result.Email}
+		email.ID = stixObservableID(email.Type, map[string]string{"value": email.Value})
+		if account != nil && account.UserID == result.Email {
+			email.BelongsToRef = account.ID
+		}
+		add(email)
+		refs = append(refs, email.ID)
+	}
+	return refs
+}
+
+// stixObservableID returns the deterministic UUIDv5 ID of a cyber-observable
+// object, computed over the JSON of its identifying properties with empty
+// ones left out.
+func stixObservableID(objectType string, properties map[string]string) string {
+	contributing := make(map[string]string, len(properties))
+	for key, value := range properties {
+		if value != "" {
+			contributing[key] = value
+		}
+	}
+	// encoding/json sorts map keys, which makes the serialization canonical
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	_ = encoder.Encode(contributing)
+	return objectType + "--" + uuid.NewSHA1(stixNamespace, bytes.TrimSpace(buf.Bytes())).String()
+}
+
+// stixTime formats t as a STIX timestamp.
+func stixTime(t time.Time) string {
+	return t.UTC().Format(stixTimeLayout)
+}
@@ -0,0 +1,262 @@
+package runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/vflame6/leaker/logger"
+	"github.com/vflame6/leaker/runner/sources"
+	"github.com/vflame6/leaker/utils"
+)
+
+// summaryErrorClasses are the error columns of the plain summary, in
+// order. Errors of any other class are counted under "other".
+var summaryErrorClasses = []sources.ErrorClass{
+	sources.ErrorAuth,
+	sources.ErrorRateLimit,
+	sources.ErrorTimeout,
+	sources.ErrorParse,
+}
+
+// RunSummary describes a whole run, per source.
+type RunSummary struct {
+	Targets  int             `json:"targets"`
+	Results  int             `json:"results"`
+	Failures int             `json:"failures"`
+	Duration int64           `json:"duration_ms"`
+	Sources  []SourceSummary `json:"sources"`
+}
+
+// typedRunSummary is a RunSummary closing an NDJSON search stream.
+type typedRunSummary struct {
+	Type string `json:"type"`
+	RunSummary
+}
+
+// SourceSummary holds the statistics of one source over a run.
+type SourceSummary struct {
+	Source         string                     `json:"source"`
+	TargetsQueried int                        `json:"targets_queried"`
+	Returned       int                        `json:"results_returned"`
+	Kept           int                        `json:"results_kept"`
+	Errors         map[sources.ErrorClass]int `json:"errors"`
+	StoppedEarly   int                        `json:"stopped_early"`
+	AvgLatency     int64                      `json:"avg_latency_ms"`
+	Credits        int                        `json:"credits_used"`
+
+	latency time.Duration
+}
+
+// ErrorCount returns the number of errors the source reported.
+func (s *SourceSummary) ErrorCount() int {
+	total := 0
+	for _, n := range s.Errors {
+		total += n
+	}
+	return total
+}
+
+// runStats collects per-source statistics while a run is in progress.
+// A nil *runStats ignores every call.
+type runStats struct {
+	mu       sync.Mutex
+	start    time.Time
+	targets  int
+	results  int
+	failures int
+	sources  map[string]*SourceSummary
+}
+
+func newRunStats() *runStats {
+	return &runStats{start: time.Now(), sources: make(map[string]*SourceSummary)}
+}
+
+func (s *runStats) source(name string) *SourceSummary {
+	summary, ok := s.sources[name]
+	if !ok {
+		summary = &SourceSummary{Source: name, Errors: make(map[sources.ErrorClass]int)}
+		s.sources[name] = summary
+	}
+	return summary
+}
+
+// target records that a target was enumerated.
+func (s *runStats) target() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.targets++
+}
+
+// queried records that a source finished querying one target.
+func (s *runStats) queried(name string, latency time.Duration) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	summary := s.source(name)
+	summary.TargetsQueried++
+	summary.latency += latency
+}
+
+// returned records a result as received from a source.
+func (s *runStats) returned(name string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.source(name).Returned++
+}
+
+// kept records a result that survived filtering and deduplication.
+func (s *runStats) kept(name string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.source(name).Kept++
+	s.results++
+}
+
+// failed records an error reported by a source. Budget stops are counted
+// separately since they are deliberate.
+func (s *runStats) failed(name string, err error) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	class := sources.ClassifyError(err)
+	if class != sources.ErrorBudget {
+		s.failures++
+	}
+	if name == "" {
+		return
+	}
+	summary := s.source(name)
+	if class == sources.ErrorBudget {
+		summary.StoppedEarly++
+	} else {
+		summary.Errors[class]++
+	}
+}
+
+// snapshot returns the run summary, with credits taken from quota.
+func (s *runStats) snapshot(quota *sources.QuotaTracker) RunSummary {
+	if s == nil {
+		return RunSummary{}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	summary := RunSummary{
+		Targets:  s.targets,
+		Results:  s.results,
+		Failures: s.failures,
+		Duration: time.Since(s.start).Milliseconds(),
+		Sources:  make([]SourceSummary, 0, len(s.sources)),
+	}
+	for _, src := range s.sources {
+		out := *src
+		out.Errors = make(map[sources.ErrorClass]int, len(src.Errors))
+		for class, n := range src.Errors {
+			out.Errors[class] = n
+		}
+		if out.TargetsQueried > 0 {
+			out.AvgLatency = (src.latency / time.Duration(out.TargetsQueried)).Milliseconds()
+		}
+		out.Credits = quota.Consumed(out.Source)
+		summary.Sources = append(summary.Sources, out)
+	}
+	slices.SortFunc(summary.Sources, func(a, b SourceSummary) int {
+		return strings.Compare(a.Source, b.Source)
+	})
+	return summary
+}
+
+// WritePlainSummary writes the run summary as an aligned table.
+func WritePlainSummary(w io.Writer, summary RunSummary) error {
+	_, err := fmt.Fprintf(w, "Run summary: %d targets, %d results in %v\n",
+		summary.Targets, summary.Results, time.Duration(summary.Duration)*time.Millisecond)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	header := []string{"SOURCE", "TARGETS", "RETURNED", "KEPT"}
+	for _, class := range summaryErrorClasses {
+		header = append(header, strings.ToUpper(strings.ReplaceAll(string(class), "_", " ")))
+	}
+	header = append(header, "OTHER", "STOPPED", "AVG LATENCY", "CREDITS")
+	_, _ = fmt.Fprintln(tw, strings.Join(header, "\t"))
+
+	for _, s := range summary.Sources {
+		row := []string{
+			s.Source,
+			fmt.Sprint(s.TargetsQueried),
+			fmt.Sprint(s.Returned),
+			fmt.Sprint(s.Kept),
+		}
+		other := s.ErrorCount()
+		for _, class := range summaryErrorClasses {
+			row = append(row, fmt.Sprint(s.Errors[class]))
+			other -= s.Errors[class]
+		}
+		row = append(row,
+			fmt.Sprint(other),
+			fmt.Sprint(s.StoppedEarly),
+			(time.Duration(s.AvgLatency) * time.Millisecond).String(),
+			fmt.Sprint(s.Credits),
+		)
+		_, _ = fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// WriteJSONSummary writes the run summary as an indented JSON document.
+func WriteJSONSummary(w io.Writer, summary RunSummary) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(summary)
+}
+
+// reportSummary writes the run summary requested with --summary and
+// --summary-json. The plain table goes to stderr so it never mixes with
+// results on stdout.
+func (r *Runner) reportSummary(stderr io.Writer) error {
+	if !r.options.Summary && r.options.SummaryJSON == "" {
+		return nil
+	}
+	summary := r.stats.snapshot(r.quota)
+
+	if r.options.Summary {
+		if err := WritePlainSummary(stderr, summary); err != nil {
+			return err
+		}
+	}
+	if r.options.SummaryJSON != "" {
+		file, err := utils.CreateFileWithSafe(r.options.SummaryJSON, false, r.options.Overwrite)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = file.Close()
+		}()
+		if err := WriteJSONSummary(file, summary); err != nil {
+			return err
+		}
+		logger.Debugf("Wrote run summary to %s", r.options.SummaryJSON)
+	}
+	return nil
+}
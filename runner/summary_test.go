@@ -0,0 +1,89 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vflame6/leaker/runner/sources"
+)
+
+func TestEnumerate_CollectsRunStats(t *testing.T) {
+	r := newTestRunner([]string{})
+	r.stats = newRunStats()
+	r.scanSources = []sources.Source{
+		&fakeSource{name: "one", emits: []sources.Result{
+			{Source: "one", Email: "a@b.com", Password: "p1"},
+			{Source: "one", Email: "a@b.com", Password: "p1"},
+			{Source: "one", Email: "other@b.com", Password: "p2"},
+		}},
+		&fakeSource{name: "two", emits: []sources.Result{
+			{Source: "two", Error: errors.New("two returned status 401: bad key")},
+			{Source: "two", Error: errors.New("two returned status 429: slow down")},
+		}},
+	}
+
+	var out bytes.Buffer
+	if err := r.EnumerateSingleTarget(context.Background(), "a@b.com", sources.TypeEmail, 5*time.Second, []io.Writer{&out}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	summary := r.stats.snapshot(nil)
+	if summary.Targets != 1 || summary.Results != 1 || len(summary.Sources) != 2 {
+		t.Fatalf("unexpected summary: %+v", summary)
+	}
+	one, two := summary.Sources[0], summary.Sources[1]
+	if one.TargetsQueried != 1 || one.Returned != 3 || one.Kept != 1 || one.ErrorCount() != 0 {
+		t.Errorf("unexpected stats for one: %+v", one)
+	}
+	if two.Errors[sources.ErrorAuth] != 1 || two.Errors[sources.ErrorRateLimit] != 1 || two.Returned != 0 {
+		t.Errorf("unexpected stats for two: %+v", two)
+	}
+}
+
+func TestWriteSummary(t *testing.T) {
+	summary := RunSummary{Targets: 2, Results: 5, Duration: 1500, Sources: []SourceSummary{{
+		Source:         "dehashed",
+		TargetsQueried: 2,
+		Returned:       7,
+		Kept:           5,
+		Errors:         map[sources.ErrorClass]int{sources.ErrorTimeout: 1, sources.ErrorOther: 2},
+		AvgLatency:     250,
+		Credits:        4,
+	}}}
+
+	var plain bytes.Buffer
+	if err := WritePlainSummary(&plain, summary); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(plain.String()), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[0], "2 targets, 5 results in 1.5s") {
+		t.Fatalf("unexpected plain summary:\n%s", plain.String())
+	}
+	if fields := strings.Fields(lines[2]); strings.Join(fields, " ") != "dehashed 2 7 5 0 0 1 0 2 0 250ms 4" {
+		t.Errorf("unexpected row %q", lines[2])
+	}
+
+	var buf bytes.Buffer
+	if err := WriteJSONSummary(&buf, summary); err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		Sources []struct {
+			Source  string         `json:"source"`
+			Errors  map[string]int `json:"errors"`
+			Credits int            `json:"credits_used"`
+		} `json:"sources"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Sources) != 1 || decoded.Sources[0].Errors["timeout"] != 1 || decoded.Sources[0].Credits != 4 {
+		t.Errorf("unexpected JSON summary: %s", buf.String())
+	}
+}
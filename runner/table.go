@@ -0,0 +1,243 @@
+package runner
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/vflame6/leaker/runner/sources"
+)
+
+// Output formats accepted by --format.
+const (
+	FormatPlain = "plain"
+	FormatJSON  = "json"
+	FormatCSV   = "csv"
+	FormatTSV   = "tsv"
+)
+
+// extraColumn is the column holding Result.Extra as a JSON object, and the
+// prefix of the flattened extra columns.
+const extraColumn = "extra"
+
+// tableColumns are the fixed columns of CSV and TSV output: every string
+// field of jsonResult, named and ordered by its JSON tag.
+var tableColumns = func() []string {
+	var columns []string
+	t := reflect.TypeFor[jsonResult]()
+	for i := range t.NumField() {
+		if field := t.Field(i); field.Type.Kind() == reflect.String {
+			columns = append(columns, jsonFieldName(field))
+		}
+	}
+	return columns
+}()
+
+func jsonFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	return name
+}
+
+// ResultTable writes results as CSV or TSV rows with RFC 4180 quoting
+// under a header of tableColumns, so even a run without results yields a
+// header. Result.Extra goes into a JSON "extra" column or, when
+// flattened, into one "extra.<key>" column per key seen during the run;
+// flattened rows and their header are buffered until Flush because the
+// columns are only known then. It embeds the destination so it can stand
+// in for it in the runner's output writers.
+type ResultTable struct {
+	io.Writer
+	csv            *csv.Writer
+	flattenExtra   bool
+	metadata       bool
+	escapeFormulas bool
+	header         bool
+	rows           []jsonResult
+}
+
+// NewResultTable creates a table writing to w in the given format, which
+// must be FormatCSV or FormatTSV. With escapeFormulas, values a
+// spreadsheet would evaluate as a formula are prefixed with "'".
+func NewResultTable(w io.Writer, format string, flattenExtra, includeMetadata, escapeFormulas bool) *ResultTable {
+	writer := csv.NewWriter(w)
+	if format == FormatTSV {
+		writer.Comma = '\t'
+	}
+	return &ResultTable{Writer: w, csv: writer, flattenExtra: flattenExtra, metadata: includeMetadata, escapeFormulas: escapeFormulas}
+}
+
+// WriteResult writes one result, or buffers it when Extra is flattened.
+func (t *ResultTable) WriteResult(result *sources.Result, target string) error {
+	return t.writeRow(newJSONResult(t.metadata, result, target))
+}
+
+// WriteHeader writes the header of a table whose Extra is not flattened,
+// unless it was written already. The runner calls it when it sets up the
+// outputs, before any result arrives.
+func (t *ResultTable) WriteHeader() error {
+	if t.flattenExtra || t.header {
+		return nil
+	}
+	t.header = true
+	if err := t.csv.Write(append(slices.Clone(tableColumns), extraColumn)); err != nil {
+		return err
+	}
+	t.csv.Flush()
+	return t.csv.Error()
+}
+
+func (t *ResultTable) writeRow(jr jsonResult) error {
+	if t.flattenExtra {
+		t.rows = append(t.rows, jr)
+		return nil
+	}
+	if err := t.WriteHeader(); err != nil {
+		return err
+	}
+	extra := ""
+	if len(jr.Extra) > 0 {
+		data, err := json.Marshal(jr.Extra)
+		if err != nil {
+			return err
+		}
+		extra = string(data)
+	}
+	if err := t.csv.Write(t.escape(append(tableRow(jr), extra))); err != nil {
+		return err
+	}
+	// flush every row so output streams like the other formats
+	t.csv.Flush()
+	return t.csv.Error()
+}
+
+// Flush writes the buffered rows of a flattened table, with the extra
+// keys found in any of them as sorted columns after the fixed ones. A
+// table that has not written a header yet writes one even without rows.
+func (t *ResultTable) Flush() error {
+	if !t.flattenExtra {
+		return t.WriteHeader()
+	}
+	if len(t.rows) == 0 && t.header {
+		return nil
+	}
+	t.header = true
+	keys := make(map[string]struct{})
+	for _, jr := range t.rows {
+		for key := range jr.Extra {
+			keys[key] = struct{}{}
+		}
+	}
+	extraKeys := make([]string, 0, len(keys))
+	for key := range keys {
+		extraKeys = append(extraKeys, key)
+	}
+	slices.Sort(extraKeys)
+
+	header := slices.Clone(tableColumns)
+	for _, key := range extraKeys {
+		header = append(header, fmt.Sprintf("%s.%s", extraColumn, key))
+	}
+	if err := t.csv.Write(header); err != nil {
+		return err
+	}
+	for _, jr := range t.rows {
+		row := tableRow(jr)
+		for _, key := range extraKeys {
+			row = append(row, jr.Extra[key])
+		}
+		if err := t.csv.Write(t.escape(row)); err != nil {
+			return err
+		}
+	}
+	t.rows = nil
+	t.csv.Flush()
+	return t.csv.Error()
+}
+
+// escape prefixes the values of row that start like a spreadsheet formula
+// with "'", when the table escapes formulas. Leak data is attacker
+// controlled, so a leaked "=HYPERLINK(...)" must not run when an analyst
+// opens the file.
+func (t *ResultTable) escape(row []string) []string {
+	if !t.escapeFormulas {
+		return row
+	}
+	for i, value := range row {
+		if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+			row[i] = "'" + value
+		}
+	}
+	return row
+}
+
+// tableRow returns the values of the tableColumns of jr.
+func tableRow(jr jsonResult) []string {
+	v := reflect.ValueOf(jr)
+	row := make([]string, 0, len(tableColumns))
+	for i := range v.NumField() {
+		if field := v.Field(i); field.Kind() == reflect.String {
+			row = append(row, field.String())
+		}
+	}
+	return row
+}
+
+// writeResult writes a result to one of the runner's output writers in
+// the selected output format.
+func (r *Runner) writeResult(writer io.Writer, result *sources.Result, target string) error {
+	switch w := writer.(type) {
+	case *ResultTable:
+		return w.WriteResult(result, target)
+	case *ResultTemplate:
+		return w.WriteResult(result, target)
+	case *Report:
+		w.Add(result, target)
+		return nil
+	case *Findings:
+		w.Add(result, target)
+		return nil
+	case *TargetDirectory:
+		return w.WriteResult(result, target)
+	}
+	if r.options.JSON {
+		return WriteJSONResult(writer, r.options.Metadata, result, target)
+	}
+	return WritePlainResult(writer, r.options.Verbose, r.options.Metadata, result)
+}
+
+// formatOutputs wraps the output writers in result tables or templates
+// when such an output format was selected. Tables write their header
+// right away.
+func (r *Runner) formatOutputs(outputs []io.Writer) ([]io.Writer, error) {
+	wrapped := make([]io.Writer, 0, len(outputs))
+	for _, output := range outputs {
+		switch {
+		case r.outputTemplate != nil:
+			output = NewResultTemplate(output, r.outputTemplate)
+		case r.options.Format == FormatCSV || r.options.Format == FormatTSV:
+			table := NewResultTable(output, r.options.Format, r.options.FlattenExtra, r.options.Metadata, r.options.EscapeFormulas)
+			if err := table.WriteHeader(); err != nil {
+				return nil, err
+			}
+			output = table
+		}
+		wrapped = append(wrapped, output)
+	}
+	return wrapped, nil
+}
+
+// flushOutputs writes the rows buffered by result tables.
+func flushOutputs(outputs []io.Writer) error {
+	for _, output := range outputs {
+		if table, ok := output.(*ResultTable); ok {
+			if err := table.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/vflame6/leaker/runner/sources"
+)
+
+func readTable(t *testing.T, data string, comma rune) [][]string {
+	t.Helper()
+	reader := csv.NewReader(strings.NewReader(data))
+	reader.Comma = comma
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		t.Fatalf("invalid table %q: %v", data, err)
+	}
+	return records
+}
+
+func TestTableColumns_FollowJSONResult(t *testing.T) {
+	want := []string{"source", "target", "email", "username", "password", "hash", "salt",
+		"ip", "phone", "name", "database", "url", "first_seen"}
+	if strings.Join(tableColumns, ",") != strings.Join(want, ",") {
+		t.Errorf("unexpected columns: %v", tableColumns)
+	}
+}
+
+func TestResultTable_CSVQuoting(t *testing.T) {
+	var buf bytes.Buffer
+	table := NewResultTable(&buf, FormatCSV, false, true, false)
+	result := &sources.Result{
+		Source:   "seed",
+		Email:    "alice@example.com",
+		Password: `p,a:s"s` + "\nword",
+		Database: "Combo",
+		Extra:    map[string]string{"city": "Paris"},
+	}
+	if err := table.WriteResult(result, "alice@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if err := table.WriteResult(&sources.Result{Source: "seed", Email: "bob@example.com"}, "bob@example.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	records := readTable(t, buf.String(), ',')
+	if len(records) != 3 {
+		t.Fatalf("expected a header and 2 rows, got %v", records)
+	}
+	header := records[0]
+	if header[len(header)-1] != "extra" {
+		t.Errorf("expected the extra column last, got %v", header)
+	}
+	row := map[string]string{}
+	for i, column := range header {
+		row[column] = records[1][i]
+	}
+	if row["password"] != result.Password || row["target"] != "alice@example.com" || row["database"] != "Combo" {
+		t.Errorf("unexpected row: %v", row)
+	}
+	if row["extra"] != `{"city":"Paris"}` {
+		t.Errorf("unexpected extra column: %q", row["extra"])
+	}
+	if !strings.Contains(buf.String(), `"p,a:s""s`) {
+		t.Errorf("expected RFC 4180 quoting, got %q", buf.String())
+	}
+}
+
+func TestResultTable_TSVFlattensExtra(t *testing.T) {
+	var buf bytes.Buffer
+	table := NewResultTable(&buf, FormatTSV, true, false, false)
+	for _, result := range []*sources.Result{
+		{Source: "seed", Email: "alice@example.com", Extra: map[string]string{"city": "Paris"}},
+		{Source: "seed", Email: "bob@example.com", Database: "Combo", Extra: map[string]string{"age": "42"}},
+	} {
+		if err := table.WriteResult(result, result.Email); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if buf.Len() != 0 {
+		t.Fatal("flattened rows must be buffered until Flush")
+	}
+	if err := table.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	records := readTable(t, buf.String(), '\t')
+	header := records[0]
+	if got := strings.Join(header[len(header)-2:], ","); got != "extra.age,extra.city" {
+		t.Errorf("expected sorted extra columns, got %v", header)
+	}
+	if records[1][len(header)-1] != "Paris" || records[2][len(header)-2] != "42" {
+		t.Errorf("unexpected rows: %v", records[1:])
+	}
+	// database is only filled with -M
+	if records[2][10] != "" {
+		t.Errorf("expected an empty database column, got %q", records[2][10])
+	}
+}
+
+func TestResultTable_EscapesFormulas(t *testing.T) {
+	result := &sources.Result{Source: "seed", Email: "alice@example.com", Password: "=HYPERLINK(\"http://x\")", Name: "-1+1", Extra: map[string]string{"note": "@SUM(A1)"}}
+	for _, tc := range []struct {
+		escape   bool
+		flatten  bool
+		password string
+		name     string
+		extra    string
+	}{
+		{false, false, result.Password, "-1+1", `{"note":"@SUM(A1)"}`},
+		{true, false, "'" + result.Password, "'-1+1", `{"note":"@SUM(A1)"}`},
+		{true, true, "'" + result.Password, "'-1+1", "'@SUM(A1)"},
+	} {
+		var buf bytes.Buffer
+		table := NewResultTable(&buf, FormatCSV, tc.flatten, false, tc.escape)
+		if err := table.WriteResult(result, "alice@example.com"); err != nil {
+			t.Fatal(err)
+		}
+		if err := table.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		records := readTable(t, buf.String(), ',')
+		row := map[string]string{}
+		for i, column := range records[0] {
+			row[column] = records[1][i]
+		}
+		extra := row["extra"]
+		if tc.flatten {
+			extra = row["extra.note"]
+		}
+		if row["password"] != tc.password || row["name"] != tc.name || extra != tc.extra || row["email"] != "alice@example.com" {
+			t.Errorf("escape=%v flatten=%v: unexpected row %v", tc.escape, tc.flatten, row)
+		}
+	}
+}
+
+func TestResultTable_HeaderWithoutResults(t *testing.T) {
+	for _, flatten := range []bool{false, true} {
+		var buf bytes.Buffer
+		r := newTestRunner([]string{})
+		r.options.Format = FormatCSV
+		r.options.FlattenExtra = flatten
+		outputs, err := r.formatOutputs([]io.Writer{&buf})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !flatten && buf.Len() == 0 {
+			t.Error("expected the header before any result")
+		}
+		if err := flushOutputs(outputs); err != nil {
+			t.Fatal(err)
+		}
+		if err := flushOutputs(outputs); err != nil {
+			t.Fatal(err)
+		}
+
+		records := readTable(t, buf.String(), ',')
+		if len(records) != 1 || records[0][0] != tableColumns[0] {
+			t.Errorf("flatten=%v: expected a single header, got %v", flatten, records)
+		}
+	}
+}
+
+func TestEnumerate_WritesCSVToTableOutputs(t *testing.T) {
+	var buf bytes.Buffer
+	r := newTestRunner([]string{})
+	r.scanSources = []sources.Source{&fakeSource{name: "fake", emits: []sources.Result{
+		{Source: "fake", Email: "alice@example.com", Password: "a,b"},
+	}}}
+	r.options.Type = sources.TypeEmail
+	r.options.Format = FormatCSV
+
+	outputs, err := r.formatOutputs([]io.Writer{&buf})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.EnumerateMultipleTargets(context.Background(), strings.NewReader("alice@example.com\n"), outputs); err != nil {
+		t.Fatal(err)
+	}
+	if err := flushOutputs(outputs); err != nil {
+		t.Fatal(err)
+	}
+	records := readTable(t, buf.String(), ',')
+	if len(records) != 2 || records[1][1] != "alice@example.com" || records[1][4] != "a,b" {
+		t.Errorf("unexpected CSV output: %v", records)
+	}
+}
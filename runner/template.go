@@ -0,0 +1,128 @@
+package runner
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"reflect"
+	"strings"
+	"text/template"
+
+	"github.com/vflame6/leaker/runner/sources"
+)
+
+// templateFuncs are the helpers available to --template:
+//
+//	mask      keep the first and last character: {{mask .Password}}
+//	hash      hex digest (md5, sha1, sha256, sha512): {{hash "sha256" .Password}}
+//	json      JSON encoding, e.g. for a quoted string: {"e":{{json .Email}}}
+//	default   fallback for empty values: {{.Database | default "unknown"}}
+var templateFuncs = template.FuncMap{
+	"mask":    maskPassword,
+	"hash":    hashString,
+	"json":    jsonString,
+	"default": defaultValue,
+}
+
+// templateData is what --template renders for each result: every
+// sources.Result field and method, plus the target and, with leaker
+// monitor, the time the leak was first seen.
+type templateData struct {
+	*sources.Result
+	Target    string
+	FirstSeen string
+}
+
+// ParseOutputTemplate parses a --template text. A missing trailing newline
+// is added so every result renders on its own line.
+func ParseOutputTemplate(text string) (*template.Template, error) {
+	if !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	return template.New("output").Funcs(templateFuncs).Parse(text)
+}
+
+// configureOutputTemplate parses --template.
+func (r *Runner) configureOutputTemplate() error {
+	if r.options.Template == "" {
+		return nil
+	}
+	tmpl, err := ParseOutputTemplate(r.options.Template)
+	if err != nil {
+		return err
+	}
+	r.outputTemplate = tmpl
+	return nil
+}
+
+// ResultTemplate renders results through a user template, skipping
+// results that render to blank text. It embeds the destination so it can
+// stand in for it in the runner's output writers.
+type ResultTemplate struct {
+	io.Writer
+	template *template.Template
+}
+
+// NewResultTemplate creates a writer rendering each result with tmpl.
+func NewResultTemplate(w io.Writer, tmpl *template.Template) *ResultTemplate {
+	return &ResultTemplate{Writer: w, template: tmpl}
+}
+
+// WriteResult renders one result.
+func (t *ResultTemplate) WriteResult(result *sources.Result, target string) error {
+	return t.render(templateData{Result: result, Target: target})
+}
+
+func (t *ResultTemplate) render(data templateData) error {
+	var buf bytes.Buffer
+	if err := t.template.Execute(&buf, data); err != nil {
+		return err
+	}
+	if strings.TrimSpace(buf.String()) == "" {
+		return nil
+	}
+	_, err := t.Writer.Write(buf.Bytes())
+	return err
+}
+
+func hashString(algorithm, value string) (string, error) {
+	var h hash.Hash
+	switch strings.ToLower(algorithm) {
+	case "md5":
+		h = md5.New()
+	case "sha1":
+		h = sha1.New()
+	case "sha256":
+		h = sha256.New()
+	case "sha512":
+		h = sha512.New()
+	default:
+		return "", fmt.Errorf("unknown hash algorithm %q", algorithm)
+	}
+	h.Write([]byte(value))
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func jsonString(value any) (string, error) {
+	data, err := json.Marshal(value)
+	return string(data), err
+}
+
+// defaultValue returns value, or fallback when value is empty. Its
+// argument order allows {{.Field | default "x"}}.
+func defaultValue(fallback, value any) any {
+	if value == nil {
+		return fallback
+	}
+	if v := reflect.ValueOf(value); v.IsZero() {
+		return fallback
+	}
+	return value
+}
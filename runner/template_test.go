@@ -0,0 +1,92 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/vflame6/leaker/runner/sources"
+)
+
+func renderTemplate(t *testing.T, text string, result *sources.Result, target string) string {
+	t.Helper()
+	tmpl, err := ParseOutputTemplate(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := NewResultTemplate(&buf, tmpl).WriteResult(result, target); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestResultTemplate_Fields(t *testing.T) {
+	result := &sources.Result{Source: "seed", Email: "alice@example.com", Password: "hunter2", URL: "https://example.com"}
+	got := renderTemplate(t, "{{.URL}}|{{.Email}}|{{.Password}}|{{.Source}}|{{.Target}}", result, "alice@example.com")
+	if got != "https://example.com|alice@example.com|hunter2|seed|alice@example.com\n" {
+		t.Errorf("unexpected render: %q", got)
+	}
+}
+
+func TestResultTemplate_Helpers(t *testing.T) {
+	result := &sources.Result{Source: "seed", Email: `al"ice@example.com`, Password: "hunter2"}
+	for text, want := range map[string]string{
+		`{{mask .Password}}`:             "h*****2\n",
+		`{{hash "md5" .Password}}`:       "2ab96390c7dbe3439de74d0c9b0b1767\n",
+		`{{hash "sha256" "abc"}}`:        "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad\n",
+		`{"email":{{json .Email}}}`:      `{"email":"al\"ice@example.com"}` + "\n",
+		`{{.Database | default "n/a"}}`:  "n/a\n",
+		`{{.Password | default "n/a"}}`:  "hunter2\n",
+		`{{.Value}}`:                     "email:al\"ice@example.com, password:hunter2\n",
+		"{{.Hash}}:{{.Salt}}\n":          ":\n",
+		`{{if .Hash}}{{.Hash}}{{end}}  `: "",
+	} {
+		if got := renderTemplate(t, text, result, "alice@example.com"); got != want {
+			t.Errorf("%s: got %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestResultTemplate_UnknownHash(t *testing.T) {
+	tmpl, err := ParseOutputTemplate(`{{hash "crc" .Password}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := NewResultTemplate(io.Discard, tmpl).WriteResult(&sources.Result{Password: "x"}, ""); err == nil {
+		t.Error("expected an error for an unknown hash algorithm")
+	}
+}
+
+func TestParseOutputTemplate_Invalid(t *testing.T) {
+	if _, err := ParseOutputTemplate("{{.Email"); err == nil {
+		t.Error("expected a parse error")
+	}
+}
+
+func TestEnumerate_WritesTemplateAndSkipsEmptyRenders(t *testing.T) {
+	var buf bytes.Buffer
+	r := newTestRunner([]string{})
+	r.scanSources = []sources.Source{&fakeSource{name: "fake", emits: []sources.Result{
+		{Source: "fake", Email: "alice@example.com", Password: "one"},
+		{Source: "fake", Email: "alice@example.com", Hash: "5f4dcc3b5aa765d61d8327deb882cf99"},
+	}}}
+	r.options.Type = sources.TypeEmail
+	r.options.Template = "{{if .Password}}{{.Email}}:{{.Password}}{{end}}"
+	if err := r.configureOutputTemplate(); err != nil {
+		t.Fatal(err)
+	}
+
+	outputs, err := r.formatOutputs([]io.Writer{&buf})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.EnumerateMultipleTargets(context.Background(), strings.NewReader("alice@example.com\n"), outputs); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "alice@example.com:one\n" {
+		t.Errorf("unexpected output: %q", buf.String())
+	}
+}
@@ -7,7 +7,6 @@ import (
 	"github.com/vflame6/leaker/runner/sources"
 	"io"
 	"net/http"
-	"regexp"
 	"strconv"
 	"strings"
 	"sync"
@@ -51,6 +50,9 @@ func (v *Verifier) EnrichResult(result *sources.Result) {
 	// Hash Format Identification
 	if result.Hash != "" {
 		result.SetExtra("hash_type", identifyHash(result.Hash))
+		if modes := hashcatModes(result.Hash, result.Salt); modes != "" {
+			result.SetExtra("hashcat_mode", modes)
+		}
 	}
 }
 
@@ -151,42 +153,26 @@ func (v *Verifier) fetchHIBPRange(prefix string) ([]string, error) {
 	return lines, nil
 }
 
-// identifyHash returns the hash algorithm name based on the hash string's format.
+// identifyHash returns the hash algorithm name based on the hash string's
+// format: the most likely of its hashcat modes, see hashModes.
 func identifyHash(hash string) string {
-	// Modular crypt formats
-	if matched, _ := regexp.MatchString(`^\$2[aby]\$`, hash); matched {
-		return "bcrypt"
-	}
-	if strings.HasPrefix(hash, "$1$") {
-		return "md5crypt"
-	}
-	if strings.HasPrefix(hash, "$5$") {
-		return "sha256crypt"
-	}
-	if strings.HasPrefix(hash, "$6$") {
-		return "sha512crypt"
-	}
-	if strings.HasPrefix(hash, "$argon2") {
-		return "argon2"
-	}
-
-	// Raw hex hashes
-	if isHex(hash) {
-		switch len(hash) {
-		case 32:
-			return "md5"
-		case 40:
-			return "sha1"
-		case 64:
-			return "sha256"
-		case 128:
-			return "sha512"
-		}
+	if modes := hashModes(hash, ""); len(modes) > 0 {
+		return modes[0].Name
 	}
-
 	return "unknown"
 }
 
+// hashcatModes returns the comma-separated hashcat modes a hash may be
+// cracked with; more than one means the hash type is ambiguous.
+func hashcatModes(hash, salt string) string {
+	modes := hashModes(hash, salt)
+	numbers := make([]string, 0, len(modes))
+	for _, mode := range modes {
+		numbers = append(numbers, strconv.Itoa(mode.Mode))
+	}
+	return strings.Join(numbers, ",")
+}
+
 // isHex returns true if s contains only hexadecimal characters.
 func isHex(s string) bool {
 	if s == "" {
@@ -0,0 +1,19 @@
+package utils
+
+import "strings"
+
+// EmailInDomain reports whether email belongs to domain or one of its
+// subdomains. The comparison is case-insensitive:
+//
+//	"alice@example.com"      in "example.com" → true
+//	"bob@mail.example.com"   in "example.com" → true
+//	"carol@notexample.com"   in "example.com" → false
+func EmailInDomain(email, domain string) bool {
+	at := strings.LastIndex(email, "@")
+	if at <= 0 || domain == "" {
+		return false
+	}
+	host := strings.ToLower(email[at+1:])
+	domain = strings.ToLower(domain)
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
@@ -0,0 +1,23 @@
+package utils
+
+import "testing"
+
+func TestEmailInDomain(t *testing.T) {
+	tests := []struct {
+		email  string
+		domain string
+		want   bool
+	}{
+		{"alice@example.com", "example.com", true},
+		{"Bob@Mail.Example.com", "example.com", true},
+		{"carol@notexample.com", "example.com", false},
+		{"example.com", "example.com", false},
+		{"@example.com", "example.com", false},
+		{"dave@example.com", "", false},
+	}
+	for _, tc := range tests {
+		if got := EmailInDomain(tc.email, tc.domain); got != tc.want {
+			t.Errorf("EmailInDomain(%q, %q) = %v, want %v", tc.email, tc.domain, got, tc.want)
+		}
+	}
+}